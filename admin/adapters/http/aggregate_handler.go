@@ -78,3 +78,20 @@ func (h *AggregateHandler[T]) RepublishAggregate(w http.ResponseWriter, r *http.
 		NbRepublishedEvents: nbRepublishedEvents,
 	})
 }
+
+func (h *AggregateHandler[T]) VerifyAggregate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	aggregateId, err := xhttp.PathParamUUID(r, "aggregate_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse aggregate_id", err)
+		return
+	}
+
+	report, err := h.add.VerifyAggregate(ctx, aggregateId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusInternalServerError, "failed to verify aggregate", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromHashChainReport(report))
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/davidterranova/cqrs/admin"
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/xhttp"
+	"github.com/google/uuid"
+)
+
+type DeadLetterHandler[T eventsourcing.Aggregate] struct {
+	app *admin.App[T]
+}
+
+func NewDeadLetterHandler[T eventsourcing.Aggregate](app *admin.App[T]) *DeadLetterHandler[T] {
+	return &DeadLetterHandler[T]{
+		app: app,
+	}
+}
+
+func (h *DeadLetterHandler[T]) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	strAggregateType, err := xhttp.QueryParamStr(r, "aggregate_type")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse aggregate_type", err)
+		return
+	}
+
+	var aggregateType *eventsourcing.AggregateType
+	if strAggregateType != "" {
+		at := eventsourcing.AggregateType(strAggregateType)
+		aggregateType = &at
+	}
+
+	deadLetters, err := h.app.ListDeadLetters(ctx, aggregateType)
+	if err != nil {
+		xhttp.WriteError(ctx, w, deadLetterErrorStatus(err), "failed to list dead letters", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromDeadLetterSlice(deadLetters))
+}
+
+func (h *DeadLetterHandler[T]) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventId, err := xhttp.PathParamUUID(r, "event_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse event_id", err)
+		return
+	}
+
+	deadLetter, err := h.app.GetDeadLetter(ctx, eventId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, deadLetterErrorStatus(err), "failed to get dead letter", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromDeadLetter(deadLetter))
+}
+
+type deadLetterActionResponse struct {
+	EventId uuid.UUID `json:"event_id"`
+}
+
+func (h *DeadLetterHandler[T]) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventId, err := xhttp.PathParamUUID(r, "event_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse event_id", err)
+		return
+	}
+
+	err = h.app.RetryDeadLetter(ctx, eventId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, deadLetterErrorStatus(err), "failed to retry dead letter", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, deadLetterActionResponse{EventId: eventId})
+}
+
+func (h *DeadLetterHandler[T]) DiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventId, err := xhttp.PathParamUUID(r, "event_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse event_id", err)
+		return
+	}
+
+	err = h.app.DiscardDeadLetter(ctx, eventId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, deadLetterErrorStatus(err), "failed to discard dead letter", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, deadLetterActionResponse{EventId: eventId})
+}
+
+func deadLetterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, eventsourcing.ErrDeadLetterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, eventsourcing.ErrDeadLetterUnsupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
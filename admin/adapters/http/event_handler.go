@@ -60,6 +60,15 @@ func (h *EventHandler[T]) ListEvent(w http.ResponseWriter, r *http.Request) {
 		filter = append(filter, eventsourcing.EventQueryWithPublished(*published))
 	}
 
+	afterPosition, err := xhttp.QueryParamInt(r, "after_position")
+	if err != nil {
+		xhttp.WriteError(r.Context(), w, http.StatusBadRequest, "failed to parse after_position", err)
+		return
+	}
+	if afterPosition > 0 {
+		filter = append(filter, eventsourcing.EventQueryWithAfterPosition(int64(afterPosition)))
+	}
+
 	events, err := h.app.ListEvent(
 		r.Context(),
 		eventsourcing.NewEventQuery(filter...),
@@ -71,3 +80,14 @@ func (h *EventHandler[T]) ListEvent(w http.ResponseWriter, r *http.Request) {
 
 	xhttp.WriteObject(ctx, w, http.StatusOK, fromEventInternalSlice(events))
 }
+
+func (h *EventHandler[T]) VerifyEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	report, err := h.app.VerifyEvents(ctx)
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusInternalServerError, "failed to verify events", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromHashChainReport(report))
+}
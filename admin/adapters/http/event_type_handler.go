@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/davidterranova/cqrs/admin"
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/xhttp"
+)
+
+type EventTypeHandler[T eventsourcing.Aggregate] struct {
+	app *admin.App[T]
+}
+
+func NewEventTypeHandler[T eventsourcing.Aggregate](app *admin.App[T]) *EventTypeHandler[T] {
+	return &EventTypeHandler[T]{
+		app: app,
+	}
+}
+
+func (h *EventTypeHandler[T]) ListEventTypes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	schemas, err := h.app.ListEventTypes(ctx)
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusInternalServerError, "failed to list event types", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromEventSchemaSlice(schemas))
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"encoding/hex"
 	"time"
 
 	"github.com/davidterranova/cqrs/eventsourcing"
@@ -17,6 +18,11 @@ type Event struct {
 	AggregateVersion int       `json:"aggregate_version"`
 	EventData        string    `json:"event_data"`
 	EventPublished   bool      `json:"event_published"`
+	GlobalPosition   int64     `json:"global_position"`
+
+	EventHash         string `json:"event_hash,omitempty"`
+	PreviousEventHash string `json:"previous_event_hash,omitempty"`
+	EventSignature    string `json:"event_signature,omitempty"`
 }
 
 func fromEventInternalSlice(e []eventsourcing.EventInternal) []Event {
@@ -38,5 +44,213 @@ func fromEventInternal(e eventsourcing.EventInternal) Event {
 		AggregateType:    string(e.AggregateType),
 		AggregateVersion: e.AggregateVersion,
 		EventData:        string(e.EventData),
+		GlobalPosition:   e.GlobalPosition,
+
+		EventHash:         hex.EncodeToString(e.EventHash),
+		PreviousEventHash: hex.EncodeToString(e.PreviousEventHash),
+		EventSignature:    hex.EncodeToString(e.EventSignature),
+	}
+}
+
+type DeadLetter struct {
+	Event          Event     `json:"event"`
+	Attempts       int       `json:"attempts"`
+	Reason         string    `json:"reason"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+func fromDeadLetterSlice(d []eventsourcing.DeadLetter) []DeadLetter {
+	deadLetters := make([]DeadLetter, len(d))
+	for i, v := range d {
+		deadLetters[i] = fromDeadLetter(v)
+	}
+	return deadLetters
+}
+
+func fromDeadLetter(d eventsourcing.DeadLetter) DeadLetter {
+	return DeadLetter{
+		Event:          fromEventInternal(d.Event),
+		Attempts:       d.Attempts,
+		Reason:         d.Reason,
+		DeadLetteredAt: d.DeadLetteredAt,
+	}
+}
+
+type EventType struct {
+	EventType string                    `json:"event_type"`
+	GoType    string                    `json:"go_type"`
+	Schema    *eventsourcing.JSONSchema `json:"schema"`
+}
+
+func fromEventSchemaSlice(s []eventsourcing.EventSchema) []EventType {
+	eventTypes := make([]EventType, len(s))
+	for i, v := range s {
+		eventTypes[i] = EventType{
+			EventType: v.EventType.String(),
+			GoType:    v.GoType.String(),
+			Schema:    v.Schema,
+		}
+	}
+	return eventTypes
+}
+
+type HashChainBreak struct {
+	EventId          uuid.UUID `json:"event_id"`
+	AggregateType    string    `json:"aggregate_type"`
+	AggregateId      uuid.UUID `json:"aggregate_id"`
+	AggregateVersion int       `json:"aggregate_version"`
+	GlobalPosition   int64     `json:"global_position"`
+	Reason           string    `json:"reason"`
+}
+
+type HashChainReport struct {
+	Valid        bool            `json:"valid"`
+	NbEvents     int             `json:"nb_events"`
+	NbAggregates int             `json:"nb_aggregates"`
+	FirstBreak   *HashChainBreak `json:"first_break,omitempty"`
+}
+
+func fromHashChainReport(r eventsourcing.HashChainReport) HashChainReport {
+	report := HashChainReport{
+		Valid:        r.Valid(),
+		NbEvents:     r.NbEvents,
+		NbAggregates: r.NbAggregates,
+	}
+
+	if r.FirstBreak != nil {
+		report.FirstBreak = &HashChainBreak{
+			EventId:          r.FirstBreak.EventId,
+			AggregateType:    string(r.FirstBreak.AggregateType),
+			AggregateId:      r.FirstBreak.AggregateId,
+			AggregateVersion: r.FirstBreak.AggregateVersion,
+			GlobalPosition:   r.FirstBreak.GlobalPosition,
+			Reason:           string(r.FirstBreak.Reason),
+		}
+	}
+
+	return report
+}
+
+type PublisherStatus struct {
+	AggregateType       string     `json:"aggregate_type"`
+	State               string     `json:"state"`
+	StartedAt           *time.Time `json:"started_at,omitempty"`
+	StoppedAt           *time.Time `json:"stopped_at,omitempty"`
+	Published           int        `json:"published"`
+	LastPublishedAt     *time.Time `json:"last_published_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+}
+
+func fromPublisherStatus(s eventsourcing.PublisherStatus) PublisherStatus {
+	return PublisherStatus{
+		AggregateType:       string(s.AggregateType),
+		State:               string(s.State),
+		StartedAt:           optionalTime(s.StartedAt),
+		StoppedAt:           optionalTime(s.StoppedAt),
+		Published:           s.Published,
+		LastPublishedAt:     optionalTime(s.LastPublishedAt),
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		LastError:           s.LastError,
+		LastErrorAt:         optionalTime(s.LastErrorAt),
+	}
+}
+
+type Webhook struct {
+	Id         uuid.UUID `json:"id"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Paused     bool      `json:"paused"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RegisteredWebhook is only returned on registration, the secret is not exposed afterwards
+type RegisteredWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+func fromWebhookSlice(w []eventsourcing.Webhook) []Webhook {
+	webhooks := make([]Webhook, len(w))
+	for i, v := range w {
+		webhooks[i] = fromWebhook(v)
+	}
+	return webhooks
+}
+
+func fromWebhook(w eventsourcing.Webhook) Webhook {
+	eventTypes := make([]string, len(w.EventTypes))
+	for i, v := range w.EventTypes {
+		eventTypes[i] = v.String()
+	}
+
+	return Webhook{
+		Id:         w.Id,
+		Url:        w.Url,
+		EventTypes: eventTypes,
+		Paused:     w.Paused,
+		CreatedAt:  w.CreatedAt,
+	}
+}
+
+type WebhookDelivery struct {
+	Id          uuid.UUID `json:"id"`
+	EventId     uuid.UUID `json:"event_id"`
+	EventType   string    `json:"event_type"`
+	Attempt     int       `json:"attempt"`
+	Succeeded   bool      `json:"succeeded"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+func fromWebhookDeliverySlice(d []eventsourcing.WebhookDelivery) []WebhookDelivery {
+	deliveries := make([]WebhookDelivery, len(d))
+	for i, v := range d {
+		deliveries[i] = WebhookDelivery{
+			Id:          v.Id,
+			EventId:     v.EventId,
+			EventType:   v.EventType.String(),
+			Attempt:     v.Attempt,
+			Succeeded:   v.Succeeded(),
+			StatusCode:  v.StatusCode,
+			Error:       v.Error,
+			DurationMs:  v.Duration.Milliseconds(),
+			DeliveredAt: v.DeliveredAt,
+		}
+	}
+	return deliveries
+}
+
+type WebhookPendingDelivery struct {
+	EventId       uuid.UUID  `json:"event_id"`
+	EventType     string     `json:"event_type"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func fromWebhookPendingDeliverySlice(d []eventsourcing.WebhookPendingDelivery) []WebhookPendingDelivery {
+	pending := make([]WebhookPendingDelivery, len(d))
+	for i, v := range d {
+		pending[i] = WebhookPendingDelivery{
+			EventId:       v.EventId,
+			EventType:     v.EventType.String(),
+			Attempts:      v.Attempts,
+			NextAttemptAt: optionalTime(v.NextAttemptAt),
+			LastError:     v.LastError,
+			CreatedAt:     v.CreatedAt,
+		}
+	}
+	return pending
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
 	}
+	return &t
 }
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/davidterranova/cqrs/admin"
+	"github.com/davidterranova/cqrs/admin/usecase"
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/xhttp"
+)
+
+type PublisherHandler[T eventsourcing.Aggregate] struct {
+	app *admin.App[T]
+}
+
+func NewPublisherHandler[T eventsourcing.Aggregate](app *admin.App[T]) *PublisherHandler[T] {
+	return &PublisherHandler[T]{
+		app: app,
+	}
+}
+
+func (h *PublisherHandler[T]) GetPublisher(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	status, err := h.app.PublisherStatus()
+	if err != nil {
+		xhttp.WriteError(ctx, w, publisherErrorStatus(err), "failed to get publisher status", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromPublisherStatus(status))
+}
+
+func (h *PublisherHandler[T]) StartPublisher(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	status, err := h.app.StartPublisher(ctx)
+	if err != nil {
+		xhttp.WriteError(ctx, w, publisherErrorStatus(err), "failed to start publisher", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromPublisherStatus(status))
+}
+
+// StopPublisher responds once the outbox is drained, the drain is interrupted if the request is canceled
+func (h *PublisherHandler[T]) StopPublisher(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	status, err := h.app.StopPublisher(ctx)
+	if err != nil {
+		xhttp.WriteError(ctx, w, publisherErrorStatus(err), "failed to stop publisher", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromPublisherStatus(status))
+}
+
+func publisherErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrPublisherNotConfigured):
+		return http.StatusNotImplemented
+	case errors.Is(err, eventsourcing.ErrPublisherRunning), errors.Is(err, eventsourcing.ErrPublisherNotRunning):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -9,12 +9,45 @@ import (
 func New[T eventsourcing.Aggregate](root *mux.Router, app *admin.App[T]) *mux.Router {
 	aggregateHandler := NewAggregateHandler[T](app)
 
-	root.HandleFunc("/v1/aggregates/{aggregate_id}", aggregateHandler.LoadAggregate).Methods("GET")
+	// custom methods are registered first so that they are not matched as an aggregate_id
 	root.HandleFunc("/v1/aggregates/{aggregate_id}:republish", aggregateHandler.RepublishAggregate).Methods("POST")
+	root.HandleFunc("/v1/aggregates/{aggregate_id}:verify", aggregateHandler.VerifyAggregate).Methods("GET")
+	root.HandleFunc("/v1/aggregates/{aggregate_id}", aggregateHandler.LoadAggregate).Methods("GET")
 
 	eventHandler := NewEventHandler[T](app)
 
 	root.HandleFunc("/v1/events", eventHandler.ListEvent).Methods("GET")
+	root.HandleFunc("/v1/events:verify", eventHandler.VerifyEvents).Methods("GET")
+
+	eventTypeHandler := NewEventTypeHandler[T](app)
+
+	root.HandleFunc("/v1/event-types", eventTypeHandler.ListEventTypes).Methods("GET")
+
+	deadLetterHandler := NewDeadLetterHandler[T](app)
+
+	root.HandleFunc("/v1/dead-letters", deadLetterHandler.ListDeadLetters).Methods("GET")
+	root.HandleFunc("/v1/dead-letters/{event_id}:retry", deadLetterHandler.RetryDeadLetter).Methods("POST")
+	root.HandleFunc("/v1/dead-letters/{event_id}", deadLetterHandler.GetDeadLetter).Methods("GET")
+	root.HandleFunc("/v1/dead-letters/{event_id}", deadLetterHandler.DiscardDeadLetter).Methods("DELETE")
+
+	publisherHandler := NewPublisherHandler[T](app)
+
+	root.HandleFunc("/v1/publisher", publisherHandler.GetPublisher).Methods("GET")
+	root.HandleFunc("/v1/publisher:start", publisherHandler.StartPublisher).Methods("POST")
+	root.HandleFunc("/v1/publisher:stop", publisherHandler.StopPublisher).Methods("POST")
+
+	webhookHandler := NewWebhookHandler[T](app)
+
+	root.HandleFunc("/v1/webhooks", webhookHandler.RegisterWebhook).Methods("POST")
+	root.HandleFunc("/v1/webhooks", webhookHandler.ListWebhooks).Methods("GET")
+	root.HandleFunc("/v1/webhooks/{webhook_id}:pause", webhookHandler.PauseWebhook).Methods("POST")
+	root.HandleFunc("/v1/webhooks/{webhook_id}:resume", webhookHandler.ResumeWebhook).Methods("POST")
+	root.HandleFunc("/v1/webhooks/{webhook_id}/deliveries", webhookHandler.ListWebhookDeliveries).Methods("GET")
+	root.HandleFunc("/v1/webhooks/{webhook_id}/pending", webhookHandler.ListWebhookPendingDeliveries).Methods("GET")
+	root.HandleFunc("/v1/webhooks/{webhook_id}/pending/{event_id}:retry", webhookHandler.RetryWebhookPendingDelivery).Methods("POST")
+	root.HandleFunc("/v1/webhooks/{webhook_id}/pending/{event_id}", webhookHandler.DropWebhookPendingDelivery).Methods("DELETE")
+	root.HandleFunc("/v1/webhooks/{webhook_id}", webhookHandler.GetWebhook).Methods("GET")
+	root.HandleFunc("/v1/webhooks/{webhook_id}", webhookHandler.DeleteWebhook).Methods("DELETE")
 
 	return root
 }
@@ -0,0 +1,254 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/davidterranova/cqrs/admin"
+	"github.com/davidterranova/cqrs/admin/usecase"
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/xhttp"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler[T eventsourcing.Aggregate] struct {
+	app *admin.App[T]
+}
+
+func NewWebhookHandler[T eventsourcing.Aggregate](app *admin.App[T]) *WebhookHandler[T] {
+	return &WebhookHandler[T]{
+		app: app,
+	}
+}
+
+type registerWebhookRequest struct {
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+func (h *WebhookHandler[T]) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req registerWebhookRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to decode request body", err)
+		return
+	}
+
+	eventTypes := make([]eventsourcing.EventType, len(req.EventTypes))
+	for i, v := range req.EventTypes {
+		eventTypes[i] = eventsourcing.EventType(v)
+	}
+
+	webhook, err := h.app.RegisterWebhook(ctx, usecase.RegisterWebhook{
+		Url:        req.Url,
+		EventTypes: eventTypes,
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to register webhook", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusCreated, RegisteredWebhook{
+		Webhook: fromWebhook(webhook),
+		Secret:  webhook.Secret,
+	})
+}
+
+func (h *WebhookHandler[T]) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhooks, err := h.app.ListWebhooks(ctx)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to list webhooks", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromWebhookSlice(webhooks))
+}
+
+func (h *WebhookHandler[T]) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse webhook_id", err)
+		return
+	}
+
+	webhook, err := h.app.GetWebhook(ctx, webhookId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to get webhook", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromWebhook(webhook))
+}
+
+func (h *WebhookHandler[T]) PauseWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse webhook_id", err)
+		return
+	}
+
+	webhook, err := h.app.PauseWebhook(ctx, webhookId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to pause webhook", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromWebhook(webhook))
+}
+
+func (h *WebhookHandler[T]) ResumeWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse webhook_id", err)
+		return
+	}
+
+	webhook, err := h.app.ResumeWebhook(ctx, webhookId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to resume webhook", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromWebhook(webhook))
+}
+
+type webhookActionResponse struct {
+	WebhookId uuid.UUID `json:"webhook_id"`
+}
+
+func (h *WebhookHandler[T]) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse webhook_id", err)
+		return
+	}
+
+	err = h.app.DeleteWebhook(ctx, webhookId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to delete webhook", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, webhookActionResponse{WebhookId: webhookId})
+}
+
+func (h *WebhookHandler[T]) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse webhook_id", err)
+		return
+	}
+
+	limit, err := xhttp.QueryParamInt(r, "limit")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse limit", err)
+		return
+	}
+
+	deliveries, err := h.app.ListWebhookDeliveries(ctx, webhookId, limit)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to list webhook deliveries", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromWebhookDeliverySlice(deliveries))
+}
+
+func (h *WebhookHandler[T]) ListWebhookPendingDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse webhook_id", err)
+		return
+	}
+
+	limit, err := xhttp.QueryParamInt(r, "limit")
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse limit", err)
+		return
+	}
+
+	pending, err := h.app.ListWebhookPendingDeliveries(ctx, webhookId, limit)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to list webhook pending deliveries", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, fromWebhookPendingDeliverySlice(pending))
+}
+
+type webhookPendingActionResponse struct {
+	WebhookId uuid.UUID `json:"webhook_id"`
+	EventId   uuid.UUID `json:"event_id"`
+}
+
+func (h *WebhookHandler[T]) RetryWebhookPendingDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, eventId, err := webhookPendingParams(r)
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse path parameters", err)
+		return
+	}
+
+	err = h.app.RetryWebhookPendingDelivery(ctx, webhookId, eventId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to retry webhook pending delivery", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, webhookPendingActionResponse{WebhookId: webhookId, EventId: eventId})
+}
+
+func (h *WebhookHandler[T]) DropWebhookPendingDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhookId, eventId, err := webhookPendingParams(r)
+	if err != nil {
+		xhttp.WriteError(ctx, w, http.StatusBadRequest, "failed to parse path parameters", err)
+		return
+	}
+
+	err = h.app.DropWebhookPendingDelivery(ctx, webhookId, eventId)
+	if err != nil {
+		xhttp.WriteError(ctx, w, webhookErrorStatus(err), "failed to drop webhook pending delivery", err)
+		return
+	}
+
+	xhttp.WriteObject(ctx, w, http.StatusOK, webhookPendingActionResponse{WebhookId: webhookId, EventId: eventId})
+}
+
+func webhookPendingParams(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	webhookId, err := xhttp.PathParamUUID(r, "webhook_id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	eventId, err := xhttp.PathParamUUID(r, "event_id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	return webhookId, eventId, nil
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, eventsourcing.ErrWebhookNotFound), errors.Is(err, eventsourcing.ErrWebhookDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, eventsourcing.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrWebhooksNotConfigured):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
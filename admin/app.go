@@ -10,12 +10,42 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-const AllVersions = usecase.AllVersions
+const (
+	AllVersions = usecase.AllVersions
+
+	verifyHashChainBatchSize = 500
+)
 
 type App[T eventsourcing.Aggregate] struct {
 	listEvent          *usecase.ListEventHandler
+	listEventTypes     *usecase.ListEventTypesHandler[T]
 	loadAggregate      *usecase.LoadAggregateHandler[T]
 	republishAggregate *usecase.RepublishAggregateHandler[T]
+	verifyHashChain    *usecase.VerifyHashChainHandler
+	deadLetters        *usecase.DeadLettersHandler
+	publisher          *usecase.PublisherHandler
+	webhooks           *usecase.WebhooksHandler
+}
+
+type appOptions struct {
+	publisher eventsourcing.PublisherController
+	webhooks  eventsourcing.WebhookRepository
+}
+
+type AppOption func(*appOptions)
+
+// AppWithPublisher lets the admin app report the status of the publisher, start and stop it
+func AppWithPublisher(publisher eventsourcing.PublisherController) AppOption {
+	return func(o *appOptions) {
+		o.publisher = publisher
+	}
+}
+
+// AppWithWebhooks lets the admin app register, list, pause and delete the webhooks stored in repo
+func AppWithWebhooks(repo eventsourcing.WebhookRepository) AppOption {
+	return func(o *appOptions) {
+		o.webhooks = repo
+	}
 }
 
 func NewApp[T eventsourcing.Aggregate](
@@ -24,7 +54,13 @@ func NewApp[T eventsourcing.Aggregate](
 	userFactory eventsourcing.UserFactory,
 	aggregateType eventsourcing.AggregateType,
 	factory eventsourcing.AggregateFactory[T],
+	opts ...AppOption,
 ) (*App[T], error) {
+	var options appOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// set to false to disable CQRS and remain in eventsourcing context
 	CQRS := true
 	eventstore := eventsourcing.NewEventStore[T](eventRepository, registry, userFactory, CQRS)
@@ -35,7 +71,8 @@ func NewApp[T eventsourcing.Aggregate](
 	)
 
 	return &App[T]{
-		listEvent: usecase.NewListEventHandler(eventRepository),
+		listEvent:      usecase.NewListEventHandler(eventRepository),
+		listEventTypes: usecase.NewListEventTypesHandler[T](registry),
 		loadAggregate: usecase.NewLoadAggregateHandler[T](
 			commandHandler,
 			eventRepository,
@@ -44,6 +81,10 @@ func NewApp[T eventsourcing.Aggregate](
 			aggregateType,
 		),
 		republishAggregate: usecase.NewRepublishAggregateHandler[T](eventRepository), // should be set to nil if CQRS is disabled
+		verifyHashChain:    usecase.NewVerifyHashChainHandler(eventRepository, verifyHashChainBatchSize),
+		deadLetters:        usecase.NewDeadLettersHandler(eventRepository),
+		publisher:          usecase.NewPublisherHandler(options.publisher),
+		webhooks:           usecase.NewWebhooksHandler(options.webhooks),
 	}, nil
 }
 
@@ -51,6 +92,10 @@ func (a *App[T]) ListEvent(ctx context.Context, filter eventsourcing.EventQuery)
 	return a.listEvent.Handle(ctx, filter)
 }
 
+func (a *App[T]) ListEventTypes(ctx context.Context) ([]eventsourcing.EventSchema, error) {
+	return a.listEventTypes.Handle(ctx)
+}
+
 func (a *App[T]) LoadAggregate(ctx context.Context, aggregateId uuid.UUID, toVersion int) (*T, error) {
 	return a.loadAggregate.Handle(ctx, aggregateId, toVersion)
 }
@@ -63,3 +108,79 @@ func (a *App[T]) RepublishAggregate(ctx context.Context, aggregateId uuid.UUID)
 
 	return a.republishAggregate.Handle(ctx, aggregateId)
 }
+
+func (a *App[T]) VerifyAggregate(ctx context.Context, aggregateId uuid.UUID) (eventsourcing.HashChainReport, error) {
+	return a.verifyHashChain.HandleAggregate(ctx, aggregateId)
+}
+
+func (a *App[T]) VerifyEvents(ctx context.Context) (eventsourcing.HashChainReport, error) {
+	return a.verifyHashChain.HandleAll(ctx)
+}
+
+func (a *App[T]) ListDeadLetters(ctx context.Context, aggregateType *eventsourcing.AggregateType) ([]eventsourcing.DeadLetter, error) {
+	return a.deadLetters.HandleList(ctx, aggregateType)
+}
+
+func (a *App[T]) GetDeadLetter(ctx context.Context, eventId uuid.UUID) (eventsourcing.DeadLetter, error) {
+	return a.deadLetters.HandleGet(ctx, eventId)
+}
+
+func (a *App[T]) RetryDeadLetter(ctx context.Context, eventId uuid.UUID) error {
+	return a.deadLetters.HandleRetry(ctx, eventId)
+}
+
+func (a *App[T]) DiscardDeadLetter(ctx context.Context, eventId uuid.UUID) error {
+	return a.deadLetters.HandleDiscard(ctx, eventId)
+}
+
+func (a *App[T]) PublisherStatus() (eventsourcing.PublisherStatus, error) {
+	return a.publisher.HandleStatus()
+}
+
+func (a *App[T]) StartPublisher(ctx context.Context) (eventsourcing.PublisherStatus, error) {
+	return a.publisher.HandleStart(ctx)
+}
+
+func (a *App[T]) StopPublisher(ctx context.Context) (eventsourcing.PublisherStatus, error) {
+	return a.publisher.HandleStop(ctx)
+}
+
+func (a *App[T]) RegisterWebhook(ctx context.Context, cmd usecase.RegisterWebhook) (eventsourcing.Webhook, error) {
+	return a.webhooks.HandleRegister(ctx, cmd)
+}
+
+func (a *App[T]) ListWebhooks(ctx context.Context) ([]eventsourcing.Webhook, error) {
+	return a.webhooks.HandleList(ctx)
+}
+
+func (a *App[T]) GetWebhook(ctx context.Context, id uuid.UUID) (eventsourcing.Webhook, error) {
+	return a.webhooks.HandleGet(ctx, id)
+}
+
+func (a *App[T]) PauseWebhook(ctx context.Context, id uuid.UUID) (eventsourcing.Webhook, error) {
+	return a.webhooks.HandlePause(ctx, id, true)
+}
+
+func (a *App[T]) ResumeWebhook(ctx context.Context, id uuid.UUID) (eventsourcing.Webhook, error) {
+	return a.webhooks.HandlePause(ctx, id, false)
+}
+
+func (a *App[T]) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return a.webhooks.HandleDelete(ctx, id)
+}
+
+func (a *App[T]) ListWebhookDeliveries(ctx context.Context, id uuid.UUID, limit int) ([]eventsourcing.WebhookDelivery, error) {
+	return a.webhooks.HandleListDeliveries(ctx, id, limit)
+}
+
+func (a *App[T]) ListWebhookPendingDeliveries(ctx context.Context, id uuid.UUID, limit int) ([]eventsourcing.WebhookPendingDelivery, error) {
+	return a.webhooks.HandleListPending(ctx, id, limit)
+}
+
+func (a *App[T]) DropWebhookPendingDelivery(ctx context.Context, id uuid.UUID, eventId uuid.UUID) error {
+	return a.webhooks.HandleDropPending(ctx, id, eventId)
+}
+
+func (a *App[T]) RetryWebhookPendingDelivery(ctx context.Context, id uuid.UUID, eventId uuid.UUID) error {
+	return a.webhooks.HandleRetryPending(ctx, id, eventId)
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+type DeadLettersHandler struct {
+	repo eventsourcing.DeadLetterRepository
+}
+
+// NewDeadLettersHandler returns a handler failing with eventsourcing.ErrDeadLetterUnsupported when repo does not support dead letters
+func NewDeadLettersHandler(repo eventsourcing.EventRepository) *DeadLettersHandler {
+	deadLetters, _ := repo.(eventsourcing.DeadLetterRepository)
+
+	return &DeadLettersHandler{
+		repo: deadLetters,
+	}
+}
+
+func (h *DeadLettersHandler) HandleList(ctx context.Context, aggregateType *eventsourcing.AggregateType) ([]eventsourcing.DeadLetter, error) {
+	if h.repo == nil {
+		return nil, eventsourcing.ErrDeadLetterUnsupported
+	}
+
+	deadLetters, err := h.repo.ListDeadLetters(ctx, aggregateType)
+	if err != nil {
+		return nil, fmt.Errorf("deadLettersHandler: failed to list dead letters: %w", err)
+	}
+
+	return deadLetters, nil
+}
+
+func (h *DeadLettersHandler) HandleGet(ctx context.Context, eventId uuid.UUID) (eventsourcing.DeadLetter, error) {
+	if h.repo == nil {
+		return eventsourcing.DeadLetter{}, eventsourcing.ErrDeadLetterUnsupported
+	}
+
+	deadLetter, err := h.repo.GetDeadLetter(ctx, eventId)
+	if err != nil {
+		return deadLetter, fmt.Errorf("deadLettersHandler: failed to get dead letter(%s): %w", eventId, err)
+	}
+
+	return deadLetter, nil
+}
+
+// HandleRetry puts the event back in the outbox, it is published again with the later events of its aggregate
+func (h *DeadLettersHandler) HandleRetry(ctx context.Context, eventId uuid.UUID) error {
+	if h.repo == nil {
+		return eventsourcing.ErrDeadLetterUnsupported
+	}
+
+	err := h.repo.RetryDeadLetter(ctx, eventId)
+	if err != nil {
+		return fmt.Errorf("deadLettersHandler: failed to retry dead letter(%s): %w", eventId, err)
+	}
+
+	return nil
+}
+
+// HandleDiscard drops the event without publishing it, the later events of its aggregate are published
+func (h *DeadLettersHandler) HandleDiscard(ctx context.Context, eventId uuid.UUID) error {
+	if h.repo == nil {
+		return eventsourcing.ErrDeadLetterUnsupported
+	}
+
+	err := h.repo.DiscardDeadLetter(ctx, eventId)
+	if err != nil {
+		return fmt.Errorf("deadLettersHandler: failed to discard dead letter(%s): %w", eventId, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+)
+
+type ListEventTypesHandler[T eventsourcing.Aggregate] struct {
+	registry eventsourcing.EventRegistry[T]
+}
+
+func NewListEventTypesHandler[T eventsourcing.Aggregate](registry eventsourcing.EventRegistry[T]) *ListEventTypesHandler[T] {
+	return &ListEventTypesHandler[T]{
+		registry: registry,
+	}
+}
+
+func (h *ListEventTypesHandler[T]) Handle(_ context.Context) ([]eventsourcing.EventSchema, error) {
+	schemas, err := eventsourcing.EventSchemas[T](h.registry)
+	if err != nil {
+		return nil, fmt.Errorf("listEventTypesHandler: failed to derive event schemas: %w", err)
+	}
+
+	return schemas, nil
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+)
+
+var ErrPublisherNotConfigured = errors.New("no publisher is configured")
+
+type PublisherHandler struct {
+	publisher eventsourcing.PublisherController
+}
+
+// NewPublisherHandler returns a handler failing with ErrPublisherNotConfigured when publisher is nil
+func NewPublisherHandler(publisher eventsourcing.PublisherController) *PublisherHandler {
+	return &PublisherHandler{
+		publisher: publisher,
+	}
+}
+
+func (h *PublisherHandler) HandleStatus() (eventsourcing.PublisherStatus, error) {
+	if h.publisher == nil {
+		return eventsourcing.PublisherStatus{}, ErrPublisherNotConfigured
+	}
+
+	return h.publisher.Status(), nil
+}
+
+func (h *PublisherHandler) HandleStart(ctx context.Context) (eventsourcing.PublisherStatus, error) {
+	if h.publisher == nil {
+		return eventsourcing.PublisherStatus{}, ErrPublisherNotConfigured
+	}
+
+	err := h.publisher.Start(ctx)
+	if err != nil {
+		return eventsourcing.PublisherStatus{}, fmt.Errorf("publisherHandler: failed to start publisher: %w", err)
+	}
+
+	return h.publisher.Status(), nil
+}
+
+// HandleStop returns once the publisher drained the outbox or ctx is done
+func (h *PublisherHandler) HandleStop(ctx context.Context) (eventsourcing.PublisherStatus, error) {
+	if h.publisher == nil {
+		return eventsourcing.PublisherStatus{}, ErrPublisherNotConfigured
+	}
+
+	err := h.publisher.Stop(ctx)
+	if err != nil {
+		return eventsourcing.PublisherStatus{}, fmt.Errorf("publisherHandler: failed to stop publisher: %w", err)
+	}
+
+	return h.publisher.Status(), nil
+}
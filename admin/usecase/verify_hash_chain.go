@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+type VerifyHashChainHandler struct {
+	verifier *eventsourcing.HashChainVerifier
+}
+
+func NewVerifyHashChainHandler(repo eventsourcing.EventRepository, batchSize int) *VerifyHashChainHandler {
+	return &VerifyHashChainHandler{
+		verifier: eventsourcing.NewHashChainVerifier(repo, batchSize),
+	}
+}
+
+// HandleAggregate verifies the hash chain of a single aggregate
+func (h *VerifyHashChainHandler) HandleAggregate(ctx context.Context, aggregateId uuid.UUID) (eventsourcing.HashChainReport, error) {
+	report, err := h.verifier.VerifyAggregate(ctx, aggregateId)
+	if err != nil {
+		return report, fmt.Errorf("verifyHashChainHandler: failed to verify aggregate(%s): %w", aggregateId, err)
+	}
+
+	return report, nil
+}
+
+// HandleAll verifies the hash chain of the whole event store
+func (h *VerifyHashChainHandler) HandleAll(ctx context.Context) (eventsourcing.HashChainReport, error) {
+	report, err := h.verifier.VerifyAll(ctx)
+	if err != nil {
+		return report, fmt.Errorf("verifyHashChainHandler: failed to verify event store: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookSecretSize = 32
+
+	DefaultWebhookDeliveriesLimit = 50
+)
+
+var ErrWebhooksNotConfigured = errors.New("no webhook repository is configured")
+
+type RegisterWebhook struct {
+	Url        string
+	EventTypes []eventsourcing.EventType
+	// Secret is generated when empty
+	Secret string
+}
+
+type WebhooksHandler struct {
+	repo eventsourcing.WebhookRepository
+}
+
+// NewWebhooksHandler returns a handler failing with ErrWebhooksNotConfigured when repo is nil
+func NewWebhooksHandler(repo eventsourcing.WebhookRepository) *WebhooksHandler {
+	return &WebhooksHandler{
+		repo: repo,
+	}
+}
+
+// HandleRegister creates a webhook for an absolute http(s) url, the returned webhook holds its secret
+func (h *WebhooksHandler) HandleRegister(ctx context.Context, cmd RegisterWebhook) (eventsourcing.Webhook, error) {
+	if h.repo == nil {
+		return eventsourcing.Webhook{}, ErrWebhooksNotConfigured
+	}
+
+	u, err := url.Parse(cmd.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return eventsourcing.Webhook{}, fmt.Errorf("%w: url(%s) must be an absolute http or https url", eventsourcing.ErrInvalidWebhook, cmd.Url)
+	}
+
+	secret := cmd.Secret
+	if secret == "" {
+		secret, err = newWebhookSecret()
+		if err != nil {
+			return eventsourcing.Webhook{}, err
+		}
+	}
+
+	webhook := eventsourcing.Webhook{
+		Id:         uuid.New(),
+		Url:        u.String(),
+		Secret:     secret,
+		EventTypes: cmd.EventTypes,
+		CreatedAt:  time.Now().UTC(),
+	}
+	err = h.repo.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return eventsourcing.Webhook{}, fmt.Errorf("webhooksHandler: failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (h *WebhooksHandler) HandleList(ctx context.Context) ([]eventsourcing.Webhook, error) {
+	if h.repo == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+
+	webhooks, err := h.repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("webhooksHandler: failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (h *WebhooksHandler) HandleGet(ctx context.Context, id uuid.UUID) (eventsourcing.Webhook, error) {
+	if h.repo == nil {
+		return eventsourcing.Webhook{}, ErrWebhooksNotConfigured
+	}
+
+	webhook, err := h.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return webhook, fmt.Errorf("webhooksHandler: failed to get webhook(%s): %w", id, err)
+	}
+
+	return webhook, nil
+}
+
+// HandlePause pauses or resumes the webhook and returns it
+func (h *WebhooksHandler) HandlePause(ctx context.Context, id uuid.UUID, paused bool) (eventsourcing.Webhook, error) {
+	if h.repo == nil {
+		return eventsourcing.Webhook{}, ErrWebhooksNotConfigured
+	}
+
+	err := h.repo.SetWebhookPaused(ctx, id, paused)
+	if err != nil {
+		return eventsourcing.Webhook{}, fmt.Errorf("webhooksHandler: failed to set webhook(%s) paused(%t): %w", id, paused, err)
+	}
+
+	return h.HandleGet(ctx, id)
+}
+
+func (h *WebhooksHandler) HandleDelete(ctx context.Context, id uuid.UUID) error {
+	if h.repo == nil {
+		return ErrWebhooksNotConfigured
+	}
+
+	err := h.repo.DeleteWebhook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("webhooksHandler: failed to delete webhook(%s): %w", id, err)
+	}
+
+	return nil
+}
+
+// HandleListDeliveries lists the latest deliveries of the webhook first, limit defaults to DefaultWebhookDeliveriesLimit
+func (h *WebhooksHandler) HandleListDeliveries(ctx context.Context, id uuid.UUID, limit int) ([]eventsourcing.WebhookDelivery, error) {
+	if h.repo == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveriesLimit
+	}
+
+	// an unknown webhook is reported as not found rather than as having no deliveries
+	_, err := h.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("webhooksHandler: failed to get webhook(%s): %w", id, err)
+	}
+
+	deliveries, err := h.repo.ListDeliveries(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("webhooksHandler: failed to list deliveries of webhook(%s): %w", id, err)
+	}
+
+	return deliveries, nil
+}
+
+// HandleListPending lists the deliveries pending for the webhook in delivery order, limit defaults to DefaultWebhookDeliveriesLimit
+func (h *WebhooksHandler) HandleListPending(ctx context.Context, id uuid.UUID, limit int) ([]eventsourcing.WebhookPendingDelivery, error) {
+	if h.repo == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveriesLimit
+	}
+
+	_, err := h.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("webhooksHandler: failed to get webhook(%s): %w", id, err)
+	}
+
+	pending, err := h.repo.ListPendingDeliveries(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("webhooksHandler: failed to list pending deliveries of webhook(%s): %w", id, err)
+	}
+
+	return pending, nil
+}
+
+// HandleDropPending removes a pending delivery without delivering it, releasing the deliveries held behind it
+func (h *WebhooksHandler) HandleDropPending(ctx context.Context, id uuid.UUID, eventId uuid.UUID) error {
+	if h.repo == nil {
+		return ErrWebhooksNotConfigured
+	}
+
+	// an unknown delivery is reported as not found rather than silently ignored
+	_, err := h.repo.GetPendingDelivery(ctx, id, eventId)
+	if err != nil {
+		return fmt.Errorf("webhooksHandler: failed to get pending delivery of event(%s) to webhook(%s): %w", eventId, id, err)
+	}
+
+	err = h.repo.DeletePendingDelivery(ctx, id, eventId)
+	if err != nil {
+		return fmt.Errorf("webhooksHandler: failed to drop pending delivery of event(%s) to webhook(%s): %w", eventId, id, err)
+	}
+
+	return nil
+}
+
+// HandleRetryPending resets the attempts of a pending delivery so that the publisher delivers it again right away
+func (h *WebhooksHandler) HandleRetryPending(ctx context.Context, id uuid.UUID, eventId uuid.UUID) error {
+	if h.repo == nil {
+		return ErrWebhooksNotConfigured
+	}
+
+	delivery, err := h.repo.GetPendingDelivery(ctx, id, eventId)
+	if err != nil {
+		return fmt.Errorf("webhooksHandler: failed to get pending delivery of event(%s) to webhook(%s): %w", eventId, id, err)
+	}
+
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Time{}
+	err = h.repo.UpdatePendingDelivery(ctx, delivery)
+	if err != nil {
+		return fmt.Errorf("webhooksHandler: failed to retry pending delivery of event(%s) to webhook(%s): %w", eventId, id, err)
+	}
+
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("webhooksHandler: failed to generate secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
@@ -27,7 +27,12 @@ type AggregateBase[T Aggregate] struct {
 }
 
 func NewAggregateBase[T Aggregate](aggregateId uuid.UUID, version int) *AggregateBase[T] {
-	now := time.Now().UTC()
+	return NewAggregateBaseWith[T](SystemClock{}, aggregateId, version)
+}
+
+// NewAggregateBaseWith creates an aggregate base which creation time is provided by the given clock
+func NewAggregateBaseWith[T Aggregate](clock Clock, aggregateId uuid.UUID, version int) *AggregateBase[T] {
+	now := clock.Now()
 	return &AggregateBase[T]{
 		aggregateId:      aggregateId,
 		aggregateVersion: version,
@@ -53,6 +58,13 @@ func (a AggregateBase[T]) AggregateId() uuid.UUID {
 	return a.aggregateId
 }
 
+// resetClock sets the creation time of an aggregate created by a factory from clock, so that replays are deterministic
+func (a *AggregateBase[T]) resetClock(clock Clock) {
+	now := clock.Now()
+	a.createdAt = now
+	a.updatedAt = now
+}
+
 // Init is used to initialize an aggregate from an event
 func (a *AggregateBase[T]) Init(e Event[T]) {
 	a.aggregateId = e.AggregateId()
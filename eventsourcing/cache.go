@@ -11,6 +11,7 @@ import (
 type Cache[K comparable, V any] interface {
 	Add(key K, value V) bool
 	Get(key K) (V, bool)
+	Remove(key K) bool
 }
 
 type CacheOption struct {
@@ -58,6 +59,10 @@ func (c *noopCache[K, V]) Get(key K) (V, bool) {
 	return v, false
 }
 
+func (c *noopCache[K, V]) Remove(key K) bool {
+	return false
+}
+
 type cacheLogger[K comparable, V any] struct {
 	cache Cache[K, V]
 }
@@ -84,3 +89,10 @@ func (c *cacheLogger[K, V]) Get(key K) (V, bool) {
 
 	return v, ok
 }
+
+func (c *cacheLogger[K, V]) Remove(key K) bool {
+	log.Info().
+		Str("key", fmt.Sprintf("%v", key)).
+		Msg("cache remove")
+	return c.cache.Remove(key)
+}
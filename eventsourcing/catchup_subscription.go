@@ -0,0 +1,344 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultGapTimeout is long enough for the transactions inserting events to commit
+const DefaultGapTimeout = 5 * time.Second
+
+// CatchUpSubscription reads the events of an aggregate type from the event repository, starting after its checkpoint
+// it handles the stored events in order, then tails the new ones, independently of the outbox and of the other subscriptions
+// events are handled at least once: the checkpoint is saved after each batch, the events of a batch may be handled again after a crash
+//
+// in pg, global positions are taken from a sequence when events are inserted, a transaction committing late can make
+// an event visible after later positions were handled, the subscription waits for such gaps, see CatchUpSubscriptionWithGapTimeout
+type CatchUpSubscription[T Aggregate] struct {
+	name          string
+	eventRepo     EventRepository
+	checkpoints   CheckpointStore
+	eventRegistry EventRegistry[T]
+	aggregateType AggregateType
+	userFactory   UserFactory
+	handle        func(ctx context.Context, e Event[T]) error
+	filter        SubscriptionFilter
+	options       catchUpSubscriptionOptions
+
+	position atomic.Int64
+	live     atomic.Bool
+	// gap is only used by the running goroutine
+	gap positionGap
+}
+
+type catchUpSubscriptionOptions struct {
+	batchSize          int
+	pollInterval       time.Duration
+	backoff            PublisherBackoff
+	onError            func(ctx context.Context, err error)
+	unknownEventPolicy UnknownEventPolicy
+	outboxNotifier     OutboxNotifier
+	gapTimeout         time.Duration
+	filter             []SubscribeOption
+}
+
+type CatchUpSubscriptionOption func(*catchUpSubscriptionOptions)
+
+// CatchUpSubscriptionWithBatchSize sets the maximum number of events read at once, defaults to DefaultPublisherBatchSize
+func CatchUpSubscriptionWithBatchSize(batchSize int) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		if batchSize > 0 {
+			o.batchSize = batchSize
+		}
+	}
+}
+
+// CatchUpSubscriptionWithPollInterval sets the wait before reading new events once the subscription is live
+func CatchUpSubscriptionWithPollInterval(pollInterval time.Duration) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		if pollInterval > 0 {
+			o.pollInterval = pollInterval
+		}
+	}
+}
+
+// CatchUpSubscriptionWithBackoff delays the retries of the events which failed to be handled
+func CatchUpSubscriptionWithBackoff(backoff PublisherBackoff) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		o.backoff = backoff
+	}
+}
+
+// CatchUpSubscriptionWithOnError registers a hook called with every error of the subscription
+// it is called from the subscription goroutine and must not block
+func CatchUpSubscriptionWithOnError(onError func(ctx context.Context, err error)) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		o.onError = onError
+	}
+}
+
+// CatchUpSubscriptionWithUnknownEventPolicy defines how events of an unregistered type are handled, defaults to UnknownEventStrict
+// skipped events move the checkpoint forward, with UnknownEventStrict they are retried until the type is registered
+func CatchUpSubscriptionWithUnknownEventPolicy(policy UnknownEventPolicy) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		o.unknownEventPolicy = policy
+	}
+}
+
+// CatchUpSubscriptionWithOutboxNotifier reads new events on outbox notifications instead of waiting for the poll interval
+// events saved without the outbox are not notified, they are read by the next poll
+func CatchUpSubscriptionWithOutboxNotifier(notifier OutboxNotifier) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		o.outboxNotifier = notifier
+	}
+}
+
+// CatchUpSubscriptionWithFilter handles only the events matching the options, the other ones move the checkpoint forward
+func CatchUpSubscriptionWithFilter(opts ...SubscribeOption) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		o.filter = append(o.filter, opts...)
+	}
+}
+
+// CatchUpSubscriptionWithGapTimeout waits up to timeout for the missing positions between two events to become visible, defaults to DefaultGapTimeout
+// sequences leave gaps on rollbacks, the gaps which are not filled after timeout are skipped
+// the events of every aggregate type are read to detect gaps, those of other types are ignored
+// a timeout <= 0 disables the detection, it is only safe for repositories assigning positions in commit order such as sqlite
+func CatchUpSubscriptionWithGapTimeout(timeout time.Duration) CatchUpSubscriptionOption {
+	return func(o *catchUpSubscriptionOptions) {
+		o.gapTimeout = timeout
+	}
+}
+
+// NewCatchUpSubscription returns a subscription to the events of an aggregate type, its position is stored in checkpoints under name
+// events are handled one at a time, an event which fails to be handled is retried with a backoff before the next ones
+func NewCatchUpSubscription[T Aggregate](name string, eventRepo EventRepository, checkpoints CheckpointStore, eventRegistry EventRegistry[T], aggregateType AggregateType, userFactory UserFactory, handle func(ctx context.Context, e Event[T]) error, opts ...CatchUpSubscriptionOption) *CatchUpSubscription[T] {
+	options := catchUpSubscriptionOptions{
+		batchSize:    DefaultPublisherBatchSize,
+		pollInterval: DefaultPublisherPollInterval,
+		gapTimeout:   DefaultGapTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &CatchUpSubscription[T]{
+		name:          name,
+		eventRepo:     eventRepo,
+		checkpoints:   checkpoints,
+		eventRegistry: eventRegistry,
+		aggregateType: aggregateType,
+		userFactory:   userFactory,
+		handle:        handle,
+		filter:        NewSubscriptionFilter(options.filter...),
+		options:       options,
+	}
+}
+
+func (s *CatchUpSubscription[T]) Name() string {
+	return s.name
+}
+
+// Position returns the global position of the last event handled
+func (s *CatchUpSubscription[T]) Position() int64 {
+	return s.position.Load()
+}
+
+// Live tells if the subscription caught up with the stored events and now tails the new ones
+func (s *CatchUpSubscription[T]) Live() bool {
+	return s.live.Load()
+}
+
+// Run handles the events after the checkpoint until ctx is done, it only returns an error when the checkpoint can not be read
+// or with ErrOutboxNotifierClosed when the outbox notifier stops notifying
+func (s *CatchUpSubscription[T]) Run(ctx context.Context) error {
+	position, err := s.checkpoints.GetCheckpoint(ctx, s.name)
+	if err != nil {
+		return fmt.Errorf("failed to get checkpoint of subscription(%s): %w", s.name, err)
+	}
+	s.position.Store(position)
+	s.live.Store(false)
+
+	wakeUps := s.listen(ctx)
+	retryBackOff := s.options.backoff.newBackOff(s.options.pollInterval)
+	log.Ctx(ctx).
+		Debug().
+		Str("subscription", s.name).
+		Str("aggregate_type", string(s.aggregateType)).
+		Int64("position", position).
+		Msg("catch-up subscription: started")
+
+	wait := time.NewTimer(0)
+	defer wait.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-wakeUps:
+			if !ok {
+				// the notifier closes the channel once ctx is done
+				if ctx.Err() != nil {
+					return nil
+				}
+				return ErrOutboxNotifierClosed
+			}
+		case <-wait.C:
+		}
+
+		more, batchErr := s.processBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// full batches are followed right away by the next one
+		var next time.Duration
+		switch {
+		case batchErr != nil:
+			s.reportError(ctx, batchErr)
+			next = retryBackOff.NextBackOff()
+		case more:
+			retryBackOff.Reset()
+		default:
+			retryBackOff.Reset()
+			if !s.live.Swap(true) {
+				log.Ctx(ctx).Debug().Str("subscription", s.name).Int64("position", s.position.Load()).Msg("catch-up subscription: live")
+			}
+			next = s.options.pollInterval
+		}
+
+		resetTimer(wait, next)
+	}
+}
+
+// listen returns the outbox notifications or a nil channel, which never receives, when there is no notifier
+func (s *CatchUpSubscription[T]) listen(ctx context.Context) <-chan struct{} {
+	if s.options.outboxNotifier == nil {
+		return nil
+	}
+
+	wakeUps, err := s.options.outboxNotifier.Listen(ctx, s.aggregateType)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("subscription", s.name).Msg("catch-up subscription: failed to listen to outbox notifications, falling back to polling")
+		return nil
+	}
+
+	return wakeUps
+}
+
+// processBatch handles the events following the position and saves the checkpoint, more is true when the batch was full
+func (s *CatchUpSubscription[T]) processBatch(ctx context.Context) (more bool, err error) {
+	from := s.position.Load()
+	query := []EventQueryOption{
+		EventQueryWithAfterPosition(from),
+		EventQueryWithOrderByPosition(ASC),
+		EventQueryWithLimit(s.options.batchSize),
+	}
+	if s.options.gapTimeout <= 0 {
+		query = append(query, EventQueryWithAggregateType(s.aggregateType))
+	}
+
+	internalEvents, err := s.eventRepo.Get(ctx, NewEventQuery(query...))
+	if err != nil {
+		return false, fmt.Errorf("failed to get events after position(%d): %w", from, err)
+	}
+	defer s.saveCheckpoint(ctx, from)
+
+	for _, internalEvent := range internalEvents {
+		if s.gapPending(ctx, internalEvent) {
+			return false, nil
+		}
+
+		err = s.handleEvent(ctx, internalEvent)
+		if err != nil {
+			return false, err
+		}
+		s.position.Store(internalEvent.GlobalPosition)
+	}
+
+	return len(internalEvents) == s.options.batchSize, nil
+}
+
+// gapPending tells if positions are missing before the event and may still be filled by a transaction committing late
+func (s *CatchUpSubscription[T]) gapPending(ctx context.Context, internalEvent EventInternal) bool {
+	return s.gap.pending(s.position.Load(), internalEvent, s.options.gapTimeout, log.Ctx(ctx).With().Str("subscription", s.name).Logger())
+}
+
+func (s *CatchUpSubscription[T]) handleEvent(ctx context.Context, internalEvent EventInternal) error {
+	if internalEvent.AggregateType != s.aggregateType {
+		return nil
+	}
+
+	events, err := FromEventInternalSliceWithPolicy[T]([]EventInternal{internalEvent}, s.eventRegistry, s.userFactory, s.options.unknownEventPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to convert event(%s): %w", internalEvent.EventId, err)
+	}
+
+	for _, event := range events {
+		if !s.filter.Match(event) {
+			continue
+		}
+
+		err = s.handle(ctx, event)
+		if err != nil {
+			return fmt.Errorf("failed to handle event(%s) at position(%d): %w", event.Id(), internalEvent.GlobalPosition, err)
+		}
+	}
+
+	return nil
+}
+
+// saveCheckpoint stores the position reached since from, even when ctx is done so that handled events are not replayed
+func (s *CatchUpSubscription[T]) saveCheckpoint(ctx context.Context, from int64) {
+	position := s.position.Load()
+	if position == from {
+		return
+	}
+
+	err := s.checkpoints.SaveCheckpoint(context.WithoutCancel(ctx), s.name, position)
+	if err != nil {
+		s.reportError(ctx, fmt.Errorf("failed to save checkpoint(%d) of subscription(%s): %w", position, s.name, err))
+	}
+}
+
+func (s *CatchUpSubscription[T]) reportError(ctx context.Context, err error) {
+	log.Ctx(ctx).Error().Err(err).Str("subscription", s.name).Msg("catch-up subscription: failed to process batch")
+	if s.options.onError != nil {
+		s.options.onError(ctx, err)
+	}
+}
+
+// positionGap tracks the missing positions following a handled position
+type positionGap struct {
+	after int64
+	since time.Time
+}
+
+// pending tells if positions are missing between position and the event and should still be waited for
+// a gap is aged from the time it was first seen by this process, the issue time of the events depends on the clocks of the writers
+func (g *positionGap) pending(position int64, internalEvent EventInternal, timeout time.Duration, logger zerolog.Logger) bool {
+	if timeout <= 0 || internalEvent.GlobalPosition == position+1 {
+		return false
+	}
+
+	if g.since.IsZero() || g.after != position {
+		g.after = position
+		g.since = time.Now()
+	}
+	if time.Since(g.since) < timeout {
+		return true
+	}
+
+	logger.Warn().
+		Int64("from", position+1).
+		Int64("to", internalEvent.GlobalPosition-1).
+		Msg("skipping missing positions")
+	g.since = time.Time{}
+
+	return false
+}
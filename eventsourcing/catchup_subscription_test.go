@@ -0,0 +1,250 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHandler struct {
+	mtx      sync.Mutex
+	ids      []uuid.UUID
+	failures map[uuid.UUID]int
+}
+
+func (h *recordingHandler) handle(_ context.Context, e eventsourcing.Event[signedAggregate]) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.failures[e.Id()] > 0 {
+		h.failures[e.Id()]--
+		return errors.New("failed to handle")
+	}
+
+	h.ids = append(h.ids, e.Id())
+	return nil
+}
+
+func (h *recordingHandler) handled() []uuid.UUID {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return append([]uuid.UUID{}, h.ids...)
+}
+
+func eventIds(events []eventsourcing.Event[signedAggregate]) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id())
+	}
+
+	return ids
+}
+
+func TestCatchUpSubscription(t *testing.T) {
+	repo := eventrepository.NewInMemoryEventRepository()
+	checkpoints := repo.(eventsourcing.CheckpointStore)
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, false)
+
+	run := func(t *testing.T, name string, handler *recordingHandler) (*eventsourcing.CatchUpSubscription[signedAggregate], func()) {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		subscription := eventsourcing.NewCatchUpSubscription[signedAggregate](
+			name, repo, checkpoints, registry, signedAggregateType, userFactory, handler.handle,
+			eventsourcing.CatchUpSubscriptionWithBatchSize(2),
+			eventsourcing.CatchUpSubscriptionWithPollInterval(10*time.Millisecond),
+			eventsourcing.CatchUpSubscriptionWithBackoff(eventsourcing.PublisherBackoff{Disabled: true}),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			assert.NoError(t, subscription.Run(ctx))
+			close(done)
+		}()
+
+		return subscription, func() {
+			cancel()
+			<-done
+		}
+	}
+
+	stored := newPublisherTestEvents(t, 5)
+	require.NoError(t, store.Store(context.Background(), stored...))
+
+	t.Run("replays the stored events then tails the new ones", func(t *testing.T) {
+		handler := &recordingHandler{}
+		subscription, stop := run(t, "replay", handler)
+		defer stop()
+
+		assert.Eventually(t, subscription.Live, time.Second, 5*time.Millisecond)
+		assert.Equal(t, eventIds(stored), handler.handled())
+
+		live := newPublisherTestEvents(t, 3)
+		require.NoError(t, store.Store(context.Background(), live...))
+		assert.Eventually(t, func() bool { return len(handler.handled()) == 8 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, append(eventIds(stored), eventIds(live)...), handler.handled())
+	})
+
+	t.Run("resumes from its checkpoint", func(t *testing.T) {
+		handler := &recordingHandler{}
+		subscription, stop := run(t, "resume", handler)
+		assert.Eventually(t, subscription.Live, time.Second, 5*time.Millisecond)
+		stop()
+
+		position, err := checkpoints.GetCheckpoint(context.Background(), "resume")
+		require.NoError(t, err)
+		assert.Equal(t, subscription.Position(), position)
+
+		later := newPublisherTestEvents(t, 2)
+		require.NoError(t, store.Store(context.Background(), later...))
+
+		handler = &recordingHandler{}
+		subscription, stop = run(t, "resume", handler)
+		defer stop()
+
+		assert.Eventually(t, subscription.Live, time.Second, 5*time.Millisecond)
+		assert.Equal(t, eventIds(later), handler.handled())
+	})
+
+	t.Run("retries failed events before the next ones", func(t *testing.T) {
+		events := newPublisherTestEvents(t, 3)
+		require.NoError(t, store.Store(context.Background(), events...))
+
+		handler := &recordingHandler{failures: map[uuid.UUID]int{stored[1].Id(): 2}}
+		subscription, stop := run(t, "retry", handler)
+		defer stop()
+
+		assert.Eventually(t, subscription.Live, time.Second, 5*time.Millisecond)
+		handled := handler.handled()
+		require.Len(t, handled, 13)
+		assert.Equal(t, eventIds(stored), handled[:5])
+		assert.Equal(t, eventIds(events), handled[10:])
+	})
+}
+
+// lateCommitRepository hides events as if their transactions were not committed yet
+type lateCommitRepository struct {
+	eventsourcing.EventRepository
+
+	mtx    sync.Mutex
+	hidden map[uuid.UUID]bool
+	// age moves the issue time of the returned events back
+	age time.Duration
+}
+
+func (r *lateCommitRepository) Get(ctx context.Context, query eventsourcing.EventQuery) ([]eventsourcing.EventInternal, error) {
+	events, err := r.EventRepository.Get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	visible := make([]eventsourcing.EventInternal, 0, len(events))
+	for _, e := range events {
+		if r.hidden[e.EventId] {
+			continue
+		}
+		e.EventIssuedAt = e.EventIssuedAt.Add(-r.age)
+		visible = append(visible, e)
+	}
+
+	return visible, nil
+}
+
+func (r *lateCommitRepository) commit(id uuid.UUID) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	delete(r.hidden, id)
+}
+
+func TestCatchUpSubscriptionGaps(t *testing.T) {
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+
+	run := func(t *testing.T, age time.Duration, opts ...eventsourcing.CatchUpSubscriptionOption) (*lateCommitRepository, []eventsourcing.Event[signedAggregate], *recordingHandler, func()) {
+		t.Helper()
+
+		inner := eventrepository.NewInMemoryEventRepository()
+		store := eventsourcing.NewEventStore[signedAggregate](inner, registry, userFactory, false)
+		events := newPublisherTestEvents(t, 3)
+		require.NoError(t, store.Store(context.Background(), events...))
+		repo := &lateCommitRepository{EventRepository: inner, hidden: map[uuid.UUID]bool{events[1].Id(): true}, age: age}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		handler := &recordingHandler{}
+		subscription := eventsourcing.NewCatchUpSubscription[signedAggregate](
+			"gaps", repo, inner.(eventsourcing.CheckpointStore), registry, signedAggregateType, userFactory, handler.handle,
+			append([]eventsourcing.CatchUpSubscriptionOption{
+				eventsourcing.CatchUpSubscriptionWithPollInterval(10 * time.Millisecond),
+				eventsourcing.CatchUpSubscriptionWithBackoff(eventsourcing.PublisherBackoff{Disabled: true}),
+			}, opts...)...,
+		)
+
+		done := make(chan struct{})
+		go func() {
+			assert.NoError(t, subscription.Run(ctx))
+			close(done)
+		}()
+
+		return repo, events, handler, func() {
+			cancel()
+			<-done
+		}
+	}
+
+	t.Run("waits for the events committed late by default", func(t *testing.T) {
+		repo, events, handler, stop := run(t, 0)
+		defer stop()
+
+		assert.Eventually(t, func() bool { return len(handler.handled()) == 1 }, time.Second, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, eventIds(events[:1]), handler.handled())
+
+		repo.commit(events[1].Id())
+		assert.Eventually(t, func() bool { return len(handler.handled()) == 3 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, eventIds(events), handler.handled())
+	})
+
+	t.Run("skips the gaps not filled after the timeout", func(t *testing.T) {
+		_, events, handler, stop := run(t, 0, eventsourcing.CatchUpSubscriptionWithGapTimeout(30*time.Millisecond))
+		defer stop()
+
+		assert.Eventually(t, func() bool { return len(handler.handled()) == 2 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []uuid.UUID{events[0].Id(), events[2].Id()}, handler.handled())
+	})
+
+	t.Run("waits for the gaps before events issued long ago", func(t *testing.T) {
+		// the clock of the writer is behind, the gap is only aged from the time it was seen
+		repo, events, handler, stop := run(t, time.Hour, eventsourcing.CatchUpSubscriptionWithGapTimeout(time.Second))
+		defer stop()
+
+		assert.Eventually(t, func() bool { return len(handler.handled()) == 1 }, time.Second, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, eventIds(events[:1]), handler.handled())
+
+		repo.commit(events[1].Id())
+		assert.Eventually(t, func() bool { return len(handler.handled()) == 3 }, time.Second, 5*time.Millisecond)
+	})
+}
@@ -0,0 +1,13 @@
+package eventsourcing
+
+import (
+	"context"
+)
+
+// CheckpointStore is implemented by event repositories which store the positions of catch-up subscriptions
+type CheckpointStore interface {
+	// GetCheckpoint returns the global position of the last event handled by the subscription, 0 when it has no checkpoint
+	GetCheckpoint(ctx context.Context, name string) (int64, error)
+	// SaveCheckpoint stores the position of the subscription, replacing the previous one
+	SaveCheckpoint(ctx context.Context, name string, position int64) error
+}
@@ -0,0 +1,55 @@
+package eventsourcing
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to events, commands and aggregates
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock returns the current UTC time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// FakeClock is a manually driven clock for tests and deterministic replays
+type FakeClock struct {
+	now time.Time
+	mtx sync.RWMutex
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.now
+}
+
+// Set moves the clock to the given time
+func (c *FakeClock) Set(now time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock forward by the given duration and returns the new time
+func (c *FakeClock) Advance(d time.Duration) time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.now = c.now.Add(d)
+
+	return c.now
+}
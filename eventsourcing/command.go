@@ -28,11 +28,16 @@ type CommandBase[T Aggregate] struct {
 }
 
 func NewCommandBase[T Aggregate](aggregateId uuid.UUID, aggregateType AggregateType, issuedBy User) CommandBase[T] {
+	return NewCommandBaseWith[T](SystemClock{}, aggregateId, aggregateType, issuedBy)
+}
+
+// NewCommandBaseWith creates a command base which creation time is provided by the given clock
+func NewCommandBaseWith[T Aggregate](clock Clock, aggregateId uuid.UUID, aggregateType AggregateType, issuedBy User) CommandBase[T] {
 	return CommandBase[T]{
 		BCAggregateId:   aggregateId,
 		BCAggregateType: aggregateType,
 		BCIssuedBy:      issuedBy,
-		BCCreatedAt:     time.Now().UTC(),
+		BCCreatedAt:     clock.Now(),
 	}
 }
 
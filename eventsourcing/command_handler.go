@@ -41,18 +41,49 @@ type commandHandler[T Aggregate] struct {
 	eventStore EventStore[T]
 	factory    AggregateFactory[T]
 	cache      Cache[uuid.UUID, *T]
+	options    commandHandlerOptions
+}
+
+type commandHandlerOptions struct {
+	clock       Clock
+	idGenerator IDGenerator
+}
+
+type CommandHandlerOption func(*commandHandlerOptions)
+
+// CommandHandlerWithClock overrides the issue time of the events emitted by commands and sets the creation time of new aggregates
+// defaults to the clock of the event store (see EventStoreWithClock), by default events keep the time set when they were created
+func CommandHandlerWithClock(clock Clock) CommandHandlerOption {
+	return func(o *commandHandlerOptions) {
+		o.clock = clock
+	}
+}
+
+// CommandHandlerWithIDGenerator overrides the id of the events emitted by commands
+// by default events keep the id set when they were created
+func CommandHandlerWithIDGenerator(idGenerator IDGenerator) CommandHandlerOption {
+	return func(o *commandHandlerOptions) {
+		o.idGenerator = idGenerator
+	}
 }
 
 // NewCommandHandler creates a new command handler
 // cacheOption is used to configure the command handler cache and reduce the number of calls to the event store
 // if cacheOption.Disabled is set to true, the cache will be disabled
-func NewCommandHandler[T Aggregate](eventStore EventStore[T], factory AggregateFactory[T], cacheOption CacheOption) *commandHandler[T] {
+func NewCommandHandler[T Aggregate](eventStore EventStore[T], factory AggregateFactory[T], cacheOption CacheOption, opts ...CommandHandlerOption) *commandHandler[T] {
 	cmdHandler := &commandHandler[T]{
 		eventStore: eventStore,
 		factory:    factory,
 		cache:      NewCache[uuid.UUID, *T](cacheOption),
 	}
 
+	for _, opt := range opts {
+		opt(&cmdHandler.options)
+	}
+	if s, ok := eventStore.(interface{ configuredClock() Clock }); ok && cmdHandler.options.clock == nil {
+		cmdHandler.options.clock = s.configuredClock()
+	}
+
 	return cmdHandler
 }
 
@@ -75,15 +106,17 @@ func (h *commandHandler[T]) HandleCommand(ctx context.Context, c Command[T]) (*T
 		return new(T), fmt.Errorf("failed to apply events to aggregate(%s#%s): %w", c.AggregateType(), c.AggregateId(), err)
 	}
 
-	// update cache
-	h.cache.Add(c.AggregateId(), aggregate)
-
 	// persist and publish events
 	err = h.PersistEvents(ctx, events...)
 	if err != nil {
+		// the cached aggregate may be stale, e.g. after ErrConcurrencyConflict, it is loaded again by the next command
+		h.cache.Remove(c.AggregateId())
 		return new(T), fmt.Errorf("failed to persist and publish events for aggregate(%s#%s): %w", c.AggregateType(), c.AggregateId(), err)
 	}
 
+	// update cache
+	h.cache.Add(c.AggregateId(), aggregate)
+
 	// return aggregate
 	return aggregate, nil
 }
@@ -115,6 +148,9 @@ func (h *commandHandler[T]) HydrateAggregate(ctx context.Context, aggregateType
 func (h *commandHandler[T]) HydrateAggregateFromEvents(ctx context.Context, aggregateType AggregateType, events ...Event[T]) (*T, error) {
 	// create new aggregate
 	aggregate := h.factory()
+	if c, ok := any(aggregate).(interface{ resetClock(Clock) }); ok && h.options.clock != nil {
+		c.resetClock(h.options.clock)
+	}
 
 	// apply events
 	for _, event := range events {
@@ -136,6 +172,13 @@ func (h *commandHandler[T]) ApplyCommand(ctx context.Context, aggregate *T, c Co
 		return nil, fmt.Errorf("command (%T) is invalid for aggregate(%s#%s): %w", c, agg.AggregateType(), agg.AggregateId(), err)
 	}
 
+	// stamp events with the configured clock and id generator
+	if h.options.clock != nil || h.options.idGenerator != nil {
+		for _, event := range events {
+			stampEvent(event, h.options.clock, h.options.idGenerator)
+		}
+	}
+
 	// return events
 	return events, nil
 }
@@ -168,3 +211,26 @@ func (h *commandHandler[T]) PersistEvents(ctx context.Context, events ...Event[T
 
 	return nil
 }
+
+// stampEvent overrides the event id and issue time with the given generators, nil generators are ignored
+func stampEvent[T Aggregate](event Event[T], clock Clock, idGenerator IDGenerator) {
+	eventId := event.Id()
+	if idGenerator != nil {
+		eventId = idGenerator.NewID()
+	}
+
+	issuedAt := event.IssuedAt()
+	if clock != nil {
+		issuedAt = clock.Now()
+	}
+
+	event.SetBase(*NewEventBaseFromRepository[T](
+		eventId,
+		event.EventType(),
+		event.IssuedBy(),
+		issuedAt,
+		event.AggregateType(),
+		event.AggregateId(),
+		event.AggregateVersion(),
+	))
+}
@@ -0,0 +1,526 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultConsumerGroupPartitions        = 16
+	DefaultConsumerGroupHeartbeatInterval = 3 * time.Second
+	DefaultConsumerGroupLeaseTTL          = 10 * time.Second
+)
+
+// ConsumerGroupCoordinator tracks the live members of consumer groups and leases them the partitions of the group
+type ConsumerGroupCoordinator interface {
+	// Heartbeat renews the membership of the member and returns the partitions it leases for ttl
+	// the partitions assigned to other members are released, the assigned ones are leased once released or expired
+	Heartbeat(ctx context.Context, group string, member string, partitions int, ttl time.Duration) ([]int, error)
+	// Leave releases the partitions and the membership of the member
+	Leave(ctx context.Context, group string, member string) error
+}
+
+// PartitionOf returns the partition of an aggregate, all its events belong to the same partition
+func PartitionOf(aggregateId uuid.UUID, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write(aggregateId[:])
+
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// AssignPartitions spreads the partitions among the live members, every member computes the same assignment from the same members
+// it returns the partitions of member, used by coordinators to decide which partitions to release and to lease
+func AssignPartitions(members []string, member string, partitions int) []int {
+	sorted := slices.Clone(members)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	idx, ok := slices.BinarySearch(sorted, member)
+	if !ok {
+		return nil
+	}
+
+	assigned := make([]int, 0, partitions/len(sorted)+1)
+	for partition := idx; partition < partitions; partition += len(sorted) {
+		assigned = append(assigned, partition)
+	}
+
+	return assigned
+}
+
+// ConsumerGroup shares the events of an aggregate type among the members of a group, each running a ConsumerGroup with the same name
+// events are partitioned by aggregate id so that the events of an aggregate are handled in order by a single member at a time
+// every partition has its own checkpoint, a member leasing a partition resumes it where its previous owner stopped
+//
+// events are handled at least once, the partitions of a member which stopped without leaving are leased again once their lease expired
+// as CatchUpSubscription, it reads the event repository and is independent of the outbox, and waits for the events committed late
+type ConsumerGroup[T Aggregate] struct {
+	group         string
+	eventRepo     EventRepository
+	checkpoints   CheckpointStore
+	coordinator   ConsumerGroupCoordinator
+	eventRegistry EventRegistry[T]
+	aggregateType AggregateType
+	userFactory   UserFactory
+	options       consumerGroupOptions
+
+	mtx         sync.RWMutex
+	subscribers []consumerGroupSubscriber[T]
+	partitions  []int
+	// owned holds the checkpoint of the partitions leased by the member until leasedUntil, only used by the running goroutine
+	owned       map[int]int64
+	leasedUntil time.Time
+	gap         positionGap
+}
+
+type consumerGroupSubscriber[T Aggregate] struct {
+	sub    SubscribeFnV2[T]
+	filter SubscriptionFilter
+}
+
+type consumerGroupOptions struct {
+	member             string
+	partitions         int
+	heartbeatInterval  time.Duration
+	leaseTTL           time.Duration
+	batchSize          int
+	pollInterval       time.Duration
+	backoff            PublisherBackoff
+	onError            func(ctx context.Context, err error)
+	unknownEventPolicy UnknownEventPolicy
+	outboxNotifier     OutboxNotifier
+	gapTimeout         time.Duration
+}
+
+type ConsumerGroupOption func(*consumerGroupOptions)
+
+// ConsumerGroupWithMember sets the id of the member, it must be unique in the group and defaults to a random id
+func ConsumerGroupWithMember(member string) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		if member != "" {
+			o.member = member
+		}
+	}
+}
+
+// ConsumerGroupWithPartitions sets the number of partitions, it must be the same for all the members and must not change once the group started
+func ConsumerGroupWithPartitions(partitions int) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		if partitions > 0 {
+			o.partitions = partitions
+		}
+	}
+}
+
+// ConsumerGroupWithLease sets how often the member renews its lease and for how long, a batch must be handled within the lease
+func ConsumerGroupWithLease(heartbeatInterval time.Duration, ttl time.Duration) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		if heartbeatInterval > 0 {
+			o.heartbeatInterval = heartbeatInterval
+		}
+		if ttl > 0 {
+			o.leaseTTL = ttl
+		}
+	}
+}
+
+// ConsumerGroupWithBatchSize sets the maximum number of events read at once, defaults to DefaultPublisherBatchSize
+func ConsumerGroupWithBatchSize(batchSize int) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		if batchSize > 0 {
+			o.batchSize = batchSize
+		}
+	}
+}
+
+// ConsumerGroupWithPollInterval sets the wait before reading new events once the member caught up
+func ConsumerGroupWithPollInterval(pollInterval time.Duration) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		if pollInterval > 0 {
+			o.pollInterval = pollInterval
+		}
+	}
+}
+
+// ConsumerGroupWithBackoff delays the retries of the events which were negatively acknowledged
+func ConsumerGroupWithBackoff(backoff PublisherBackoff) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		o.backoff = backoff
+	}
+}
+
+// ConsumerGroupWithOnError registers a hook called with every error of the member
+// it is called from the member goroutine and must not block
+func ConsumerGroupWithOnError(onError func(ctx context.Context, err error)) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		o.onError = onError
+	}
+}
+
+// ConsumerGroupWithUnknownEventPolicy defines how events of an unregistered type are handled, defaults to UnknownEventStrict
+func ConsumerGroupWithUnknownEventPolicy(policy UnknownEventPolicy) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		o.unknownEventPolicy = policy
+	}
+}
+
+// ConsumerGroupWithOutboxNotifier reads new events on outbox notifications instead of waiting for the poll interval
+func ConsumerGroupWithOutboxNotifier(notifier OutboxNotifier) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		o.outboxNotifier = notifier
+	}
+}
+
+// ConsumerGroupWithGapTimeout waits up to timeout for the missing positions between two events to become visible, defaults to DefaultGapTimeout
+// see CatchUpSubscriptionWithGapTimeout
+func ConsumerGroupWithGapTimeout(timeout time.Duration) ConsumerGroupOption {
+	return func(o *consumerGroupOptions) {
+		o.gapTimeout = timeout
+	}
+}
+
+// NewConsumerGroup returns a member of the consumer group, its subscribers receive the events of the partitions leased by the member
+// the checkpoint of each partition is stored in checkpoints under the group name followed by the partition
+func NewConsumerGroup[T Aggregate](group string, eventRepo EventRepository, checkpoints CheckpointStore, coordinator ConsumerGroupCoordinator, eventRegistry EventRegistry[T], aggregateType AggregateType, userFactory UserFactory, opts ...ConsumerGroupOption) *ConsumerGroup[T] {
+	options := consumerGroupOptions{
+		member:            uuid.NewString(),
+		partitions:        DefaultConsumerGroupPartitions,
+		heartbeatInterval: DefaultConsumerGroupHeartbeatInterval,
+		leaseTTL:          DefaultConsumerGroupLeaseTTL,
+		batchSize:         DefaultPublisherBatchSize,
+		pollInterval:      DefaultPublisherPollInterval,
+		gapTimeout:        DefaultGapTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &ConsumerGroup[T]{
+		group:         group,
+		eventRepo:     eventRepo,
+		checkpoints:   checkpoints,
+		coordinator:   coordinator,
+		eventRegistry: eventRegistry,
+		aggregateType: aggregateType,
+		userFactory:   userFactory,
+		options:       options,
+	}
+}
+
+// Subscribe delivers to sub the events of the partitions leased by the member, sub must be subscribed before Run
+// the subscribers without acknowledgements, such as read models, subscribe through SubscriberV1FromV2
+// a negatively acknowledged event is delivered again to all the subscribers before the next events
+func (g *ConsumerGroup[T]) Subscribe(sub SubscribeFnV2[T], opts ...SubscribeOption) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.subscribers = append(g.subscribers, consumerGroupSubscriber[T]{sub: sub, filter: NewSubscriptionFilter(opts...)})
+}
+
+func (g *ConsumerGroup[T]) Member() string {
+	return g.options.member
+}
+
+// Partitions returns the sorted partitions leased by the member
+func (g *ConsumerGroup[T]) Partitions() []int {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	return slices.Clone(g.partitions)
+}
+
+// Run joins the group and handles the events of its partitions until ctx is done, then leaves the group
+// it returns ErrOutboxNotifierClosed when the outbox notifier stops notifying
+func (g *ConsumerGroup[T]) Run(ctx context.Context) error {
+	defer g.leave(ctx)
+
+	wakeUps := g.listen(ctx)
+	retryBackOff := g.options.backoff.newBackOff(g.options.pollInterval)
+	log.Ctx(ctx).
+		Debug().
+		Str("group", g.group).
+		Str("member", g.options.member).
+		Int("partitions", g.options.partitions).
+		Msg("consumer group: joining")
+
+	wait := time.NewTimer(0)
+	defer wait.Stop()
+
+	var heartbeatAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-wakeUps:
+			if !ok {
+				// the notifier closes the channel once ctx is done
+				if ctx.Err() != nil {
+					return nil
+				}
+				return ErrOutboxNotifierClosed
+			}
+		case <-wait.C:
+		}
+
+		// partitions are only released by heartbeats, after the checkpoints of the previous batch were saved
+		var heartbeatErr error
+		if time.Since(heartbeatAt) >= g.options.heartbeatInterval {
+			heartbeatErr = g.heartbeat(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if heartbeatErr != nil {
+				g.reportError(ctx, heartbeatErr)
+			} else {
+				heartbeatAt = time.Now()
+			}
+		}
+
+		more, batchErr := g.processBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// full batches are followed right away by the next one
+		var next time.Duration
+		switch {
+		case batchErr != nil || heartbeatErr != nil:
+			if batchErr != nil {
+				g.reportError(ctx, batchErr)
+			}
+			next = retryBackOff.NextBackOff()
+		case more:
+			retryBackOff.Reset()
+		default:
+			retryBackOff.Reset()
+			next = g.options.pollInterval
+		}
+		if heartbeatErr == nil {
+			next = min(next, max(g.options.heartbeatInterval-time.Since(heartbeatAt), 0))
+		}
+
+		resetTimer(wait, next)
+	}
+}
+
+// listen returns the outbox notifications or a nil channel, which never receives, when there is no notifier
+func (g *ConsumerGroup[T]) listen(ctx context.Context) <-chan struct{} {
+	if g.options.outboxNotifier == nil {
+		return nil
+	}
+
+	wakeUps, err := g.options.outboxNotifier.Listen(ctx, g.aggregateType)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("group", g.group).Msg("consumer group: failed to listen to outbox notifications, falling back to polling")
+		return nil
+	}
+
+	return wakeUps
+}
+
+// heartbeat renews the lease of the member and loads the checkpoints of the partitions it newly leased
+func (g *ConsumerGroup[T]) heartbeat(ctx context.Context) error {
+	// the lease may start as soon as the heartbeat is sent, its end is computed from the earliest start
+	leasedUntil := time.Now().Add(g.options.leaseTTL)
+	partitions, err := g.coordinator.Heartbeat(ctx, g.group, g.options.member, g.options.partitions, g.options.leaseTTL)
+	if err != nil {
+		return fmt.Errorf("failed to heartbeat consumer group(%s): %w", g.group, err)
+	}
+
+	owned := make(map[int]int64, len(partitions))
+	var checkpointErr error
+	for _, partition := range partitions {
+		position, ok := g.owned[partition]
+		if !ok {
+			position, err = g.checkpoints.GetCheckpoint(ctx, g.checkpointName(partition))
+			if err != nil {
+				// the partition stays leased and its checkpoint is read again by the next heartbeat
+				checkpointErr = fmt.Errorf("failed to get checkpoint of partition(%d): %w", partition, err)
+				continue
+			}
+		}
+		owned[partition] = position
+	}
+
+	leased := sortedPartitions(owned)
+	if !slices.Equal(leased, sortedPartitions(g.owned)) {
+		log.Ctx(ctx).Info().
+			Str("group", g.group).
+			Str("member", g.options.member).
+			Ints("partitions", leased).
+			Msg("consumer group: partitions rebalanced")
+	}
+
+	g.owned = owned
+	g.leasedUntil = leasedUntil
+	g.mtx.Lock()
+	g.partitions = leased
+	g.mtx.Unlock()
+
+	return checkpointErr
+}
+
+// processBatch handles the events of the leased partitions following their checkpoints, more is true when the batch was full
+func (g *ConsumerGroup[T]) processBatch(ctx context.Context) (bool, error) {
+	if len(g.owned) == 0 {
+		return false, nil
+	}
+	if time.Now().After(g.leasedUntil) {
+		// other members may lease the partitions, they are handled again once the member leases them back
+		g.owned = nil
+		g.mtx.Lock()
+		g.partitions = nil
+		g.mtx.Unlock()
+		return false, fmt.Errorf("lease of member(%s) expired", g.options.member)
+	}
+
+	saved := maps.Clone(g.owned)
+	from := int64(-1)
+	for _, position := range g.owned {
+		if from < 0 || position < from {
+			from = position
+		}
+	}
+	defer g.saveCheckpoints(ctx, saved)
+
+	query := []EventQueryOption{
+		EventQueryWithAfterPosition(from),
+		EventQueryWithOrderByPosition(ASC),
+		EventQueryWithLimit(g.options.batchSize),
+	}
+	if g.options.gapTimeout <= 0 {
+		query = append(query, EventQueryWithAggregateType(g.aggregateType))
+	}
+
+	internalEvents, err := g.eventRepo.Get(ctx, NewEventQuery(query...))
+	if err != nil {
+		return false, fmt.Errorf("failed to get events after position(%d): %w", from, err)
+	}
+
+	// reached is the position up to which all the events were read, it only moves past the gaps which are skipped
+	reached := from
+	logger := log.Ctx(ctx).With().Str("group", g.group).Str("member", g.options.member).Logger()
+	for _, internalEvent := range internalEvents {
+		if g.gap.pending(reached, internalEvent, g.options.gapTimeout, logger) {
+			break
+		}
+		reached = internalEvent.GlobalPosition
+		if internalEvent.AggregateType != g.aggregateType {
+			continue
+		}
+
+		partition := PartitionOf(internalEvent.AggregateId, g.options.partitions)
+		position, ok := g.owned[partition]
+		if !ok || internalEvent.GlobalPosition <= position {
+			continue
+		}
+
+		err = g.handleEvent(ctx, internalEvent)
+		if err != nil {
+			return false, err
+		}
+		g.owned[partition] = internalEvent.GlobalPosition
+	}
+
+	// the partitions without events in the batch are checkpointed where it was read up to so that they are not read again
+	for partition, position := range g.owned {
+		g.owned[partition] = max(position, reached)
+	}
+
+	return len(internalEvents) == g.options.batchSize && reached == internalEvents[len(internalEvents)-1].GlobalPosition, nil
+}
+
+func (g *ConsumerGroup[T]) handleEvent(ctx context.Context, internalEvent EventInternal) error {
+	events, err := FromEventInternalSliceWithPolicy[T]([]EventInternal{internalEvent}, g.eventRegistry, g.userFactory, g.options.unknownEventPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to convert event(%s): %w", internalEvent.EventId, err)
+	}
+
+	g.mtx.RLock()
+	subscribers := g.subscribers
+	g.mtx.RUnlock()
+
+	for _, event := range events {
+		for _, s := range subscribers {
+			if !s.filter.Match(event) {
+				continue
+			}
+
+			err = deliverMessage(ctx, s.sub, event)
+			if err != nil {
+				return fmt.Errorf("failed to handle event(%s) at position(%d): %w", event.Id(), internalEvent.GlobalPosition, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deliverMessage waits for the subscriber to acknowledge the event, which it may do after returning
+func deliverMessage[T Aggregate](ctx context.Context, sub SubscribeFnV2[T], event Event[T]) error {
+	settled := make(chan error, 1)
+	sub(ctx, NewMessage(event, func(err error) { settled <- err }))
+
+	select {
+	case err := <-settled:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// saveCheckpoints stores the checkpoints which moved since saved, even when ctx is done so that handled events are not replayed
+func (g *ConsumerGroup[T]) saveCheckpoints(ctx context.Context, saved map[int]int64) {
+	for partition, position := range g.owned {
+		if position == saved[partition] {
+			continue
+		}
+
+		err := g.checkpoints.SaveCheckpoint(context.WithoutCancel(ctx), g.checkpointName(partition), position)
+		if err != nil {
+			g.reportError(ctx, fmt.Errorf("failed to save checkpoint(%d) of partition(%d): %w", position, partition, err))
+		}
+	}
+}
+
+func (g *ConsumerGroup[T]) leave(ctx context.Context) {
+	err := g.coordinator.Leave(context.WithoutCancel(ctx), g.group, g.options.member)
+	if err != nil {
+		g.reportError(ctx, fmt.Errorf("failed to leave consumer group(%s): %w", g.group, err))
+	}
+
+	g.owned = nil
+	g.mtx.Lock()
+	g.partitions = nil
+	g.mtx.Unlock()
+	log.Ctx(ctx).Debug().Str("group", g.group).Str("member", g.options.member).Msg("consumer group: left")
+}
+
+func sortedPartitions(owned map[int]int64) []int {
+	partitions := make([]int, 0, len(owned))
+	for partition := range owned {
+		partitions = append(partitions, partition)
+	}
+	slices.Sort(partitions)
+
+	return partitions
+}
+
+func (g *ConsumerGroup[T]) checkpointName(partition int) string {
+	return fmt.Sprintf("%s/%d", g.group, partition)
+}
+
+func (g *ConsumerGroup[T]) reportError(ctx context.Context, err error) {
+	log.Ctx(ctx).Error().Err(err).Str("group", g.group).Str("member", g.options.member).Msg("consumer group: failed to process batch")
+	if g.options.onError != nil {
+		g.options.onError(ctx, err)
+	}
+}
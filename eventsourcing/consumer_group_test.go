@@ -0,0 +1,209 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignPartitions(t *testing.T) {
+	members := []string{"c", "a", "b"}
+
+	assert.Equal(t, []int{0, 3, 6}, eventsourcing.AssignPartitions(members, "a", 8))
+	assert.Equal(t, []int{1, 4, 7}, eventsourcing.AssignPartitions(members, "b", 8))
+	assert.Equal(t, []int{2, 5}, eventsourcing.AssignPartitions(members, "c", 8))
+	assert.Nil(t, eventsourcing.AssignPartitions(members, "unknown", 8))
+}
+
+func TestPartitionOf(t *testing.T) {
+	aggregateId := uuid.New()
+	partition := eventsourcing.PartitionOf(aggregateId, 8)
+
+	assert.Equal(t, partition, eventsourcing.PartitionOf(aggregateId, 8))
+	assert.GreaterOrEqual(t, partition, 0)
+	assert.Less(t, partition, 8)
+}
+
+// groupRecorder records the events handled by the members of a group
+type groupRecorder struct {
+	mtx       sync.Mutex
+	byMember  map[string][]eventsourcing.Event[signedAggregate]
+	aggregate map[uuid.UUID]string
+}
+
+func (r *groupRecorder) subscriber(member string) eventsourcing.SubscribeFnV2[signedAggregate] {
+	return func(_ context.Context, msg eventsourcing.Message[signedAggregate]) {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		r.byMember[member] = append(r.byMember[member], msg.Event())
+		msg.Ack()
+	}
+}
+
+func (r *groupRecorder) handled() map[uuid.UUID]int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	handled := make(map[uuid.UUID]int)
+	for _, events := range r.byMember {
+		for _, e := range events {
+			handled[e.Id()]++
+		}
+	}
+
+	return handled
+}
+
+func TestConsumerGroup(t *testing.T) {
+	repo := eventrepository.NewInMemoryEventRepository()
+	checkpoints := repo.(eventsourcing.CheckpointStore)
+	coordinator := eventrepository.NewInMemoryConsumerGroupCoordinator()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, false)
+	recorder := &groupRecorder{byMember: make(map[string][]eventsourcing.Event[signedAggregate])}
+
+	join := func(member string) (*eventsourcing.ConsumerGroup[signedAggregate], func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		group := eventsourcing.NewConsumerGroup[signedAggregate](
+			"projection", repo, checkpoints, coordinator, registry, signedAggregateType, userFactory,
+			eventsourcing.ConsumerGroupWithMember(member),
+			eventsourcing.ConsumerGroupWithPartitions(4),
+			eventsourcing.ConsumerGroupWithLease(10*time.Millisecond, time.Second),
+			eventsourcing.ConsumerGroupWithBatchSize(4),
+			eventsourcing.ConsumerGroupWithPollInterval(5*time.Millisecond),
+		)
+		group.Subscribe(recorder.subscriber(member))
+
+		done := make(chan struct{})
+		go func() {
+			assert.NoError(t, group.Run(ctx))
+			close(done)
+		}()
+
+		return group, func() {
+			cancel()
+			<-done
+		}
+	}
+
+	a, stopA := join("a")
+	b, stopB := join("b")
+	defer stopA()
+
+	var stored []eventsourcing.Event[signedAggregate]
+
+	t.Run("partitions are shared among the members", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			return len(a.Partitions()) == 2 && len(b.Partitions()) == 2
+		}, time.Second, 5*time.Millisecond)
+		assert.NotContains(t, a.Partitions(), b.Partitions()[0])
+		assert.NotContains(t, a.Partitions(), b.Partitions()[1])
+	})
+
+	for i := 0; i < 8; i++ {
+		events := newPublisherTestEvents(t, 3)
+		require.NoError(t, store.Store(context.Background(), events...))
+		stored = append(stored, events...)
+	}
+
+	t.Run("events are handled by the member of their partition in order", func(t *testing.T) {
+		assert.Eventually(t, func() bool { return len(recorder.handled()) == len(stored) }, time.Second, 5*time.Millisecond)
+
+		recorder.mtx.Lock()
+		defer recorder.mtx.Unlock()
+		for member, events := range recorder.byMember {
+			versions := make(map[uuid.UUID]int)
+			for _, e := range events {
+				assert.Equal(t, member == "a", containsPartition(a.Partitions(), e.AggregateId()), "event(%s) handled by member(%s)", e.Id(), member)
+				assert.Equal(t, versions[e.AggregateId()]+1, e.AggregateVersion())
+				versions[e.AggregateId()] = e.AggregateVersion()
+			}
+		}
+	})
+
+	t.Run("partitions of a leaving member are taken over from their checkpoint", func(t *testing.T) {
+		stopB()
+		assert.Eventually(t, func() bool { return len(a.Partitions()) == 4 }, time.Second, 5*time.Millisecond)
+
+		events := newPublisherTestEvents(t, 2)
+		require.NoError(t, store.Store(context.Background(), events...))
+		stored = append(stored, events...)
+
+		assert.Eventually(t, func() bool { return len(recorder.handled()) == len(stored) }, time.Second, 5*time.Millisecond)
+		for _, nb := range recorder.handled() {
+			assert.Equal(t, 1, nb)
+		}
+	})
+}
+
+func containsPartition(partitions []int, aggregateId uuid.UUID) bool {
+	partition := eventsourcing.PartitionOf(aggregateId, 4)
+	for _, p := range partitions {
+		if p == partition {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestConsumerGroupGaps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inner := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](inner, registry, userFactory, false)
+	events := newPublisherTestEvents(t, 3)
+	require.NoError(t, store.Store(ctx, events...))
+	repo := &lateCommitRepository{EventRepository: inner, hidden: map[uuid.UUID]bool{events[1].Id(): true}}
+
+	recorder := &groupRecorder{byMember: make(map[string][]eventsourcing.Event[signedAggregate])}
+	group := eventsourcing.NewConsumerGroup[signedAggregate](
+		"gaps", repo, inner.(eventsourcing.CheckpointStore), eventrepository.NewInMemoryConsumerGroupCoordinator(),
+		registry, signedAggregateType, userFactory,
+		eventsourcing.ConsumerGroupWithMember("a"),
+		eventsourcing.ConsumerGroupWithPartitions(2),
+		eventsourcing.ConsumerGroupWithPollInterval(5*time.Millisecond),
+	)
+	group.Subscribe(recorder.subscriber("a"))
+
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, group.Run(ctx))
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	assert.Eventually(t, func() bool { return len(recorder.handled()) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, recorder.handled(), 1)
+
+	repo.commit(events[1].Id())
+	assert.Eventually(t, func() bool { return len(recorder.handled()) == 3 }, time.Second, 5*time.Millisecond)
+
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	assert.Equal(t, eventIds(events), eventIds(recorder.byMember["a"]))
+}
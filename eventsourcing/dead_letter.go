@@ -0,0 +1,43 @@
+package eventsourcing
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxPublishAttempts is the number of failed publications after which an event is dead lettered
+const DefaultMaxPublishAttempts = 5
+
+// DeadLetter is an event which failed to be published too many times
+// it is not published anymore and holds back the later events of its aggregate until it is retried or discarded
+type DeadLetter struct {
+	Event          EventInternal
+	Attempts       int
+	Reason         string
+	DeadLetteredAt time.Time
+}
+
+// DeadLetterRepository is implemented by event repositories which count the failed publications of outbox events
+type DeadLetterRepository interface {
+	// MarkFailed records a failed publication of an outbox event and releases its lease
+	// the event is dead lettered once it failed maxAttempts times, deadLettered is then true
+	MarkFailed(ctx context.Context, event EventInternal, reason string, maxAttempts int) (deadLettered bool, err error)
+	// ListDeadLetters lists dead letters by global position, of every aggregate type when aggregateType is nil
+	ListDeadLetters(ctx context.Context, aggregateType *AggregateType) ([]DeadLetter, error)
+	// GetDeadLetter returns ErrDeadLetterNotFound when the event is not dead lettered
+	GetDeadLetter(ctx context.Context, eventId uuid.UUID) (DeadLetter, error)
+	// RetryDeadLetter puts the event back in the outbox with no failed attempt
+	RetryDeadLetter(ctx context.Context, eventId uuid.UUID) error
+	// DiscardDeadLetter marks the event as published without publishing it, releasing the later events of its aggregate
+	DiscardDeadLetter(ctx context.Context, eventId uuid.UUID) error
+}
+
+// EventStreamPublisherWithMaxAttempts sets the number of failed publications after which an event is dead lettered
+// dead letters require an event repository implementing DeadLetterRepository, a value of 0 or less retries events forever
+func EventStreamPublisherWithMaxAttempts(maxAttempts int) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		o.maxAttempts = maxAttempts
+	}
+}
@@ -3,8 +3,26 @@ package eventsourcing
 import "errors"
 
 var (
-	ErrAggregateAlreadyExists = errors.New("aggregate already exists")
-	ErrAggregateNotFound      = errors.New("aggregate not found")
-	ErrInvalidAggregateType   = errors.New("invalid aggregate type")
-	ErrUnknownEventType       = errors.New("unknown event type")
+	ErrAggregateAlreadyExists  = errors.New("aggregate already exists")
+	ErrAggregateNotFound       = errors.New("aggregate not found")
+	ErrInvalidAggregateType    = errors.New("invalid aggregate type")
+	ErrUnknownEventType        = errors.New("unknown event type")
+	ErrDuplicateEventType      = errors.New("duplicate event type")
+	ErrInvalidEventSignature   = errors.New("invalid event signature")
+	ErrUnknownIssuerKey        = errors.New("unknown issuer key")
+	ErrEventLogCorrupted       = errors.New("event log corrupted")
+	ErrEventAlreadyExists      = errors.New("event already exists")
+	ErrHashChainConflict       = errors.New("hash chain conflict")
+	ErrConcurrencyConflict     = errors.New("concurrency conflict")
+	ErrDeadLetterNotFound      = errors.New("dead letter not found")
+	ErrDeadLetterUnsupported   = errors.New("dead letters are not supported by the event repository")
+	ErrPublisherRunning        = errors.New("publisher is already running")
+	ErrPublisherNotRunning     = errors.New("publisher is not running")
+	ErrPublisherMaxRetries     = errors.New("publisher reached its max retries")
+	ErrDuplicatePublisherRoute = errors.New("duplicate publisher route")
+	ErrEventNacked             = errors.New("event negatively acknowledged")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhook          = errors.New("invalid webhook")
+	ErrOutboxNotifierClosed    = errors.New("outbox notifications channel closed")
 )
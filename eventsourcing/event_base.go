@@ -18,10 +18,15 @@ type EventBase[T Aggregate] struct {
 }
 
 func NewEventBase[T Aggregate](aggregateType AggregateType, aggregateVersion int, eventType EventType, aggregateId uuid.UUID, issuedBy User) *EventBase[T] {
+	return NewEventBaseWith[T](SystemClock{}, UUIDv4Generator{}, aggregateType, aggregateVersion, eventType, aggregateId, issuedBy)
+}
+
+// NewEventBaseWith creates an event base which id and issue time are provided by the given generators
+func NewEventBaseWith[T Aggregate](clock Clock, idGenerator IDGenerator, aggregateType AggregateType, aggregateVersion int, eventType EventType, aggregateId uuid.UUID, issuedBy User) *EventBase[T] {
 	return &EventBase[T]{
-		eventId:          uuid.New(),
+		eventId:          idGenerator.NewID(),
 		eventIssuedBy:    issuedBy,
-		eventIssuesAt:    time.Now().UTC(),
+		eventIssuesAt:    clock.Now(),
 		eventType:        eventType,
 		aggregateType:    aggregateType,
 		aggregateId:      aggregateId,
@@ -2,10 +2,12 @@ package eventsourcing
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 type EventInternal struct {
@@ -18,6 +20,16 @@ type EventInternal struct {
 	AggregateType    AggregateType
 	AggregateId      uuid.UUID
 	AggregateVersion int
+	// GlobalPosition is the monotonically increasing position of the event in the whole store
+	// it is assigned by the repository when the event is saved
+	GlobalPosition int64
+	// EventHash chains the event to the previous event of the same aggregate, see ComputeEventHash
+	// it is empty when the event store is not configured with a hash chain
+	EventHash         []byte
+	PreviousEventHash []byte
+	// EventSignature is the signature of the event digest by its issuer, see EventDigest
+	// it is empty when the event store is not configured with a signer
+	EventSignature []byte
 }
 
 func toEventInternalSlice[T Aggregate](events []Event[T]) ([]EventInternal, error) {
@@ -52,35 +64,61 @@ func toEventInternal[T Aggregate](e Event[T]) (EventInternal, error) {
 }
 
 func FromEventInternalSlice[T Aggregate](internalEvents []EventInternal, registry EventRegistry[T], userFactory UserFactory) ([]Event[T], error) {
+	return FromEventInternalSliceWithPolicy(internalEvents, registry, userFactory, UnknownEventStrict)
+}
+
+// FromEventInternalSliceWithPolicy hydrates events handling unregistered event types according to the given policy
+func FromEventInternalSliceWithPolicy[T Aggregate](internalEvents []EventInternal, registry EventRegistry[T], userFactory UserFactory, policy UnknownEventPolicy) ([]Event[T], error) {
 	events := make([]Event[T], 0, len(internalEvents))
 	for _, internalEvent := range internalEvents {
-		event, err := fromEventInternal(internalEvent, registry, userFactory)
+		event, err := fromEventInternal(internalEvent, registry, userFactory, policy)
 		if err != nil {
 			return nil, err
 		}
+		if event == nil {
+			continue
+		}
 		events = append(events, event)
 	}
 
 	return events, nil
 }
 
-func fromEventInternal[T Aggregate](internalEvent EventInternal, registry EventRegistry[T], userFactory UserFactory) (Event[T], error) {
+// fromEventInternal returns a nil event when it has been skipped according to the unknown event policy
+func fromEventInternal[T Aggregate](internalEvent EventInternal, registry EventRegistry[T], userFactory UserFactory, policy UnknownEventPolicy) (Event[T], error) {
 	issuedBy := userFactory()
 	err := issuedBy.FromString(internalEvent.EventIssuedBy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal user: %w", err)
 	}
 
-	return registry.Hydrate(
-		*NewEventBaseFromRepository[T](
-			internalEvent.EventId,
-			internalEvent.EventType,
-			issuedBy,
-			internalEvent.EventIssuedAt,
-			internalEvent.AggregateType,
-			internalEvent.AggregateId,
-			internalEvent.AggregateVersion,
-		),
-		internalEvent.EventData,
+	base := *NewEventBaseFromRepository[T](
+		internalEvent.EventId,
+		internalEvent.EventType,
+		issuedBy,
+		internalEvent.EventIssuedAt,
+		internalEvent.AggregateType,
+		internalEvent.AggregateId,
+		internalEvent.AggregateVersion,
 	)
+
+	event, err := registry.Hydrate(base, internalEvent.EventData)
+	if err == nil || !errors.Is(err, ErrUnknownEventType) {
+		return event, err
+	}
+
+	switch policy {
+	case UnknownEventSkip:
+		log.Warn().
+			Str("event_id", internalEvent.EventId.String()).
+			Str("event_type", internalEvent.EventType.String()).
+			Str("aggregate_type", string(internalEvent.AggregateType)).
+			Str("aggregate_id", internalEvent.AggregateId.String()).
+			Msg("skipping event of unknown type")
+		return nil, nil
+	case UnknownEventTombstone:
+		return NewUnknownEvent(base, internalEvent.EventData), nil
+	default:
+		return nil, err
+	}
 }
@@ -2,106 +2,116 @@ package eventsourcing
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"os"
-	"time"
 
-	"github.com/cenkalti/backoff/v4"
-	"github.com/rs/zerolog/log"
+	"github.com/google/uuid"
 )
 
+// EventStreamPublisher publishes the outbox of an aggregate type to a stream of hydrated events
 type EventStreamPublisher[T Aggregate] struct {
-	eventRepo     EventRepository
-	stream        Publisher[T]
-	eventRegistry EventRegistry[T]
-	aggregateType AggregateType
-	userFactory   UserFactory
-	batchSize     int
-	backoff       bool
+	*OutboxPublisher
 }
 
-func NewEventStreamPublisher[T Aggregate](eventRepo EventRepository, eventRegistry EventRegistry[T], aggregateType AggregateType, userFactory UserFactory, stream Publisher[T], batchSize int, backoff bool) *EventStreamPublisher[T] {
-	return &EventStreamPublisher[T]{
-		eventRepo:     eventRepo,
-		eventRegistry: eventRegistry,
-		aggregateType: aggregateType,
-		userFactory:   userFactory,
-		stream:        stream,
-		batchSize:     batchSize,
-		backoff:       backoff,
+// EventStreamPublisherWithUnknownEventPolicy defines how events of an unregistered type are handled before publishing
+// skipped events are marked as published with the rest of the batch, defaults to UnknownEventStrict
+func EventStreamPublisherWithUnknownEventPolicy(policy UnknownEventPolicy) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		o.unknownEventPolicy = policy
 	}
 }
 
-func (p *EventStreamPublisher[T]) Run(ctx context.Context) {
-	var b backoff.BackOff
-	if !p.backoff {
-		b = backoff.NewConstantBackOff(0 * time.Millisecond)
-		log.Ctx(ctx).
-			Warn().
-			Msg("event publisher: backoff disabled")
-	} else {
-		b = backoff.WithMaxRetries(
-			backoff.WithContext(
-				backoff.NewExponentialBackOff(),
-				ctx,
-			),
-			5,
-		)
-		log.Ctx(ctx).
-			Debug().
-			Int("max_retries", 5).
-			Msg("event publisher: explonential backoff enabled")
+// EventStreamPublisherWithSignatureVerification verifies the signature of events when they are hydrated before publishing
+// with SignatureVerificationFail, an event which fails the verification is not published, it is retried and dead lettered
+func EventStreamPublisherWithSignatureVerification(keys KeyRegistry, mode SignatureVerificationMode) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		o.keys = keys
+		o.verificationMode = mode
 	}
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			_ = backoff.Retry(func() error {
-				nb, err := p.processBatch(ctx)
-				if err != nil {
-					//! WARN: errors are not properly propagated and logged
-					log.Ctx(ctx).Error().Err(err).Msg("event publisher: failed to process batch")
-					return err
-				}
-
-				if nb == 0 {
-					return errors.New("no events to publish")
-				}
-
-				return nil
-			}, b)
-		}
-	}
+// NewEventStreamPublisher returns a publisher of the outbox of an aggregate type
+// failed batches are retried with an exponential backoff unless backoff is false, EventStreamPublisherWithOptions overrides both
+func NewEventStreamPublisher[T Aggregate](eventRepo EventRepository, eventRegistry EventRegistry[T], aggregateType AggregateType, userFactory UserFactory, stream Publisher[T], batchSize int, backoff bool, opts ...EventStreamPublisherOption) *EventStreamPublisher[T] {
+	p := newOutboxPublisher(
+		eventRepo,
+		aggregateType,
+		eventStreamPublisherOptions{
+			batchSize: batchSize,
+			backoff:   PublisherBackoff{Disabled: !backoff},
+		},
+		opts,
+	)
+	RouteEventStream[T](p, aggregateType, eventRegistry, userFactory, stream)
+
+	return &EventStreamPublisher[T]{OutboxPublisher: p}
 }
 
-func (p *EventStreamPublisher[T]) processBatch(ctx context.Context) (int, error) {
-	internalEvents, err := p.eventRepo.GetUnpublished(ctx, p.aggregateType, p.batchSize)
-	if err != nil {
-		return -1, err
-	}
+// NewEventStreamPublisherV2 returns a publisher of the outbox of an aggregate type to a stream reporting the failures of its subscribers
+// negatively acknowledged events are retried and dead lettered as the events which failed to be published
+func NewEventStreamPublisherV2[T Aggregate](eventRepo EventRepository, eventRegistry EventRegistry[T], aggregateType AggregateType, userFactory UserFactory, stream PublisherV2[T], opts ...EventStreamPublisherOption) *EventStreamPublisher[T] {
+	p := newOutboxPublisher(eventRepo, aggregateType, eventStreamPublisherOptions{}, opts)
+	RouteEventStreamV2[T](p, aggregateType, eventRegistry, userFactory, stream)
 
-	if len(internalEvents) == 0 {
-		return 0, nil
-	}
+	return &EventStreamPublisher[T]{OutboxPublisher: p}
+}
 
-	events, err := FromEventInternalSlice[T](internalEvents, p.eventRegistry, p.userFactory)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "event publisher: failed to convert internal events to events: (%p) %v\n", p.eventRegistry, err)
-		return -1, fmt.Errorf("event publisher: failed to convert internal events to events: %w", err)
+// RouteEventStream hydrates the events of the aggregate type with the registry and publishes them to stream
+// it panics when the aggregate type is already routed or not published by p
+func RouteEventStream[T Aggregate](p *OutboxPublisher, aggregateType AggregateType, eventRegistry EventRegistry[T], userFactory UserFactory, stream Publisher[T]) {
+	RouteEventStreamV2[T](p, aggregateType, eventRegistry, userFactory, PublisherV2FromV1(stream))
+}
+
+// RouteEventStreamV2 is RouteEventStream for a stream reporting the failures of its subscribers
+func RouteEventStreamV2[T Aggregate](p *OutboxPublisher, aggregateType AggregateType, eventRegistry EventRegistry[T], userFactory UserFactory, stream PublisherV2[T]) {
+	p.addRoute(aggregateType, streamRoute[T]{
+		eventRegistry:      eventRegistry,
+		userFactory:        userFactory,
+		stream:             stream,
+		unknownEventPolicy: p.options.unknownEventPolicy,
+		keys:               p.options.keys,
+		verificationMode:   p.options.verificationMode,
+	})
+}
+
+type streamRoute[T Aggregate] struct {
+	eventRegistry      EventRegistry[T]
+	userFactory        UserFactory
+	stream             PublisherV2[T]
+	unknownEventPolicy UnknownEventPolicy
+	keys               KeyRegistry
+	verificationMode   SignatureVerificationMode
+}
+
+func (r streamRoute[T]) publish(ctx context.Context, internalEvents []EventInternal) (int, int, error) {
+	// converted[i] holds the event of internalEvents[i], it is empty when the event is skipped by the unknown event policy
+	converted := make([][]Event[T], 0, len(internalEvents))
+	var failure error
+	for _, internalEvent := range internalEvents {
+		if r.keys != nil {
+			err := verifyEventSignatures(ctx, r.keys, r.verificationMode, []EventInternal{internalEvent})
+			if err != nil {
+				failure = fmt.Errorf("failed to verify event: %w", err)
+				break
+			}
+		}
+
+		events, err := FromEventInternalSliceWithPolicy[T]([]EventInternal{internalEvent}, r.eventRegistry, r.userFactory, r.unknownEventPolicy)
+		if err != nil {
+			failure = fmt.Errorf("failed to convert event: %w", err)
+			break
+		}
+		converted = append(converted, events)
 	}
 
-	err = p.stream.Publish(events...)
+	nbPublished, err := publishInOrder(ctx, converted, func(e Event[T]) uuid.UUID { return e.Id() }, r.stream.Publish)
 	if err != nil {
-		return -1, err
+		failure = err
 	}
 
-	err = p.eventRepo.MarkAs(ctx, Published, internalEvents...)
-	if err != nil {
-		return -1, err
+	nbSent := 0
+	for _, events := range converted[:nbPublished] {
+		nbSent += len(events)
 	}
 
-	return len(events), nil
+	return nbPublished, nbSent, failure
 }
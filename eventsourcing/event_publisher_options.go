@@ -0,0 +1,96 @@
+package eventsourcing
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+const (
+	DefaultPublisherPollInterval = time.Second
+	DefaultPublisherBatchSize    = 100
+	// DefaultPublisherBatchTimeout is below the default lease of the outbox (see eventrepository.DefaultOutboxLease)
+	DefaultPublisherBatchTimeout = 20 * time.Second
+)
+
+// PublisherOptions tunes how an EventStreamPublisher polls the outbox, zero values keep the defaults
+type PublisherOptions struct {
+	// BatchSize is the maximum number of events leased by a poll
+	BatchSize int
+	// PollInterval is the wait before polling an empty outbox again, the fallback poll interval replaces it when an outbox notifier is used
+	PollInterval time.Duration
+	// Backoff delays the polls following a failed batch
+	Backoff PublisherBackoff
+	// MaxRetries is the number of consecutive failed batches after which Run returns ErrPublisherMaxRetries, 0 retries forever
+	MaxRetries int
+	// BatchTimeout bounds the publication of the events of a batch, it must stay below the lease of the outbox
+	// so that the events are not leased again meanwhile, defaults to DefaultPublisherBatchTimeout
+	BatchTimeout time.Duration
+}
+
+// PublisherBackoff is an exponential backoff, failed batches are retried after the poll interval when it is disabled
+type PublisherBackoff struct {
+	Disabled        bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+}
+
+// EventStreamPublisherWithOptions overrides the batch size and the backoff given to NewEventStreamPublisher
+func EventStreamPublisherWithOptions(options PublisherOptions) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		if options.BatchSize > 0 {
+			o.batchSize = options.BatchSize
+		}
+		if options.PollInterval > 0 {
+			o.pollInterval = options.PollInterval
+		}
+		if options.MaxRetries > 0 {
+			o.maxRetries = options.MaxRetries
+		}
+		if options.BatchTimeout > 0 {
+			o.batchTimeout = options.BatchTimeout
+		}
+
+		o.backoff.Disabled = options.Backoff.Disabled
+		if options.Backoff.InitialInterval > 0 {
+			o.backoff.InitialInterval = options.Backoff.InitialInterval
+		}
+		if options.Backoff.MaxInterval > 0 {
+			o.backoff.MaxInterval = options.Backoff.MaxInterval
+		}
+		if options.Backoff.Multiplier > 0 {
+			o.backoff.Multiplier = options.Backoff.Multiplier
+		}
+	}
+}
+
+// EventStreamPublisherWithOnError registers a hook called with the error of every failed batch
+// it is called from the publishing goroutine and must not block
+func EventStreamPublisherWithOnError(onError func(ctx context.Context, err error)) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		o.onError = onError
+	}
+}
+
+func (b PublisherBackoff) newBackOff(pollInterval time.Duration) backoff.BackOff {
+	if b.Disabled {
+		return backoff.NewConstantBackOff(pollInterval)
+	}
+
+	exponential := backoff.NewExponentialBackOff()
+	exponential.MaxElapsedTime = 0
+	if b.InitialInterval > 0 {
+		exponential.InitialInterval = b.InitialInterval
+	}
+	if b.MaxInterval > 0 {
+		exponential.MaxInterval = b.MaxInterval
+	}
+	if b.Multiplier > 0 {
+		exponential.Multiplier = b.Multiplier
+	}
+	exponential.Reset()
+
+	return exponential
+}
@@ -0,0 +1,151 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type PublisherState string
+
+const (
+	PublisherStopped  PublisherState = "stopped"
+	PublisherRunning  PublisherState = "running"
+	PublisherStopping PublisherState = "stopping"
+	// PublisherFailed publishers stopped with a terminal error, they can be started again
+	PublisherFailed PublisherState = "failed"
+)
+
+// PublisherStatus is a snapshot of the activity of a publisher since it was created
+type PublisherStatus struct {
+	AggregateType AggregateType
+	State         PublisherState
+	StartedAt     time.Time
+	StoppedAt     time.Time
+	// Published counts the published events
+	Published       int
+	LastPublishedAt time.Time
+	// ConsecutiveFailures counts the failed batches since the last successful one
+	ConsecutiveFailures int
+	LastError           string
+	LastErrorAt         time.Time
+}
+
+// PublisherController controls the lifecycle of a publisher running in the background
+type PublisherController interface {
+	// Start runs the publisher in the background, ctx only carries values such as the logger
+	Start(ctx context.Context) error
+	// Stop waits for the publisher to drain the outbox and return, the drain is interrupted once ctx is done
+	Stop(ctx context.Context) error
+	Status() PublisherStatus
+}
+
+// begin moves the publisher to running, it fails when the publisher is already running
+func (p *OutboxPublisher) begin() (<-chan struct{}, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.status.State == PublisherRunning || p.status.State == PublisherStopping {
+		return nil, ErrPublisherRunning
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.drainCtx = nil
+	p.status.State = PublisherRunning
+	p.status.StartedAt = time.Now().UTC()
+	p.status.ConsecutiveFailures = 0
+
+	return p.stop, nil
+}
+
+func (p *OutboxPublisher) end(err error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.status.State = PublisherStopped
+	if err != nil {
+		p.status.State = PublisherFailed
+		p.status.LastError = err.Error()
+		p.status.LastErrorAt = time.Now().UTC()
+	}
+	p.status.StoppedAt = time.Now().UTC()
+	close(p.done)
+}
+
+// Start runs the publisher in the background until Stop is called or it fails, the terminal error is reported by Status
+func (p *OutboxPublisher) Start(ctx context.Context) error {
+	stop, err := p.begin()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		_ = p.run(context.WithoutCancel(ctx), stop)
+	}()
+
+	return nil
+}
+
+// Stop lets the publisher finish its current batch and publish the rest of the outbox before returning
+// the drain is interrupted once ctx is done, leased events are then published again when their lease expires
+func (p *OutboxPublisher) Stop(ctx context.Context) error {
+	p.mtx.Lock()
+	if p.status.State != PublisherRunning {
+		state := p.status.State
+		p.mtx.Unlock()
+		return fmt.Errorf("%w: publisher is %s", ErrPublisherNotRunning, state)
+	}
+
+	p.status.State = PublisherStopping
+	p.drainCtx = ctx
+	close(p.stop)
+	done := p.done
+	p.mtx.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("publisher did not stop: %w", ctx.Err())
+	}
+}
+
+func (p *OutboxPublisher) Status() PublisherStatus {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.status
+}
+
+func (p *OutboxPublisher) stopContext() context.Context {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.drainCtx
+}
+
+// recordBatch updates the status with the outcome of a batch and returns the number of consecutive failures
+func (p *OutboxPublisher) recordBatch(nb int, err error) int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	now := time.Now().UTC()
+	if nb > 0 {
+		p.status.Published += nb
+		p.status.LastPublishedAt = now
+	}
+	if err != nil {
+		p.status.LastError = err.Error()
+		p.status.LastErrorAt = now
+	}
+
+	// batches publishing some events are not failures, the events which failed are dead lettered on their own
+	if err == nil || nb > 0 {
+		p.status.ConsecutiveFailures = 0
+		return 0
+	}
+	p.status.ConsecutiveFailures++
+
+	return p.status.ConsecutiveFailures
+}
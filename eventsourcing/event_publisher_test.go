@@ -0,0 +1,518 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/davidterranova/cqrs/eventsourcing/eventstream"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutboxNotifier struct {
+	wakeUps chan struct{}
+
+	mtx sync.Mutex
+	ctx context.Context
+}
+
+func (n *fakeOutboxNotifier) Listen(ctx context.Context, _ eventsourcing.AggregateType) (<-chan struct{}, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	n.ctx = ctx
+	return n.wakeUps, nil
+}
+
+func (n *fakeOutboxNotifier) listening() context.Context {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	return n.ctx
+}
+
+type recordingPublisher[T eventsourcing.Aggregate] struct {
+	mtx    sync.Mutex
+	events []eventsourcing.Event[T]
+}
+
+func (p *recordingPublisher[T]) Publish(events ...eventsourcing.Event[T]) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.events = append(p.events, events...)
+	return nil
+}
+
+func (p *recordingPublisher[T]) count() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return len(p.events)
+}
+
+func newPublisherTestEvents(t *testing.T, nb int) []eventsourcing.Event[signedAggregate] {
+	t.Helper()
+
+	aggregateId := uuid.New()
+	issuer := &signedUser{id: uuid.New()}
+	events := make([]eventsourcing.Event[signedAggregate], 0, nb)
+	for i := 0; i < nb; i++ {
+		events = append(events, &evtSigned{
+			EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, i+1, "signed", aggregateId, issuer),
+		})
+	}
+
+	return events
+}
+
+func TestEventStreamPublisherOutboxNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	notifier := &fakeOutboxNotifier{wakeUps: make(chan struct{}, 1)}
+	stream := &recordingPublisher[signedAggregate]{}
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream, 2, false,
+		eventsourcing.EventStreamPublisherWithOutboxNotifier(notifier, time.Hour),
+	)
+
+	done := make(chan error)
+	go func() {
+		done <- publisher.Run(ctx)
+	}()
+
+	// the outbox is drained once on start, then only on notifications as the fallback poll is an hour away
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, store.Store(ctx, newPublisherTestEvents(t, 5)...))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, stream.count())
+
+	notifier.wakeUps <- struct{}{}
+	assert.Eventually(t, func() bool { return stream.count() == 5 }, time.Second, 10*time.Millisecond)
+
+	unpublished, err := repo.GetUnpublished(ctx, signedAggregateType, 10)
+	require.NoError(t, err)
+	assert.Empty(t, unpublished)
+
+	// the publisher fails so that it is started again instead of silently stopping
+	close(notifier.wakeUps)
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, eventsourcing.ErrOutboxNotifierClosed)
+	case <-time.After(time.Second):
+		t.Fatal("publisher did not stop when the notifications channel was closed")
+	}
+	assert.Equal(t, eventsourcing.PublisherFailed, publisher.Status().State)
+}
+
+// failingPublisher fails to publish events a number of times, the events before a failing one are delivered
+type failingPublisher[T eventsourcing.Aggregate] struct {
+	recordingPublisher[T]
+	failures map[uuid.UUID]int
+}
+
+func (p *failingPublisher[T]) Publish(events ...eventsourcing.Event[T]) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, e := range events {
+		if p.failures[e.Id()] > 0 {
+			p.failures[e.Id()]--
+			return &eventsourcing.PublishError{EventId: e.Id(), Err: errors.New("failed to publish")}
+		}
+		p.events = append(p.events, e)
+	}
+
+	return nil
+}
+
+// delivered counts how many times each event was published
+func (p *recordingPublisher[T]) delivered() map[uuid.UUID]int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	delivered := make(map[uuid.UUID]int, len(p.events))
+	for _, e := range p.events {
+		delivered[e.Id()]++
+	}
+
+	return delivered
+}
+
+func TestEventStreamPublisherAggregateOrdering(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	failing := newPublisherTestEvents(t, 3)
+	succeeding := newPublisherTestEvents(t, 2)
+	require.NoError(t, store.Store(ctx, failing...))
+	require.NoError(t, store.Store(ctx, succeeding...))
+
+	notifier := &fakeOutboxNotifier{wakeUps: make(chan struct{}, 1)}
+	stream := &failingPublisher[signedAggregate]{failures: map[uuid.UUID]int{failing[1].Id(): 1}}
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream, 10, false,
+		eventsourcing.EventStreamPublisherWithOutboxNotifier(notifier, time.Hour),
+	)
+	go publisher.Run(ctx)
+
+	// the failing aggregate does not hold back the other one and batches publishing events are retried right away
+	assert.Eventually(t, func() bool { return stream.count() == 5 }, time.Second, 10*time.Millisecond)
+
+	// the publication resumes at the failed event, the events delivered before it are not published again
+	for _, e := range append(failing, succeeding...) {
+		assert.Equal(t, 1, stream.delivered()[e.Id()])
+	}
+
+	stream.mtx.Lock()
+	defer stream.mtx.Unlock()
+	versions := make(map[uuid.UUID]int)
+	for _, e := range stream.events {
+		assert.Equal(t, versions[e.AggregateId()]+1, e.AggregateVersion())
+		versions[e.AggregateId()] = e.AggregateVersion()
+	}
+}
+
+func TestEventStreamPublisherDeadLetters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	deadLetters, ok := repo.(eventsourcing.DeadLetterRepository)
+	require.True(t, ok)
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	poisoned := newPublisherTestEvents(t, 2)
+	healthy := newPublisherTestEvents(t, 1)
+	require.NoError(t, store.Store(ctx, poisoned...))
+	require.NoError(t, store.Store(ctx, healthy...))
+
+	notifier := &fakeOutboxNotifier{wakeUps: make(chan struct{}, 1)}
+	stream := &failingPublisher[signedAggregate]{failures: map[uuid.UUID]int{poisoned[0].Id(): 100}}
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream, 10, false,
+		eventsourcing.EventStreamPublisherWithOutboxNotifier(notifier, time.Hour),
+		eventsourcing.EventStreamPublisherWithMaxAttempts(2),
+	)
+	go publisher.Run(ctx)
+
+	assert.Eventually(t, func() bool { return stream.count() == 1 }, time.Second, 10*time.Millisecond)
+
+	// the second failure dead letters the poisoned event
+	notifier.wakeUps <- struct{}{}
+	assert.Eventually(t, func() bool {
+		listed, err := deadLetters.ListDeadLetters(ctx, nil)
+		return err == nil && len(listed) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	deadLetter, err := deadLetters.GetDeadLetter(ctx, poisoned[0].Id())
+	require.NoError(t, err)
+	assert.Equal(t, 2, deadLetter.Attempts)
+	assert.Contains(t, deadLetter.Reason, "failed to publish")
+
+	// the later event of the poisoned aggregate waits for the dead letter
+	notifier.wakeUps <- struct{}{}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, stream.count())
+
+	stream.mtx.Lock()
+	stream.failures = nil
+	stream.mtx.Unlock()
+	require.NoError(t, deadLetters.RetryDeadLetter(ctx, poisoned[0].Id()))
+	notifier.wakeUps <- struct{}{}
+	assert.Eventually(t, func() bool { return stream.count() == 3 }, time.Second, 10*time.Millisecond)
+}
+
+func TestEventStreamPublisherLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	stream := &recordingPublisher[signedAggregate]{}
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream, 2, true,
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{PollInterval: time.Hour}),
+	)
+	assert.Equal(t, eventsourcing.PublisherStopped, publisher.Status().State)
+	assert.ErrorIs(t, publisher.Stop(ctx), eventsourcing.ErrPublisherNotRunning)
+
+	require.NoError(t, publisher.Start(ctx))
+	assert.ErrorIs(t, publisher.Start(ctx), eventsourcing.ErrPublisherRunning)
+	assert.ErrorIs(t, publisher.Run(ctx), eventsourcing.ErrPublisherRunning)
+	assert.Equal(t, eventsourcing.PublisherRunning, publisher.Status().State)
+
+	// events stored while the publisher waits for its next poll are published when it stops
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, store.Store(ctx, newPublisherTestEvents(t, 5)...))
+	require.NoError(t, publisher.Stop(ctx))
+	assert.Equal(t, 5, stream.count())
+
+	status := publisher.Status()
+	assert.Equal(t, eventsourcing.PublisherStopped, status.State)
+	assert.Equal(t, 5, status.Published)
+	assert.Empty(t, status.LastError)
+
+	// a stopped publisher can be started again
+	require.NoError(t, publisher.Start(ctx))
+	require.NoError(t, publisher.Stop(ctx))
+}
+
+func TestEventStreamPublisherStopsListening(t *testing.T) {
+	ctx := context.Background()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+
+	notifier := &fakeOutboxNotifier{wakeUps: make(chan struct{}, 1)}
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, &recordingPublisher[signedAggregate]{}, 2, false,
+		eventsourcing.EventStreamPublisherWithOutboxNotifier(notifier, time.Hour),
+	)
+
+	require.NoError(t, publisher.Start(ctx))
+	assert.Eventually(t, func() bool { return notifier.listening() != nil }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, notifier.listening().Err())
+
+	require.NoError(t, publisher.Stop(ctx))
+	assert.ErrorIs(t, notifier.listening().Err(), context.Canceled)
+}
+
+func TestEventStreamPublisherMaxRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	events := newPublisherTestEvents(t, 1)
+	require.NoError(t, store.Store(ctx, events...))
+
+	var (
+		mtx    sync.Mutex
+		errs   []error
+		stream = &failingPublisher[signedAggregate]{failures: map[uuid.UUID]int{events[0].Id(): 100}}
+	)
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream, 10, true,
+		eventsourcing.EventStreamPublisherWithMaxAttempts(0),
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{
+			MaxRetries: 2,
+			Backoff:    eventsourcing.PublisherBackoff{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond},
+		}),
+		eventsourcing.EventStreamPublisherWithOnError(func(_ context.Context, err error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			errs = append(errs, err)
+		}),
+	)
+
+	err := publisher.Run(ctx)
+	require.ErrorIs(t, err, eventsourcing.ErrPublisherMaxRetries)
+
+	mtx.Lock()
+	assert.Len(t, errs, 3)
+	mtx.Unlock()
+
+	status := publisher.Status()
+	assert.Equal(t, eventsourcing.PublisherFailed, status.State)
+	assert.Equal(t, 3, status.ConsecutiveFailures)
+	assert.Contains(t, status.LastError, eventsourcing.ErrPublisherMaxRetries.Error())
+}
+
+func TestEventStreamPublisherV2Acknowledgements(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	deadLetters, ok := repo.(eventsourcing.DeadLetterRepository)
+	require.True(t, ok)
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	rejected := newPublisherTestEvents(t, 2)
+	accepted := newPublisherTestEvents(t, 1)
+	require.NoError(t, store.Store(ctx, rejected...))
+	require.NoError(t, store.Store(ctx, accepted...))
+
+	var (
+		mtx     sync.Mutex
+		handled []uuid.UUID
+	)
+	stream := eventstream.NewSyncPubSub[signedAggregate]()
+	stream.Subscribe(eventsourcing.AckingSubscribeFn(func(_ context.Context, e eventsourcing.Event[signedAggregate]) error {
+		if e.Id() == rejected[0].Id() {
+			return errors.New("read model unavailable")
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		handled = append(handled, e.Id())
+		return nil
+	}))
+	// messages may be acknowledged after the subscriber returned
+	stream.Subscribe(func(_ context.Context, msg eventsourcing.Message[signedAggregate]) {
+		go msg.Ack()
+	})
+
+	publisher := eventsourcing.NewEventStreamPublisherV2[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream,
+		eventsourcing.EventStreamPublisherWithMaxAttempts(2),
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{
+			PollInterval: 10 * time.Millisecond,
+			Backoff:      eventsourcing.PublisherBackoff{InitialInterval: time.Millisecond},
+		}),
+	)
+	require.NoError(t, publisher.Start(ctx))
+	defer func() { _ = publisher.Stop(ctx) }()
+
+	// the nacked event is dead lettered instead of being marked as published, its aggregate waits for it
+	assert.Eventually(t, func() bool {
+		deadLetter, err := deadLetters.GetDeadLetter(ctx, rejected[0].Id())
+		return err == nil && strings.Contains(deadLetter.Reason, "read model unavailable")
+	}, time.Second, 10*time.Millisecond)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, []uuid.UUID{accepted[0].Id()}, handled)
+}
+
+func TestEventStreamPublisherV2ResumesAtNackedEvent(t *testing.T) {
+	ctx := context.Background()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	events := newPublisherTestEvents(t, 3)
+	require.NoError(t, store.Store(ctx, events...))
+
+	var (
+		mtx     sync.Mutex
+		nacked  bool
+		handled []uuid.UUID
+	)
+	stream := eventstream.NewSyncPubSub[signedAggregate]()
+	stream.Subscribe(eventsourcing.AckingSubscribeFn(func(_ context.Context, e eventsourcing.Event[signedAggregate]) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if e.Id() == events[1].Id() && !nacked {
+			nacked = true
+			return errors.New("read model unavailable")
+		}
+		handled = append(handled, e.Id())
+		return nil
+	}))
+
+	publisher := eventsourcing.NewEventStreamPublisherV2[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream,
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{
+			PollInterval: 10 * time.Millisecond,
+			Backoff:      eventsourcing.PublisherBackoff{InitialInterval: time.Millisecond},
+		}),
+	)
+	require.NoError(t, publisher.Start(ctx))
+	defer func() { _ = publisher.Stop(ctx) }()
+
+	// the event acknowledged before the nacked one is neither handled nor acknowledged twice
+	assert.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(handled) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, eventIds(events), handled)
+}
+
+func TestEventStreamPublisherBatchTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	// the events of two aggregates share the deadline of the batch
+	require.NoError(t, store.Store(ctx, newPublisherTestEvents(t, 1)...))
+	require.NoError(t, store.Store(ctx, newPublisherTestEvents(t, 1)...))
+
+	stream := eventstream.NewSyncPubSub[signedAggregate]()
+	// the messages are never acknowledged
+	stream.Subscribe(func(context.Context, eventsourcing.Message[signedAggregate]) {})
+
+	publisher := eventsourcing.NewEventStreamPublisherV2[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream,
+		eventsourcing.EventStreamPublisherWithMaxAttempts(0),
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{
+			MaxRetries:   1,
+			BatchTimeout: 50 * time.Millisecond,
+			Backoff:      eventsourcing.PublisherBackoff{InitialInterval: time.Millisecond},
+		}),
+	)
+
+	start := time.Now()
+	err := publisher.Run(ctx)
+	require.ErrorIs(t, err, eventsourcing.ErrPublisherMaxRetries)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Contains(t, publisher.Status().LastError, context.DeadlineExceeded.Error())
+
+	unpublished, err := repo.GetUnpublished(ctx, signedAggregateType, 10)
+	require.NoError(t, err)
+	assert.Len(t, unpublished, 2)
+}
@@ -15,6 +15,7 @@ type eventQuery struct {
 	orderDirection *string
 	group_by       *string
 	upToVersion    *int
+	afterPosition  *int64
 }
 
 type orderDirection string
@@ -24,6 +25,9 @@ const (
 	DESC orderDirection = "DESC"
 )
 
+// OrderByGlobalPosition orders events by their position in the whole store
+const OrderByGlobalPosition = "global_position"
+
 type EventQueryOption func(*eventQuery)
 
 func NewEventQuery(opts ...EventQueryOption) *eventQuery {
@@ -72,6 +76,10 @@ func (eq *eventQuery) UpToVersion() *int {
 	return eq.upToVersion
 }
 
+func (eq *eventQuery) AfterPosition() *int64 {
+	return eq.afterPosition
+}
+
 func EventQueryWithAggregateId(aggregateId uuid.UUID) EventQueryOption {
 	return func(eq *eventQuery) {
 		eq.aggregateId = &aggregateId
@@ -111,11 +119,15 @@ func EventQueryWithLimit(limit int) EventQueryOption {
 func EventQueryWithOrderBy(orderBy string, orderDirection string) EventQueryOption {
 	return func(eq *eventQuery) {
 		eq.orderBy = &orderBy
-
-		eq.orderDirection = &orderBy
+		eq.orderDirection = &orderDirection
 	}
 }
 
+// EventQueryWithOrderByPosition orders events by their global position
+func EventQueryWithOrderByPosition(direction orderDirection) EventQueryOption {
+	return EventQueryWithOrderBy(OrderByGlobalPosition, string(direction))
+}
+
 func EventQueryWithGroupBy(group_by string) EventQueryOption {
 	return func(eq *eventQuery) {
 		eq.group_by = &group_by
@@ -127,3 +139,10 @@ func EventQueryWithUpToVersion(upToVersion int) EventQueryOption {
 		eq.upToVersion = &upToVersion
 	}
 }
+
+// EventQueryWithAfterPosition only returns events stored strictly after the given global position
+func EventQueryWithAfterPosition(position int64) EventQueryOption {
+	return func(eq *eventQuery) {
+		eq.afterPosition = &position
+	}
+}
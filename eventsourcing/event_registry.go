@@ -3,11 +3,19 @@ package eventsourcing
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
 )
 
 type EventRegistry[T Aggregate] interface {
-	Register(eventType EventType, factory func() Event[T])
+	// Register registers the factory of an event type, it fails with ErrDuplicateEventType if the event type is already registered
+	Register(eventType EventType, factory func() Event[T]) error
 	Hydrate(base EventBase[T], data []byte) (Event[T], error)
+
+	// EventTypes returns the registered event types sorted by name
+	EventTypes() []EventType
+	// GoType returns the go type of the payload registered for the given event type
+	GoType(eventType EventType) (reflect.Type, error)
 }
 
 type eventRegistry[T Aggregate] struct {
@@ -20,8 +28,21 @@ func NewEventRegistry[T Aggregate]() *eventRegistry[T] {
 	}
 }
 
-func (r *eventRegistry[T]) Register(eventType EventType, factory func() Event[T]) {
+func (r *eventRegistry[T]) Register(eventType EventType, factory func() Event[T]) error {
+	if _, ok := r.registry[eventType]; ok {
+		return fmt.Errorf("%w: event type %s already registered", ErrDuplicateEventType, eventType)
+	}
 	r.registry[eventType] = factory
+
+	return nil
+}
+
+// MustRegister registers the factory of an event type and panics if the event type is already registered
+func MustRegister[T Aggregate](registry EventRegistry[T], eventType EventType, factory func() Event[T]) {
+	err := registry.Register(eventType, factory)
+	if err != nil {
+		panic(err)
+	}
 }
 
 func (r eventRegistry[T]) create(eventType EventType) (Event[T], error) {
@@ -47,3 +68,32 @@ func (r eventRegistry[T]) Hydrate(base EventBase[T], data []byte) (Event[T], err
 
 	return event, nil
 }
+
+func (r eventRegistry[T]) EventTypes() []EventType {
+	eventTypes := make([]EventType, 0, len(r.registry))
+	for eventType := range r.registry {
+		eventTypes = append(eventTypes, eventType)
+	}
+	slices.Sort(eventTypes)
+
+	return eventTypes
+}
+
+// GoType returns the type created by the registered factory, pointers are dereferenced
+// events wrapping a payload (e.g. RoutedEvent) return the type of their payload
+func (r eventRegistry[T]) GoType(eventType EventType) (reflect.Type, error) {
+	event, err := r.create(eventType)
+	if err != nil {
+		return nil, err
+	}
+
+	goType := reflect.TypeOf(event)
+	if p, ok := event.(interface{ PayloadType() reflect.Type }); ok {
+		goType = p.PayloadType()
+	}
+	for goType.Kind() == reflect.Pointer {
+		goType = goType.Elem()
+	}
+
+	return goType, nil
+}
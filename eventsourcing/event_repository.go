@@ -9,6 +9,9 @@ import (
 const (
 	Published   = true
 	Unpublished = false
+
+	// AllAggregateTypes lets GetUnpublished return the unpublished events of every aggregate type
+	AllAggregateTypes AggregateType = ""
 )
 
 type EventRepository interface {
@@ -16,8 +19,12 @@ type EventRepository interface {
 	Get(ctx context.Context, filter EventQuery) ([]EventInternal, error)
 
 	// load events from outbox that have not been published yet
+	// returned events are leased: they are not returned again until they are marked or their lease expires
+	// events are returned by global position and an event is never returned while an earlier event of the same aggregate
+	// is unpublished, unless that earlier event is returned in the same batch
+	// aggregateType may be AllAggregateTypes to lease the events of every type in a single batch
 	GetUnpublished(ctx context.Context, aggregateType AggregateType, batchSize int) ([]EventInternal, error)
-	// MarkAs marks events as published / unpublished
+	// MarkAs marks events as published / unpublished and releases their lease
 	MarkAs(ctx context.Context, asPublished bool, events ...EventInternal) error
 }
 
@@ -31,4 +38,5 @@ type EventQuery interface {
 	OrderBy() (*string, *string)
 	GroupBy() *string
 	UpToVersion() *int
+	AfterPosition() *int64
 }
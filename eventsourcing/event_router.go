@@ -0,0 +1,147 @@
+package eventsourcing
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// aggregateBookkeeper is implemented by aggregates embedding an AggregateBase
+type aggregateBookkeeper[T Aggregate] interface {
+	Init(e Event[T])
+	Process(e Event[T])
+	Delete(e Event[T])
+}
+
+type routeKind int
+
+const (
+	routeInit routeKind = iota
+	routeProcess
+	routeDelete
+)
+
+type route[T Aggregate] struct {
+	eventType EventType
+	kind      routeKind
+	handler   any
+}
+
+// EventRouter is an alternative to implementing Apply on each event
+// the aggregate declares typed handlers which are called after the aggregate base bookkeeping
+//
+//	router := eventsourcing.NewEventRouter[Group](registry, GroupAggregateType)
+//	eventsourcing.OnInit(router, EvtTypeGroupCreated, func(g *Group, e EvtGroupCreated) {})
+//	eventsourcing.On(router, EvtTypeGroupNameSet, func(g *Group, e EvtGroupNameSet) { g.name = e.Name })
+type EventRouter[T Aggregate] struct {
+	registry      EventRegistry[T]
+	aggregateType AggregateType
+	routes        map[reflect.Type]*route[T]
+}
+
+// NewEventRouter creates a router registering its routes in the given registry
+func NewEventRouter[T Aggregate](registry EventRegistry[T], aggregateType AggregateType) *EventRouter[T] {
+	return &EventRouter[T]{
+		registry:      registry,
+		aggregateType: aggregateType,
+		routes:        make(map[reflect.Type]*route[T]),
+	}
+}
+
+// OnInit routes events initializing the aggregate, the aggregate base Init is called before the handler
+func OnInit[E any, T Aggregate](router *EventRouter[T], eventType EventType, handler func(*T, E)) {
+	register(router, eventType, routeInit, handler)
+}
+
+// On routes events updating the aggregate, the aggregate base Process is called before the handler
+func On[E any, T Aggregate](router *EventRouter[T], eventType EventType, handler func(*T, E)) {
+	register(router, eventType, routeProcess, handler)
+}
+
+// OnDelete routes events deleting the aggregate, the aggregate base Delete is called before the handler
+func OnDelete[E any, T Aggregate](router *EventRouter[T], eventType EventType, handler func(*T, E)) {
+	register(router, eventType, routeDelete, handler)
+}
+
+func register[E any, T Aggregate](router *EventRouter[T], eventType EventType, kind routeKind, handler func(*T, E)) {
+	payloadType := reflect.TypeOf((*E)(nil)).Elem()
+	if _, ok := router.routes[payloadType]; ok {
+		panic(fmt.Errorf("%w: payload %s already routed", ErrDuplicateEventType, payloadType))
+	}
+
+	r := &route[T]{
+		eventType: eventType,
+		kind:      kind,
+		handler:   handler,
+	}
+	router.routes[payloadType] = r
+	MustRegister(router.registry, eventType, func() Event[T] {
+		return &RoutedEvent[T, E]{
+			EventBase: &EventBase[T]{},
+			route:     r,
+		}
+	})
+}
+
+// NewRoutedEvent creates a new event carrying the given payload
+// the event type is the one the payload type has been routed with
+func NewRoutedEvent[E any, T Aggregate](router *EventRouter[T], aggregateId uuid.UUID, aggregateVersion int, issuedBy User, payload E) (*RoutedEvent[T, E], error) {
+	payloadType := reflect.TypeOf((*E)(nil)).Elem()
+	r, ok := router.routes[payloadType]
+	if !ok {
+		return nil, fmt.Errorf("%w: payload %s not routed", ErrUnknownEventType, payloadType)
+	}
+
+	return &RoutedEvent[T, E]{
+		EventBase: NewEventBase[T](router.aggregateType, aggregateVersion, r.eventType, aggregateId, issuedBy),
+		Payload:   payload,
+		route:     r,
+	}, nil
+}
+
+// RoutedEvent is an event which payload is applied by the handler declared on an EventRouter
+// it is marshalled as its payload so that it is stored like an event implementing Apply
+type RoutedEvent[T Aggregate, E any] struct {
+	*EventBase[T]
+	Payload E
+	route   *route[T]
+}
+
+func (e *RoutedEvent[T, E]) Apply(aggregate *T) error {
+	bookkeeper, ok := any(aggregate).(aggregateBookkeeper[T])
+	if !ok {
+		return fmt.Errorf("%w: %T does not embed an AggregateBase", ErrInvalidAggregateType, aggregate)
+	}
+
+	switch e.route.kind {
+	case routeInit:
+		bookkeeper.Init(e)
+	case routeDelete:
+		bookkeeper.Delete(e)
+	default:
+		bookkeeper.Process(e)
+	}
+
+	handler, ok := e.route.handler.(func(*T, E))
+	if !ok {
+		return fmt.Errorf("invalid handler for event(%s)", e.EventType())
+	}
+	handler(aggregate, e.Payload)
+
+	return nil
+}
+
+// PayloadType returns the go type of the payload, it is used by EventRegistry.GoType
+func (e RoutedEvent[T, E]) PayloadType() reflect.Type {
+	return reflect.TypeOf((*E)(nil)).Elem()
+}
+
+func (e RoutedEvent[T, E]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Payload)
+}
+
+func (e *RoutedEvent[T, E]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &e.Payload)
+}
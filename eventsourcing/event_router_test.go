@@ -0,0 +1,100 @@
+//go:build unit
+
+package eventsourcing
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const routedAggregateType AggregateType = "routed"
+
+type routedAggregate struct {
+	*AggregateBase[routedAggregate]
+	name string
+}
+
+func newRoutedAggregate() *routedAggregate {
+	return &routedAggregate{
+		AggregateBase: NewAggregateBase[routedAggregate](uuid.Nil, 0),
+	}
+}
+
+func (routedAggregate) AggregateType() AggregateType {
+	return routedAggregateType
+}
+
+type evtRoutedCreated struct{}
+
+type evtRoutedNameSet struct {
+	Name string
+}
+
+type evtRoutedDeleted struct{}
+
+func TestEventRouter(t *testing.T) {
+	registry := NewEventRegistry[routedAggregate]()
+	router := NewEventRouter[routedAggregate](registry, routedAggregateType)
+	OnInit(router, "routed.created", func(*routedAggregate, evtRoutedCreated) {})
+	On(router, "routed.name-set", func(a *routedAggregate, e evtRoutedNameSet) {
+		a.name = e.Name
+	})
+	OnDelete(router, "routed.deleted", func(*routedAggregate, evtRoutedDeleted) {})
+
+	assert.Equal(t, []EventType{"routed.created", "routed.deleted", "routed.name-set"}, registry.EventTypes())
+
+	aggregateId := uuid.New()
+	issuedBy := &testUser{id: uuid.New()}
+	created, err := NewRoutedEvent(router, aggregateId, 0, issuedBy, evtRoutedCreated{})
+	require.NoError(t, err)
+	nameSet, err := NewRoutedEvent(router, aggregateId, 1, issuedBy, evtRoutedNameSet{Name: "john"})
+	require.NoError(t, err)
+	deleted, err := NewRoutedEvent(router, aggregateId, 2, issuedBy, evtRoutedDeleted{})
+	require.NoError(t, err)
+	assert.Equal(t, EventType("routed.name-set"), nameSet.EventType())
+
+	t.Run("apply", func(t *testing.T) {
+		aggregate := newRoutedAggregate()
+		for _, e := range []Event[routedAggregate]{created, nameSet} {
+			require.NoError(t, e.Apply(aggregate))
+		}
+		assert.Equal(t, aggregateId, aggregate.AggregateId())
+		assert.Equal(t, 1, aggregate.AggregateVersion())
+		assert.Equal(t, "john", aggregate.name)
+		assert.Nil(t, aggregate.DeletedAt())
+
+		require.NoError(t, deleted.Apply(aggregate))
+		assert.Equal(t, 2, aggregate.AggregateVersion())
+		assert.NotNil(t, aggregate.DeletedAt())
+	})
+
+	t.Run("hydrate", func(t *testing.T) {
+		internalEvents, err := toEventInternalSlice([]Event[routedAggregate]{created, nameSet})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"Name": "john"}`, string(internalEvents[1].EventData))
+
+		events, err := FromEventInternalSlice(internalEvents, registry, newTestUser)
+		require.NoError(t, err)
+
+		aggregate := newRoutedAggregate()
+		for _, e := range events {
+			require.NoError(t, e.Apply(aggregate))
+		}
+		assert.Equal(t, "john", aggregate.name)
+		assert.Equal(t, 1, aggregate.AggregateVersion())
+	})
+
+	t.Run("schema describes the payload", func(t *testing.T) {
+		goType, err := registry.GoType("routed.name-set")
+		require.NoError(t, err)
+		assert.Equal(t, "evtRoutedNameSet", goType.Name())
+	})
+
+	t.Run("unrouted payload", func(t *testing.T) {
+		_, err := NewRoutedEvent(router, aggregateId, 3, issuedBy, struct{}{})
+		assert.ErrorIs(t, err, ErrUnknownEventType)
+	})
+}
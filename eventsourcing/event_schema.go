@@ -0,0 +1,197 @@
+package eventsourcing
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+//nolint:gochecknoglobals
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	// unsupportedKinds can not be encoded to json
+	unsupportedKinds = map[reflect.Kind]bool{
+		reflect.Chan:          true,
+		reflect.Func:          true,
+		reflect.UnsafePointer: true,
+		reflect.Complex64:     true,
+		reflect.Complex128:    true,
+	}
+)
+
+// JSONSchema is a subset of the JSON Schema specification describing the payload of an event
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// EventSchema describes a registered event type and its payload
+type EventSchema struct {
+	EventType EventType
+	GoType    reflect.Type
+	Schema    *JSONSchema
+}
+
+// EventSchemas derives the JSON schema of every event registered in the registry
+// schemas follow the encoding/json conventions used to persist events
+func EventSchemas[T Aggregate](registry EventRegistry[T]) ([]EventSchema, error) {
+	eventTypes := registry.EventTypes()
+	schemas := make([]EventSchema, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		goType, err := registry.GoType(eventType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get go type of event(%s): %w", eventType, err)
+		}
+
+		schema := NewJSONSchema(goType)
+		schema.Schema = jsonSchemaDraft
+		schema.Title = eventType.String()
+
+		schemas = append(schemas, EventSchema{
+			EventType: eventType,
+			GoType:    goType,
+			Schema:    schema,
+		})
+	}
+
+	return schemas, nil
+}
+
+// NewJSONSchema derives a JSON schema from a go type by reflection
+func NewJSONSchema(t reflect.Type) *JSONSchema {
+	return jsonSchemaOf(t, map[reflect.Type]bool{})
+}
+
+//nolint:cyclop
+func jsonSchemaOf(t reflect.Type, visiting map[reflect.Type]bool) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case t == uuidType:
+		return &JSONSchema{Type: "string", Format: "uuid"}
+	case t == rawMessageType:
+		return &JSONSchema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// custom marshalling can not be described by reflection
+		return &JSONSchema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", Format: "byte"}
+		}
+		return &JSONSchema{Type: "array", Items: jsonSchemaOf(t.Elem(), visiting)}
+	case reflect.Array:
+		return &JSONSchema{Type: "array", Items: jsonSchemaOf(t.Elem(), visiting)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: jsonSchemaOf(t.Elem(), visiting)}
+	case reflect.Struct:
+		return jsonSchemaOfStruct(t, visiting)
+	default:
+		// interfaces can hold any value
+		return &JSONSchema{}
+	}
+}
+
+func jsonSchemaOfStruct(t reflect.Type, visiting map[reflect.Type]bool) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	if visiting[t] {
+		// recursive types are not expanded
+		return schema
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip || unsupportedKinds[field.Type.Kind()] {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		// embedded structs without a json name are flattened as encoding/json does
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded := jsonSchemaOfStruct(fieldType, visiting)
+			for k, v := range embedded.Properties {
+				if _, ok := schema.Properties[k]; !ok {
+					schema.Properties[k] = v
+				}
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = jsonSchemaOf(field.Type, visiting)
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// jsonFieldName parses the json tag of a struct field
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false, false
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	omitEmpty := false
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, false
+}
@@ -0,0 +1,81 @@
+//go:build unit
+
+package eventsourcing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const schemaTestAggregateType AggregateType = "schema_test"
+
+type schemaTestAggregate struct {
+	*AggregateBase[schemaTestAggregate]
+}
+
+func (schemaTestAggregate) AggregateType() AggregateType {
+	return schemaTestAggregateType
+}
+
+type evtSchemaTest struct {
+	*EventBase[schemaTestAggregate]
+	Name    string
+	Tags    []string        `json:"tags,omitempty"`
+	Owner   uuid.UUID       `json:"owner"`
+	DueAt   *time.Time      `json:"due_at,omitempty"`
+	Labels  map[string]int  `json:"labels"`
+	Raw     json.RawMessage `json:"raw"`
+	Parent  *evtSchemaTest  `json:"parent,omitempty"`
+	Ignored string          `json:"-"`
+}
+
+func (evtSchemaTest) Apply(*schemaTestAggregate) error {
+	return nil
+}
+
+func TestEventSchemas(t *testing.T) {
+	registry := NewEventRegistry[schemaTestAggregate]()
+	MustRegister[schemaTestAggregate](registry, "b.schema-tested", func() Event[schemaTestAggregate] {
+		return &evtSchemaTest{EventBase: &EventBase[schemaTestAggregate]{}}
+	})
+	MustRegister[schemaTestAggregate](registry, "a.schema-tested", func() Event[schemaTestAggregate] {
+		return &evtSchemaTest{EventBase: &EventBase[schemaTestAggregate]{}}
+	})
+
+	assert.Equal(t, []EventType{"a.schema-tested", "b.schema-tested"}, registry.EventTypes())
+
+	_, err := registry.GoType("unknown")
+	assert.ErrorIs(t, err, ErrUnknownEventType)
+
+	schemas, err := EventSchemas[schemaTestAggregate](registry)
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+
+	schema := schemas[0].Schema
+	assert.Equal(t, "evtSchemaTest", schemas[0].GoType.Name())
+	assert.Equal(t, "a.schema-tested", schema.Title)
+	assert.Equal(t, "object", schema.Type)
+	assert.ElementsMatch(t, []string{"Name", "tags", "owner", "due_at", "labels", "raw", "parent"}, keys(schema.Properties))
+	assert.ElementsMatch(t, []string{"Name", "owner", "labels", "raw"}, schema.Required)
+	assert.Equal(t, "array", schema.Properties["tags"].Type)
+	assert.Equal(t, "string", schema.Properties["tags"].Items.Type)
+	assert.Equal(t, "uuid", schema.Properties["owner"].Format)
+	assert.Equal(t, "date-time", schema.Properties["due_at"].Format)
+	assert.Equal(t, "integer", schema.Properties["labels"].AdditionalProperties.Type)
+	assert.Empty(t, schema.Properties["raw"].Type)
+	assert.Empty(t, schema.Properties["parent"].Properties)
+}
+
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+
+	return result
+}
@@ -0,0 +1,160 @@
+package eventsourcing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SignatureVerificationMode defines how events with an invalid or missing signature are handled on hydration
+type SignatureVerificationMode int
+
+const (
+	// SignatureVerificationFail fails hydration when a signature is invalid or missing
+	SignatureVerificationFail SignatureVerificationMode = iota
+	// SignatureVerificationWarn logs events with an invalid or missing signature and hydrates them anyway
+	SignatureVerificationWarn
+)
+
+// Signer signs events with the key of the user or service that issued them
+type Signer interface {
+	Sign(ctx context.Context, e EventInternal) ([]byte, error)
+}
+
+// PrivateKeyRegistry maps an issuer, as stored in EventInternal.EventIssuedBy, to its private key
+type PrivateKeyRegistry interface {
+	PrivateKey(ctx context.Context, issuedBy string) (ed25519.PrivateKey, error)
+}
+
+// KeyRegistry maps an issuer, as stored in EventInternal.EventIssuedBy, to its public key
+type KeyRegistry interface {
+	PublicKey(ctx context.Context, issuedBy string) (ed25519.PublicKey, error)
+}
+
+// EventDigest returns the digest of an event content which is signed by a Signer
+func EventDigest(e EventInternal) ([]byte, error) {
+	return ComputeEventHash(e, nil)
+}
+
+type ed25519Signer struct {
+	keys PrivateKeyRegistry
+}
+
+// NewEd25519Signer creates a signer using the private key of the issuer of each event
+func NewEd25519Signer(keys PrivateKeyRegistry) Signer {
+	return &ed25519Signer{
+		keys: keys,
+	}
+}
+
+func (s *ed25519Signer) Sign(ctx context.Context, e EventInternal) ([]byte, error) {
+	privateKey, err := s.keys.PrivateKey(ctx, e.EventIssuedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private key of issuer(%s): %w", e.EventIssuedBy, err)
+	}
+
+	digest, err := EventDigest(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return ed25519.Sign(privateKey, digest), nil
+}
+
+// VerifyEventSignature checks the signature of an event against the public key of its issuer
+func VerifyEventSignature(ctx context.Context, keys KeyRegistry, e EventInternal) error {
+	if len(e.EventSignature) == 0 {
+		return fmt.Errorf("%w: event(%s) is not signed", ErrInvalidEventSignature, e.EventId)
+	}
+
+	publicKey, err := keys.PublicKey(ctx, e.EventIssuedBy)
+	if err != nil {
+		return fmt.Errorf("failed to get public key of issuer(%s): %w", e.EventIssuedBy, err)
+	}
+
+	digest, err := EventDigest(e)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, digest, e.EventSignature) {
+		return fmt.Errorf("%w: event(%s) issued by %s", ErrInvalidEventSignature, e.EventId, e.EventIssuedBy)
+	}
+
+	return nil
+}
+
+// verifyEventSignatures checks the signature of events according to the verification mode
+func verifyEventSignatures(ctx context.Context, keys KeyRegistry, mode SignatureVerificationMode, events []EventInternal) error {
+	for _, e := range events {
+		err := VerifyEventSignature(ctx, keys, e)
+		if err == nil {
+			continue
+		}
+
+		if mode != SignatureVerificationWarn {
+			return err
+		}
+
+		log.Ctx(ctx).Warn().
+			Err(err).
+			Str("event_id", e.EventId.String()).
+			Str("event_type", e.EventType.String()).
+			Str("event_issued_by", e.EventIssuedBy).
+			Str("aggregate_type", string(e.AggregateType)).
+			Str("aggregate_id", e.AggregateId.String()).
+			Msg("event signature verification failed")
+	}
+
+	return nil
+}
+
+// InMemoryKeyRegistry holds the ed25519 keys of issuers in memory
+// it can be used both to sign and to verify events
+type InMemoryKeyRegistry struct {
+	keys map[string]ed25519.PrivateKey
+	mtx  sync.RWMutex
+}
+
+func NewInMemoryKeyRegistry() *InMemoryKeyRegistry {
+	return &InMemoryKeyRegistry{
+		keys: make(map[string]ed25519.PrivateKey),
+	}
+}
+
+// Add registers the private key of an issuer
+func (r *InMemoryKeyRegistry) Add(issuedBy string, privateKey ed25519.PrivateKey) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.keys[issuedBy] = privateKey
+}
+
+func (r *InMemoryKeyRegistry) PrivateKey(_ context.Context, issuedBy string) (ed25519.PrivateKey, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	privateKey, ok := r.keys[issuedBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIssuerKey, issuedBy)
+	}
+
+	return privateKey, nil
+}
+
+func (r *InMemoryKeyRegistry) PublicKey(ctx context.Context, issuedBy string) (ed25519.PublicKey, error) {
+	privateKey, err := r.PrivateKey(ctx, issuedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := privateKey.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIssuerKey, issuedBy)
+	}
+
+	return publicKey, nil
+}
@@ -0,0 +1,169 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const signedAggregateType eventsourcing.AggregateType = "signed"
+
+type signedAggregate struct {
+	*eventsourcing.AggregateBase[signedAggregate]
+}
+
+func (signedAggregate) AggregateType() eventsourcing.AggregateType {
+	return signedAggregateType
+}
+
+type evtSigned struct {
+	*eventsourcing.EventBase[signedAggregate]
+}
+
+func (e evtSigned) Apply(a *signedAggregate) error {
+	a.Process(e)
+	return nil
+}
+
+type signedUser struct {
+	id uuid.UUID
+}
+
+func (u signedUser) Id() uuid.UUID {
+	return u.id
+}
+
+func (u signedUser) String() string {
+	return u.id.String()
+}
+
+func (u *signedUser) FromString(s string) error {
+	id, err := uuid.Parse(s)
+	u.id = id
+	return err
+}
+
+func TestSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+
+	issuer := &signedUser{id: uuid.New()}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := eventsourcing.NewInMemoryKeyRegistry()
+	keys.Add(issuer.String(), privateKey)
+
+	signingStore := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true,
+		eventsourcing.EventStoreWithSigner(eventsourcing.NewEd25519Signer(keys)),
+	)
+	failStore := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true,
+		eventsourcing.EventStoreWithSignatureVerification(keys, eventsourcing.SignatureVerificationFail),
+	)
+	warnStore := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true,
+		eventsourcing.EventStoreWithSignatureVerification(keys, eventsourcing.SignatureVerificationWarn),
+	)
+
+	aggregateId := uuid.New()
+	err = signingStore.Store(ctx, &evtSigned{
+		EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 0, "signed", aggregateId, issuer),
+	})
+	require.NoError(t, err)
+
+	t.Run("valid signature", func(t *testing.T) {
+		events, err := failStore.Load(ctx, signedAggregateType, aggregateId)
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("unsigned event", func(t *testing.T) {
+		unsignedStore := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+		err := unsignedStore.Store(ctx, &evtSigned{
+			EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 1, "signed", aggregateId, issuer),
+		})
+		require.NoError(t, err)
+
+		_, err = failStore.Load(ctx, signedAggregateType, aggregateId)
+		assert.ErrorIs(t, err, eventsourcing.ErrInvalidEventSignature)
+
+		events, err := warnStore.Load(ctx, signedAggregateType, aggregateId)
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+	})
+
+	t.Run("unknown issuer", func(t *testing.T) {
+		err := signingStore.Store(ctx, &evtSigned{
+			EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 0, "signed", uuid.New(), &signedUser{id: uuid.New()}),
+		})
+		assert.ErrorIs(t, err, eventsourcing.ErrUnknownIssuerKey)
+	})
+}
+
+func TestEventStreamPublisherSignatureVerification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	deadLetters, ok := repo.(eventsourcing.DeadLetterRepository)
+	require.True(t, ok)
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+
+	issuer := &signedUser{id: uuid.New()}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := eventsourcing.NewInMemoryKeyRegistry()
+	keys.Add(issuer.String(), privateKey)
+
+	signed := &evtSigned{
+		EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 1, "signed", uuid.New(), issuer),
+	}
+	unsigned := &evtSigned{
+		EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 1, "signed", uuid.New(), issuer),
+	}
+	signingStore := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true,
+		eventsourcing.EventStoreWithSigner(eventsourcing.NewEd25519Signer(keys)),
+	)
+	require.NoError(t, signingStore.Store(ctx, signed))
+	require.NoError(t, eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true).Store(ctx, unsigned))
+
+	stream := &recordingPublisher[signedAggregate]{}
+	publisher := eventsourcing.NewEventStreamPublisher[signedAggregate](
+		repo, registry, signedAggregateType, userFactory, stream, 10, false,
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{PollInterval: 10 * time.Millisecond}),
+		eventsourcing.EventStreamPublisherWithMaxAttempts(1),
+		eventsourcing.EventStreamPublisherWithSignatureVerification(keys, eventsourcing.SignatureVerificationFail),
+	)
+	go publisher.Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		listed, err := deadLetters.ListDeadLetters(ctx, nil)
+		return err == nil && len(listed) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	deadLetter, err := deadLetters.GetDeadLetter(ctx, unsigned.Id())
+	require.NoError(t, err)
+	assert.Contains(t, deadLetter.Reason, eventsourcing.ErrInvalidEventSignature.Error())
+
+	assert.Eventually(t, func() bool { return stream.count() == 1 }, time.Second, 10*time.Millisecond)
+	stream.mtx.Lock()
+	defer stream.mtx.Unlock()
+	assert.Equal(t, signed.Id(), stream.events[0].Id())
+}
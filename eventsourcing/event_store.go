@@ -2,6 +2,7 @@ package eventsourcing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ type EventStore[T Aggregate] interface {
 	Store(ctx context.Context, events ...Event[T]) error
 	// Load events from the given aggregate
 	Load(ctx context.Context, aggregateType AggregateType, aggregateId uuid.UUID) ([]Event[T], error)
-	// LoadUnpublished loads a batch of un published events
+	// LoadUnpublished loads a batch of un published events, they are leased to the caller until they are marked or the lease expires
 	LoadUnpublished(ctx context.Context, aggregateType AggregateType, batchSize int) ([]Event[T], error)
 	// MarkPublished marks events as published
 	MarkPublished(ctx context.Context, events ...Event[T]) error
@@ -25,24 +26,153 @@ type eventStore[T Aggregate] struct {
 	registry    EventRegistry[T]
 	userFactory UserFactory
 	withOutbox  bool
+	options     eventStoreOptions
 }
 
-func NewEventStore[T Aggregate](repo EventRepository, registry EventRegistry[T], userFactory UserFactory, withOutbox bool) *eventStore[T] {
-	return &eventStore[T]{
+type eventStoreOptions struct {
+	unknownEventPolicy UnknownEventPolicy
+	clock              Clock
+	idGenerator        IDGenerator
+	hashChain          bool
+	signer             Signer
+	keys               KeyRegistry
+	verificationMode   SignatureVerificationMode
+}
+
+type EventStoreOption func(*eventStoreOptions)
+
+// EventStoreWithUnknownEventPolicy defines how events of an unregistered type are handled on load
+// with UnknownEventSkip, Load still returns them as UnknownEvent so that aggregates keep their version
+// defaults to UnknownEventStrict
+func EventStoreWithUnknownEventPolicy(policy UnknownEventPolicy) EventStoreOption {
+	return func(o *eventStoreOptions) {
+		o.unknownEventPolicy = policy
+	}
+}
+
+// EventStoreWithClock overrides the issue time of the stored events, it is also used by the command handlers of the store
+// to create aggregates, by default events keep the time set when they were created
+func EventStoreWithClock(clock Clock) EventStoreOption {
+	return func(o *eventStoreOptions) {
+		o.clock = clock
+	}
+}
+
+// EventStoreWithIDGenerator overrides the id of the stored events, by default events keep the id set when they were created
+func EventStoreWithIDGenerator(idGenerator IDGenerator) EventStoreOption {
+	return func(o *eventStoreOptions) {
+		o.idGenerator = idGenerator
+	}
+}
+
+// EventStoreWithHashChain chains stored events to the previous event of their aggregate with a hash
+// it requires an additional query per aggregate on store to retrieve the last stored event
+// repositories reject a second event chained to the same predecessor with ErrHashChainConflict, Store then fails with ErrConcurrencyConflict
+func EventStoreWithHashChain() EventStoreOption {
+	return func(o *eventStoreOptions) {
+		o.hashChain = true
+	}
+}
+
+// EventStoreWithSigner signs stored events with the key of their issuer
+func EventStoreWithSigner(signer Signer) EventStoreOption {
+	return func(o *eventStoreOptions) {
+		o.signer = signer
+	}
+}
+
+// EventStoreWithSignatureVerification verifies the signature of events when they are loaded
+// mode defines whether an invalid or missing signature fails the load or is only logged
+func EventStoreWithSignatureVerification(keys KeyRegistry, mode SignatureVerificationMode) EventStoreOption {
+	return func(o *eventStoreOptions) {
+		o.keys = keys
+		o.verificationMode = mode
+	}
+}
+
+func NewEventStore[T Aggregate](repo EventRepository, registry EventRegistry[T], userFactory UserFactory, withOutbox bool, opts ...EventStoreOption) *eventStore[T] {
+	s := &eventStore[T]{
 		repo:        repo,
 		registry:    registry,
 		userFactory: userFactory,
 		withOutbox:  withOutbox,
 	}
+
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+
+	return s
 }
 
 func (s *eventStore[T]) Store(ctx context.Context, events ...Event[T]) error {
+	if s.options.clock != nil || s.options.idGenerator != nil {
+		for _, event := range events {
+			stampEvent(event, s.options.clock, s.options.idGenerator)
+		}
+	}
+
 	internalEvents, err := toEventInternalSlice[T](events)
 	if err != nil {
 		return fmt.Errorf("failed to convert events to internal events: %w", err)
 	}
 
-	return s.repo.Save(ctx, s.withOutbox, internalEvents...)
+	if s.options.hashChain {
+		err = s.chain(ctx, internalEvents)
+		if err != nil {
+			return fmt.Errorf("failed to chain events: %w", err)
+		}
+	}
+
+	if s.options.signer != nil {
+		for i := range internalEvents {
+			internalEvents[i].EventSignature, err = s.options.signer.Sign(ctx, internalEvents[i])
+			if err != nil {
+				return fmt.Errorf("failed to sign event(%s): %w", internalEvents[i].EventId, err)
+			}
+		}
+	}
+
+	err = s.repo.Save(ctx, s.withOutbox, internalEvents...)
+	// another writer appended to the aggregate since its state was loaded, the command must be handled again on the new state
+	if errors.Is(err, ErrHashChainConflict) {
+		return fmt.Errorf("%w: %w", ErrConcurrencyConflict, err)
+	}
+
+	return err
+}
+
+// chain sets the hash of events chaining them to the last stored event of their aggregate
+func (s *eventStore[T]) chain(ctx context.Context, internalEvents []EventInternal) error {
+	lastHashes := make(map[uuid.UUID][]byte)
+	for i := range internalEvents {
+		aggregateId := internalEvents[i].AggregateId
+		previousHash, ok := lastHashes[aggregateId]
+		if !ok {
+			last, err := s.repo.Get(
+				ctx,
+				NewEventQuery(
+					EventQueryWithAggregateId(aggregateId),
+					EventQueryWithOrderByPosition(DESC),
+					EventQueryWithLimit(1),
+				),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to load last event of aggregate(%s): %w", aggregateId, err)
+			}
+			if len(last) > 0 {
+				previousHash = last[0].EventHash
+			}
+		}
+
+		err := ChainEvents(previousHash, internalEvents[i:i+1])
+		if err != nil {
+			return err
+		}
+		lastHashes[aggregateId] = internalEvents[i].EventHash
+	}
+
+	return nil
 }
 
 func (s *eventStore[T]) Load(ctx context.Context, aggregateType AggregateType, aggregateId uuid.UUID) ([]Event[T], error) {
@@ -57,7 +187,13 @@ func (s *eventStore[T]) Load(ctx context.Context, aggregateType AggregateType, a
 		return nil, fmt.Errorf("failed to load events from repository: %w", err)
 	}
 
-	events, err := FromEventInternalSlice[T](internalEvents, s.registry, s.userFactory)
+	// skipped events are kept as tombstones, the aggregate version must follow the stored stream
+	policy := s.options.unknownEventPolicy
+	if policy == UnknownEventSkip {
+		policy = UnknownEventTombstone
+	}
+
+	events, err := s.hydrate(ctx, internalEvents, policy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert internal events to events: %w", err)
 	}
@@ -71,7 +207,7 @@ func (s *eventStore[T]) LoadUnpublished(ctx context.Context, aggregateType Aggre
 		return nil, fmt.Errorf("failed to load unpublished events from repository: %w", err)
 	}
 
-	events, err := FromEventInternalSlice[T](internalEvents, s.registry, s.userFactory)
+	events, err := s.hydrate(ctx, internalEvents, s.options.unknownEventPolicy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert internal events to events: %w", err)
 	}
@@ -79,6 +215,17 @@ func (s *eventStore[T]) LoadUnpublished(ctx context.Context, aggregateType Aggre
 	return events, nil
 }
 
+func (s *eventStore[T]) hydrate(ctx context.Context, internalEvents []EventInternal, policy UnknownEventPolicy) ([]Event[T], error) {
+	if s.options.keys != nil {
+		err := verifyEventSignatures(ctx, s.options.keys, s.options.verificationMode, internalEvents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return FromEventInternalSliceWithPolicy[T](internalEvents, s.registry, s.userFactory, policy)
+}
+
 func (s *eventStore[T]) MarkPublished(ctx context.Context, events ...Event[T]) error {
 	internalEvents, err := toEventInternalSlice[T](events)
 	if err != nil {
@@ -96,3 +243,8 @@ func (s *eventStore[T]) RepublishEvents(ctx context.Context, events ...Event[T])
 
 	return s.repo.MarkAs(ctx, false, internalEvents...)
 }
+
+// configuredClock is the clock of EventStoreWithClock, nil when none was configured
+func (s *eventStore[T]) configuredClock() Clock {
+	return s.options.clock
+}
@@ -0,0 +1,111 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStoreLoadUnknownEvents(t *testing.T) {
+	for _, policy := range []eventsourcing.UnknownEventPolicy{eventsourcing.UnknownEventSkip, eventsourcing.UnknownEventTombstone} {
+		t.Run(policy.String(), func(t *testing.T) {
+			ctx := context.Background()
+			repo := eventrepository.NewInMemoryEventRepository()
+			registry := eventsourcing.NewEventRegistry[signedAggregate]()
+			eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+				return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+			})
+			userFactory := func() eventsourcing.User { return &signedUser{} }
+			store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, false,
+				eventsourcing.EventStoreWithUnknownEventPolicy(policy),
+			)
+			commandHandler := eventsourcing.NewCommandHandler[signedAggregate](
+				store,
+				func() *signedAggregate {
+					return &signedAggregate{AggregateBase: eventsourcing.NewAggregateBase[signedAggregate](uuid.Nil, 0)}
+				},
+				eventsourcing.CacheOption{Disabled: true},
+			)
+
+			issuer := &signedUser{id: uuid.New()}
+			aggregateId := uuid.New()
+			require.NoError(t, store.Store(ctx, &evtSigned{
+				EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 1, "signed", aggregateId, issuer),
+			}))
+			require.NoError(t, repo.Save(ctx, false, eventsourcing.EventInternal{
+				EventId:          uuid.New(),
+				EventType:        "removed",
+				EventIssuedAt:    time.Now().UTC(),
+				EventIssuedBy:    issuer.String(),
+				EventData:        []byte(`{}`),
+				AggregateId:      aggregateId,
+				AggregateType:    signedAggregateType,
+				AggregateVersion: 2,
+			}))
+
+			aggregate, err := commandHandler.HydrateAggregate(ctx, signedAggregateType, aggregateId)
+			require.NoError(t, err)
+			assert.Equal(t, 2, aggregate.AggregateVersion())
+
+			require.NoError(t, store.Store(ctx, &evtSigned{
+				EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, aggregate.AggregateVersion()+1, "signed", aggregateId, issuer),
+			}))
+
+			stored, err := repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithAggregateId(aggregateId)))
+			require.NoError(t, err)
+			versions := make([]int, 0, len(stored))
+			for _, e := range stored {
+				versions = append(versions, e.AggregateVersion)
+			}
+			assert.Equal(t, []int{1, 2, 3}, versions)
+		})
+	}
+}
+
+func TestEventStoreClock(t *testing.T) {
+	ctx := context.Background()
+	clock := eventsourcing.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, false,
+		eventsourcing.EventStoreWithClock(clock),
+		eventsourcing.EventStoreWithIDGenerator(eventsourcing.NewSequentialIDGenerator()),
+	)
+	commandHandler := eventsourcing.NewCommandHandler[signedAggregate](
+		store,
+		func() *signedAggregate {
+			return &signedAggregate{AggregateBase: eventsourcing.NewAggregateBase[signedAggregate](uuid.Nil, 0)}
+		},
+		eventsourcing.CacheOption{Disabled: true},
+	)
+
+	aggregateId := uuid.New()
+	require.NoError(t, store.Store(ctx, &evtSigned{
+		EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 1, "signed", aggregateId, &signedUser{id: uuid.New()}),
+	}))
+
+	events, err := store.Load(ctx, signedAggregateType, aggregateId)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", events[0].Id().String())
+	assert.Equal(t, clock.Now(), events[0].IssuedAt())
+
+	// new aggregates are created with the clock of the store
+	clock.Advance(time.Hour)
+	aggregate, err := commandHandler.HydrateAggregate(ctx, signedAggregateType, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now(), aggregate.CreatedAt())
+	assert.Equal(t, clock.Now(), aggregate.UpdatedAt())
+}
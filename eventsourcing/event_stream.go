@@ -12,5 +12,6 @@ type Publisher[T Aggregate] interface {
 type SubscribeFn[T Aggregate] func(e Event[T])
 
 type Subscriber[T Aggregate] interface {
-	Subscribe(sub SubscribeFn[T])
+	// Subscribe delivers to sub the events matching the options, see SubscriptionFilter
+	Subscribe(sub SubscribeFn[T], opts ...SubscribeOption)
 }
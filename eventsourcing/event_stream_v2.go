@@ -0,0 +1,168 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type EventStreamV2[T Aggregate] interface {
+	PublisherV2[T]
+	SubscriberV2[T]
+}
+
+// PublisherV2 publishes events and reports the failures of their subscribers
+type PublisherV2[T Aggregate] interface {
+	// Publish returns an error when the events could not be delivered or were negatively acknowledged
+	// streams delivering asynchronously may return before the events are acknowledged, see their documentation
+	// streams delivering events in order return a *PublishError so that only the events from the failed one are published again
+	Publish(ctx context.Context, events ...Event[T]) error
+}
+
+// PublishError reports the first event which was not delivered, the events published before it were delivered
+// the outbox publishes all the events again when a publisher fails without a PublishError
+type PublishError struct {
+	EventId uuid.UUID
+	Err     error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("failed to deliver event(%s): %s", e.EventId, e.Err)
+}
+
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+// SubscribeFnV2 handles a message and must acknowledge it, possibly after returning
+type SubscribeFnV2[T Aggregate] func(ctx context.Context, msg Message[T])
+
+type SubscriberV2[T Aggregate] interface {
+	// Subscribe delivers to sub the events matching the options, see SubscriptionFilter
+	Subscribe(sub SubscribeFnV2[T], opts ...SubscribeOption)
+}
+
+// Message is an event delivered to a subscriber, only the first Ack or Nack is taken into account
+type Message[T Aggregate] struct {
+	event  Event[T]
+	once   *sync.Once
+	settle func(err error)
+}
+
+// NewMessage is used by streams to deliver an event, settle is called once with nil on Ack or with the error of Nack
+func NewMessage[T Aggregate](event Event[T], settle func(err error)) Message[T] {
+	return Message[T]{
+		event:  event,
+		once:   &sync.Once{},
+		settle: settle,
+	}
+}
+
+func (m Message[T]) Event() Event[T] {
+	return m.event
+}
+
+func (m Message[T]) Ack() {
+	m.once.Do(func() { m.settle(nil) })
+}
+
+// Nack reports that the event could not be handled, the publisher retries it and dead letters it after too many attempts
+func (m Message[T]) Nack(err error) {
+	nacked := ErrEventNacked
+	if err != nil {
+		nacked = fmt.Errorf("%w: %w", ErrEventNacked, err)
+	}
+
+	m.once.Do(func() { m.settle(nacked) })
+}
+
+// AckingSubscribeFn acknowledges the messages handled by handle without error and negatively acknowledges the others
+func AckingSubscribeFn[T Aggregate](handle func(ctx context.Context, e Event[T]) error) SubscribeFnV2[T] {
+	return func(ctx context.Context, msg Message[T]) {
+		err := handle(ctx, msg.Event())
+		if err != nil {
+			msg.Nack(err)
+			return
+		}
+
+		msg.Ack()
+	}
+}
+
+type publisherV2Adapter[T Aggregate] struct {
+	publisher Publisher[T]
+}
+
+// PublisherV2FromV1 adapts a Publisher, its subscribers can not report failures and ctx is only checked before publishing
+func PublisherV2FromV1[T Aggregate](publisher Publisher[T]) PublisherV2[T] {
+	return publisherV2Adapter[T]{publisher: publisher}
+}
+
+func (p publisherV2Adapter[T]) Publish(ctx context.Context, events ...Event[T]) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return p.publisher.Publish(events...)
+}
+
+type publisherV1Adapter[T Aggregate] struct {
+	publisher PublisherV2[T]
+}
+
+// PublisherV1FromV2 adapts a PublisherV2 for the code publishing without a context
+func PublisherV1FromV2[T Aggregate](publisher PublisherV2[T]) Publisher[T] {
+	return publisherV1Adapter[T]{publisher: publisher}
+}
+
+func (p publisherV1Adapter[T]) Publish(events ...Event[T]) error {
+	return p.publisher.Publish(context.Background(), events...)
+}
+
+type subscriberV2Adapter[T Aggregate] struct {
+	subscriber Subscriber[T]
+}
+
+// SubscriberV2FromV1 adapts a Subscriber, the negative acknowledgements can not reach its publisher and are only logged
+func SubscriberV2FromV1[T Aggregate](subscriber Subscriber[T]) SubscriberV2[T] {
+	return subscriberV2Adapter[T]{subscriber: subscriber}
+}
+
+func (s subscriberV2Adapter[T]) Subscribe(sub SubscribeFnV2[T], opts ...SubscribeOption) {
+	s.subscriber.Subscribe(func(e Event[T]) {
+		sub(context.Background(), NewMessage(e, func(err error) {
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("event_id", e.Id().String()).
+					Str("aggregate_type", string(e.AggregateType())).
+					Msg("event stream: negative acknowledgement lost by a v1 subscriber")
+			}
+		}))
+	}, opts...)
+}
+
+type subscriberV1Adapter[T Aggregate] struct {
+	subscriber SubscriberV2[T]
+}
+
+// SubscriberV1FromV2 adapts a SubscriberV2 such as ConsumerGroup for the code subscribing without acknowledgements
+// the events are acknowledged once handled, the failures of the subscribers can not be reported
+func SubscriberV1FromV2[T Aggregate](subscriber SubscriberV2[T]) Subscriber[T] {
+	return subscriberV1Adapter[T]{subscriber: subscriber}
+}
+
+func (s subscriberV1Adapter[T]) Subscribe(sub SubscribeFn[T], opts ...SubscribeOption) {
+	s.subscriber.Subscribe(SubscribeFnV2FromV1(sub), opts...)
+}
+
+// SubscribeFnV2FromV1 acknowledges the events once they are handled by sub, which can not report failures
+func SubscribeFnV2FromV1[T Aggregate](sub SubscribeFn[T]) SubscribeFnV2[T] {
+	return func(_ context.Context, msg Message[T]) {
+		sub(msg.Event())
+		msg.Ack()
+	}
+}
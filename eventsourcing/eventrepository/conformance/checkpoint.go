@@ -0,0 +1,38 @@
+package conformance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCheckpoints(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	checkpoints, ok := repo.(eventsourcing.CheckpointStore)
+	if !ok {
+		t.Skip("the repository does not implement eventsourcing.CheckpointStore")
+	}
+
+	t.Run("unknown subscriptions start from the beginning", func(t *testing.T) {
+		position, err := checkpoints.GetCheckpoint(ctx, "unknown")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), position)
+	})
+
+	t.Run("checkpoints are saved by subscription", func(t *testing.T) {
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, "first", 3))
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, "second", 5))
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, "first", 7))
+
+		position, err := checkpoints.GetCheckpoint(ctx, "first")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), position)
+
+		position, err = checkpoints.GetCheckpoint(ctx, "second")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), position)
+	})
+}
@@ -0,0 +1,377 @@
+// Package conformance provides a behavioural test suite for eventsourcing.EventRepository implementations
+//
+//	func TestMyRepository(t *testing.T) {
+//		conformance.Run(t, func(t *testing.T) eventsourcing.EventRepository {
+//			return NewMyRepository(...)
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	aggregateTypeA = eventsourcing.AggregateType("conformance-a")
+	aggregateTypeB = eventsourcing.AggregateType("conformance-b")
+	eventCreated   = eventsourcing.EventType("created")
+	eventUpdated   = eventsourcing.EventType("updated")
+)
+
+// Factory returns an empty repository, it is called once for every test of the suite
+type Factory func(t *testing.T) eventsourcing.EventRepository
+
+// Run runs the whole conformance suite against repositories created by factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("save and load", func(t *testing.T) { testSaveAndLoad(t, factory(t)) })
+	t.Run("filters", func(t *testing.T) { testFilters(t, factory(t)) })
+	t.Run("ordering and limit", func(t *testing.T) { testOrderingAndLimit(t, factory(t)) })
+	t.Run("duplicate event", func(t *testing.T) { testDuplicateEvent(t, factory(t)) })
+	t.Run("hash chain conflict", func(t *testing.T) { testHashChainConflict(t, factory(t)) })
+	t.Run("outbox", func(t *testing.T) { testOutbox(t, factory(t)) })
+	t.Run("outbox batch size", func(t *testing.T) { testOutboxBatchSize(t, factory(t)) })
+	t.Run("outbox ordering", func(t *testing.T) { testOutboxOrdering(t, factory(t)) })
+	t.Run("outbox leases", func(t *testing.T) { testOutboxLeases(t, factory(t)) })
+	t.Run("outbox concurrent workers", func(t *testing.T) { testOutboxConcurrentWorkers(t, factory(t)) })
+	t.Run("dead letters", func(t *testing.T) { testDeadLetters(t, factory(t)) })
+	t.Run("checkpoints", func(t *testing.T) { testCheckpoints(t, factory(t)) })
+	t.Run("concurrent saves", func(t *testing.T) { testConcurrentSaves(t, factory(t)) })
+}
+
+// stream is a helper creating the consecutive events of an aggregate
+type stream struct {
+	aggregateId   uuid.UUID
+	aggregateType eventsourcing.AggregateType
+	issuedBy      string
+	version       int
+}
+
+func newStream(aggregateType eventsourcing.AggregateType) *stream {
+	return &stream{
+		aggregateId:   uuid.New(),
+		aggregateType: aggregateType,
+		issuedBy:      uuid.New().String(),
+	}
+}
+
+func (s *stream) next(eventType eventsourcing.EventType) eventsourcing.EventInternal {
+	s.version++
+
+	return eventsourcing.EventInternal{
+		EventId:   uuid.New(),
+		EventType: eventType,
+		// timestamps are stored with a microsecond precision by some repositories
+		EventIssuedAt:    time.Now().UTC().Truncate(time.Microsecond),
+		EventIssuedBy:    s.issuedBy,
+		EventData:        []byte(`{"Version": 1, "Name": "john"}`),
+		AggregateId:      s.aggregateId,
+		AggregateType:    s.aggregateType,
+		AggregateVersion: s.version,
+	}
+}
+
+func (s *stream) events(nb int) []eventsourcing.EventInternal {
+	events := make([]eventsourcing.EventInternal, 0, nb)
+	for i := 0; i < nb; i++ {
+		eventType := eventUpdated
+		if s.version == 0 {
+			eventType = eventCreated
+		}
+		events = append(events, s.next(eventType))
+	}
+
+	return events
+}
+
+type conformanceUser struct {
+	id uuid.UUID
+}
+
+func (u conformanceUser) Id() uuid.UUID  { return u.id }
+func (u conformanceUser) String() string { return u.id.String() }
+func (u *conformanceUser) FromString(s string) error {
+	var err error
+	u.id, err = uuid.Parse(s)
+	return err
+}
+
+func get(t *testing.T, repo eventsourcing.EventRepository, opts ...eventsourcing.EventQueryOption) []eventsourcing.EventInternal {
+	t.Helper()
+
+	events, err := repo.Get(context.Background(), eventsourcing.NewEventQuery(opts...))
+	require.NoError(t, err)
+
+	return events
+}
+
+func eventIds(events []eventsourcing.EventInternal) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.EventId)
+	}
+
+	return ids
+}
+
+func testSaveAndLoad(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	s := newStream(aggregateTypeA)
+	events := s.events(3)
+	events[1].EventHash = []byte{0x01, 0x02}
+	events[1].PreviousEventHash = []byte{0x03}
+	events[1].EventSignature = []byte{0x04}
+
+	require.NoError(t, repo.Save(ctx, false))
+	require.NoError(t, repo.Save(ctx, false, events...))
+
+	loaded := get(t, repo, eventsourcing.EventQueryWithAggregateId(s.aggregateId))
+	require.Len(t, loaded, len(events))
+	for i, e := range loaded {
+		expected := events[i]
+		assert.Equal(t, expected.EventId, e.EventId)
+		assert.Equal(t, expected.EventType, e.EventType)
+		assert.Equal(t, expected.EventIssuedBy, e.EventIssuedBy)
+		assert.True(t, expected.EventIssuedAt.Equal(e.EventIssuedAt), "issued at %s, expected %s", e.EventIssuedAt, expected.EventIssuedAt)
+		assert.JSONEq(t, string(expected.EventData), string(e.EventData))
+		assert.Equal(t, expected.AggregateId, e.AggregateId)
+		assert.Equal(t, expected.AggregateType, e.AggregateType)
+		assert.Equal(t, expected.AggregateVersion, e.AggregateVersion)
+		assert.False(t, e.EventPublished)
+		assert.Positive(t, e.GlobalPosition)
+		if i > 0 {
+			assert.Greater(t, e.GlobalPosition, loaded[i-1].GlobalPosition)
+		}
+	}
+	assert.Equal(t, events[1].EventHash, loaded[1].EventHash)
+	assert.Equal(t, events[1].PreviousEventHash, loaded[1].PreviousEventHash)
+	assert.Equal(t, events[1].EventSignature, loaded[1].EventSignature)
+
+	assert.Empty(t, get(t, repo, eventsourcing.EventQueryWithAggregateId(uuid.New())))
+}
+
+func testFilters(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	a := newStream(aggregateTypeA)
+	b := newStream(aggregateTypeB)
+	aEvents := a.events(3)
+	bEvents := b.events(2)
+	require.NoError(t, repo.Save(ctx, true, aEvents...))
+	require.NoError(t, repo.Save(ctx, true, bEvents...))
+	require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published, bEvents...))
+
+	issuedBy := &conformanceUser{}
+	require.NoError(t, issuedBy.FromString(b.issuedBy))
+
+	tcs := []struct {
+		name     string
+		opts     []eventsourcing.EventQueryOption
+		expected []eventsourcing.EventInternal
+	}{
+		{"no filter", nil, append(append([]eventsourcing.EventInternal{}, aEvents...), bEvents...)},
+		{"aggregate id", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithAggregateId(b.aggregateId)}, bEvents},
+		{"aggregate type", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithAggregateType(aggregateTypeA)}, aEvents},
+		{"event type", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithEventType(eventCreated)}, []eventsourcing.EventInternal{aEvents[0], bEvents[0]}},
+		{"issued by", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithIssuedBy(issuedBy)}, bEvents},
+		{"up to version", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithAggregateId(a.aggregateId), eventsourcing.EventQueryWithUpToVersion(2)}, aEvents[:2]},
+		{"published", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithPublished(true)}, bEvents},
+		{"unpublished", []eventsourcing.EventQueryOption{eventsourcing.EventQueryWithPublished(false)}, aEvents},
+		{
+			"combined",
+			[]eventsourcing.EventQueryOption{
+				eventsourcing.EventQueryWithPublished(true),
+				eventsourcing.EventQueryWithAggregateType(aggregateTypeB),
+				eventsourcing.EventQueryWithEventType(eventUpdated),
+			},
+			bEvents[1:],
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, eventIds(tc.expected), eventIds(get(t, repo, tc.opts...)))
+		})
+	}
+
+	t.Run("after position", func(t *testing.T) {
+		all := get(t, repo)
+		require.Len(t, all, 5)
+
+		after := get(t, repo, eventsourcing.EventQueryWithAfterPosition(all[2].GlobalPosition))
+		assert.Equal(t, eventIds(all[3:]), eventIds(after))
+	})
+
+	t.Run("published flag", func(t *testing.T) {
+		for _, e := range get(t, repo, eventsourcing.EventQueryWithAggregateId(b.aggregateId)) {
+			assert.True(t, e.EventPublished)
+		}
+	})
+}
+
+func testOrderingAndLimit(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	a := newStream(aggregateTypeA)
+	b := newStream(aggregateTypeA)
+	// events of both aggregates are interleaved
+	var saved []eventsourcing.EventInternal
+	for i := 0; i < 3; i++ {
+		for _, s := range []*stream{a, b} {
+			events := s.events(1)
+			require.NoError(t, repo.Save(ctx, false, events...))
+			saved = append(saved, events...)
+		}
+	}
+
+	t.Run("global position ascending by default", func(t *testing.T) {
+		assert.Equal(t, eventIds(saved), eventIds(get(t, repo)))
+	})
+
+	t.Run("global position descending", func(t *testing.T) {
+		loaded := get(t, repo, eventsourcing.EventQueryWithOrderByPosition(eventsourcing.DESC))
+		require.Len(t, loaded, len(saved))
+		for i := range loaded {
+			assert.Equal(t, saved[len(saved)-1-i].EventId, loaded[i].EventId)
+		}
+	})
+
+	t.Run("limit is applied after ordering", func(t *testing.T) {
+		loaded := get(t, repo, eventsourcing.EventQueryWithOrderByPosition(eventsourcing.DESC), eventsourcing.EventQueryWithLimit(2))
+		assert.Equal(t, []uuid.UUID{saved[5].EventId, saved[4].EventId}, eventIds(loaded))
+	})
+
+	t.Run("aggregate version descending", func(t *testing.T) {
+		loaded := get(
+			t, repo,
+			eventsourcing.EventQueryWithAggregateId(a.aggregateId),
+			eventsourcing.EventQueryWithOrderBy("aggregate_version", string(eventsourcing.DESC)),
+		)
+		assert.Equal(t, []uuid.UUID{saved[4].EventId, saved[2].EventId, saved[0].EventId}, eventIds(loaded))
+	})
+
+	t.Run("issued at ascending", func(t *testing.T) {
+		// another aggregate type keeps the other subtests unaffected
+		c := newStream(aggregateTypeB)
+		events := c.events(3)
+		// the issued at are not in the global position order and have fractions of seconds of different lengths
+		issuedAt := time.Now().UTC().Truncate(time.Second)
+		events[0].EventIssuedAt = issuedAt.Add(500 * time.Millisecond)
+		events[1].EventIssuedAt = issuedAt
+		events[2].EventIssuedAt = issuedAt.Add(250 * time.Millisecond)
+		require.NoError(t, repo.Save(ctx, false, events...))
+
+		loaded := get(
+			t, repo,
+			eventsourcing.EventQueryWithAggregateId(c.aggregateId),
+			eventsourcing.EventQueryWithOrderBy("event_issued_at", string(eventsourcing.ASC)),
+		)
+		assert.Equal(t, []uuid.UUID{events[1].EventId, events[2].EventId, events[0].EventId}, eventIds(loaded))
+	})
+
+	t.Run("group by keeps the first event of each group", func(t *testing.T) {
+		loaded := get(
+			t, repo,
+			eventsourcing.EventQueryWithAggregateType(aggregateTypeA),
+			eventsourcing.EventQueryWithGroupBy("aggregate_id"),
+			eventsourcing.EventQueryWithOrderByPosition(eventsourcing.DESC),
+		)
+		assert.Equal(t, []uuid.UUID{saved[1].EventId, saved[0].EventId}, eventIds(loaded))
+	})
+
+	t.Run("last event of an aggregate", func(t *testing.T) {
+		loaded := get(
+			t, repo,
+			eventsourcing.EventQueryWithAggregateId(a.aggregateId),
+			eventsourcing.EventQueryWithOrderByPosition(eventsourcing.DESC),
+			eventsourcing.EventQueryWithLimit(1),
+		)
+		require.Len(t, loaded, 1)
+		assert.Equal(t, 3, loaded[0].AggregateVersion)
+	})
+}
+
+func testDuplicateEvent(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	events := newStream(aggregateTypeA).events(1)
+	require.NoError(t, repo.Save(ctx, true, events...))
+
+	assert.Error(t, repo.Save(ctx, true, events...))
+	assert.Len(t, get(t, repo), 1)
+}
+
+func testHashChainConflict(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	s := newStream(aggregateTypeA)
+	first := s.events(1)
+	require.NoError(t, eventsourcing.ChainEvents(nil, first))
+	require.NoError(t, repo.Save(ctx, true, first...))
+
+	// a second first event and two events chained to the same predecessor fork the chain
+	forkedFirst := s.events(1)
+	require.NoError(t, eventsourcing.ChainEvents(nil, forkedFirst))
+	assert.ErrorIs(t, repo.Save(ctx, true, forkedFirst...), eventsourcing.ErrHashChainConflict)
+
+	next := s.events(1)
+	require.NoError(t, eventsourcing.ChainEvents(first[0].EventHash, next))
+	require.NoError(t, repo.Save(ctx, true, next...))
+
+	forked := s.events(1)
+	require.NoError(t, eventsourcing.ChainEvents(first[0].EventHash, forked))
+	assert.ErrorIs(t, repo.Save(ctx, true, forked...), eventsourcing.ErrHashChainConflict)
+
+	batch := s.events(2)
+	require.NoError(t, eventsourcing.ChainEvents(next[0].EventHash, batch[:1]))
+	require.NoError(t, eventsourcing.ChainEvents(next[0].EventHash, batch[1:]))
+	assert.ErrorIs(t, repo.Save(ctx, true, batch...), eventsourcing.ErrHashChainConflict)
+
+	// events stored without hash chain are not linked
+	require.NoError(t, repo.Save(ctx, true, s.events(2)...))
+	assert.Len(t, get(t, repo), 4)
+}
+
+func testConcurrentSaves(t *testing.T, repo eventsourcing.EventRepository) {
+	const (
+		nbWriters = 8
+		nbBatches = 10
+		batchSize = 3
+	)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, nbWriters*nbBatches)
+	for i := 0; i < nbWriters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := newStream(aggregateTypeA)
+			for j := 0; j < nbBatches; j++ {
+				errs <- repo.Save(ctx, true, s.events(batchSize)...)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	loaded := get(t, repo)
+	require.Len(t, loaded, nbWriters*nbBatches*batchSize)
+
+	positions := make(map[int64]bool, len(loaded))
+	versions := make(map[uuid.UUID]int)
+	for _, e := range loaded {
+		assert.False(t, positions[e.GlobalPosition], "duplicate global position %d", e.GlobalPosition)
+		positions[e.GlobalPosition] = true
+
+		// events of an aggregate keep their order in the global stream
+		assert.Equal(t, versions[e.AggregateId]+1, e.AggregateVersion)
+		versions[e.AggregateId] = e.AggregateVersion
+	}
+}
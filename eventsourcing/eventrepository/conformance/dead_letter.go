@@ -0,0 +1,111 @@
+package conformance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const maxAttempts = 2
+
+// deadLetterRepository skips the test when the repository does not support dead letters
+func deadLetterRepository(t *testing.T, repo eventsourcing.EventRepository) eventsourcing.DeadLetterRepository {
+	t.Helper()
+
+	deadLetters, ok := repo.(eventsourcing.DeadLetterRepository)
+	if !ok {
+		t.Skip("the repository does not implement eventsourcing.DeadLetterRepository")
+	}
+
+	return deadLetters
+}
+
+func markFailed(t *testing.T, repo eventsourcing.DeadLetterRepository, event eventsourcing.EventInternal, reason string) bool {
+	t.Helper()
+
+	deadLettered, err := repo.MarkFailed(context.Background(), event, reason, maxAttempts)
+	require.NoError(t, err)
+
+	return deadLettered
+}
+
+func testDeadLetters(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	deadLetters := deadLetterRepository(t, repo)
+
+	a := newStream(aggregateTypeA)
+	b := newStream(aggregateTypeA)
+	aEvents := a.events(2)
+	bEvents := b.events(1)
+	require.NoError(t, repo.Save(ctx, true, aEvents...))
+	require.NoError(t, repo.Save(ctx, true, bEvents...))
+
+	t.Run("failed events are released and retried", func(t *testing.T) {
+		leased := getUnpublished(t, repo, aggregateTypeA, 1)
+		require.Equal(t, eventIds(aEvents[:1]), eventIds(leased))
+
+		assert.False(t, markFailed(t, deadLetters, leased[0], "first failure"))
+		assert.Equal(t, eventIds(append(append([]eventsourcing.EventInternal{}, aEvents...), bEvents...)), eventIds(peekUnpublished(t, repo, aggregateTypeA, 10)))
+	})
+
+	t.Run("events are dead lettered after max attempts", func(t *testing.T) {
+		leased := getUnpublished(t, repo, aggregateTypeA, 1)
+		require.Equal(t, eventIds(aEvents[:1]), eventIds(leased))
+		assert.True(t, markFailed(t, deadLetters, leased[0], "poison event"))
+
+		// the dead letter holds back the later events of its aggregate only
+		assert.Equal(t, eventIds(bEvents), eventIds(peekUnpublished(t, repo, aggregateTypeA, 10)))
+
+		listed, err := deadLetters.ListDeadLetters(ctx, nil)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, aEvents[0].EventId, listed[0].Event.EventId)
+		assert.Equal(t, maxAttempts, listed[0].Attempts)
+		assert.Equal(t, "poison event", listed[0].Reason)
+		assert.False(t, listed[0].DeadLetteredAt.IsZero())
+		assert.False(t, listed[0].Event.EventPublished)
+
+		otherType := aggregateTypeB
+		listed, err = deadLetters.ListDeadLetters(ctx, &otherType)
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+
+		deadLetter, err := deadLetters.GetDeadLetter(ctx, aEvents[0].EventId)
+		require.NoError(t, err)
+		assert.Equal(t, aEvents[0].EventId, deadLetter.Event.EventId)
+		assert.Equal(t, "poison event", deadLetter.Reason)
+
+		_, err = deadLetters.GetDeadLetter(ctx, uuid.New())
+		assert.ErrorIs(t, err, eventsourcing.ErrDeadLetterNotFound)
+	})
+
+	t.Run("retried dead letters are published again", func(t *testing.T) {
+		require.NoError(t, deadLetters.RetryDeadLetter(ctx, aEvents[0].EventId))
+		assert.ErrorIs(t, deadLetters.RetryDeadLetter(ctx, aEvents[0].EventId), eventsourcing.ErrDeadLetterNotFound)
+
+		leased := getUnpublished(t, repo, aggregateTypeA, 10)
+		assert.Equal(t, eventIds(append(append([]eventsourcing.EventInternal{}, aEvents...), bEvents...)), eventIds(leased))
+
+		// failed attempts start again from zero
+		assert.False(t, markFailed(t, deadLetters, leased[0], "failure after retry"))
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Unpublished, leased[1:]...))
+	})
+
+	t.Run("discarded dead letters are not published", func(t *testing.T) {
+		leased := getUnpublished(t, repo, aggregateTypeA, 1)
+		require.Equal(t, eventIds(aEvents[:1]), eventIds(leased))
+		require.True(t, markFailed(t, deadLetters, leased[0], "poison event"))
+
+		require.NoError(t, deadLetters.DiscardDeadLetter(ctx, aEvents[0].EventId))
+		assert.ErrorIs(t, deadLetters.DiscardDeadLetter(ctx, aEvents[0].EventId), eventsourcing.ErrDeadLetterNotFound)
+
+		assert.Equal(t, eventIds([]eventsourcing.EventInternal{aEvents[1], bEvents[0]}), eventIds(peekUnpublished(t, repo, aggregateTypeA, 10)))
+		listed, err := deadLetters.ListDeadLetters(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+	})
+}
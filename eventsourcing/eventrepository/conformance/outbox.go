@@ -0,0 +1,230 @@
+package conformance
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getUnpublished(t *testing.T, repo eventsourcing.EventRepository, aggregateType eventsourcing.AggregateType, batchSize int) []eventsourcing.EventInternal {
+	t.Helper()
+
+	events, err := repo.GetUnpublished(context.Background(), aggregateType, batchSize)
+	require.NoError(t, err)
+
+	return events
+}
+
+// peekUnpublished loads unpublished events and releases their lease so that they can be loaded again
+func peekUnpublished(t *testing.T, repo eventsourcing.EventRepository, aggregateType eventsourcing.AggregateType, batchSize int) []eventsourcing.EventInternal {
+	t.Helper()
+
+	events := getUnpublished(t, repo, aggregateType, batchSize)
+	require.NoError(t, repo.MarkAs(context.Background(), eventsourcing.Unpublished, events...))
+
+	return events
+}
+
+func testOutbox(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	a := newStream(aggregateTypeA)
+	b := newStream(aggregateTypeB)
+	withoutOutbox := newStream(aggregateTypeA)
+
+	aEvents := a.events(3)
+	require.NoError(t, repo.Save(ctx, true, aEvents...))
+	require.NoError(t, repo.Save(ctx, true, b.events(2)...))
+	withoutOutboxEvents := withoutOutbox.events(2)
+	require.NoError(t, repo.Save(ctx, false, withoutOutboxEvents...))
+
+	t.Run("events saved without the outbox are not published", func(t *testing.T) {
+		// marking them does not add them to the outbox
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Unpublished, withoutOutboxEvents...))
+
+		for _, e := range peekUnpublished(t, repo, aggregateTypeA, 100) {
+			assert.NotEqual(t, withoutOutbox.aggregateId, e.AggregateId)
+		}
+	})
+
+	t.Run("unpublished events of an aggregate type by global position", func(t *testing.T) {
+		unpublished := peekUnpublished(t, repo, aggregateTypeA, 100)
+		assert.Equal(t, eventIds(aEvents), eventIds(unpublished))
+		for _, e := range unpublished {
+			assert.False(t, e.EventPublished)
+		}
+
+		assert.Len(t, peekUnpublished(t, repo, aggregateTypeB, 100), 2)
+		assert.Empty(t, peekUnpublished(t, repo, "unknown", 100))
+	})
+
+	t.Run("unpublished events of all aggregate types by global position", func(t *testing.T) {
+		unpublished := peekUnpublished(t, repo, eventsourcing.AllAggregateTypes, 100)
+		require.Len(t, unpublished, 5)
+		assert.Equal(t, eventIds(aEvents), eventIds(unpublished[:3]))
+		for _, e := range unpublished[3:] {
+			assert.Equal(t, aggregateTypeB, e.AggregateType)
+		}
+
+		assert.Equal(t, eventIds(aEvents[:2]), eventIds(peekUnpublished(t, repo, eventsourcing.AllAggregateTypes, 2)))
+	})
+
+	t.Run("mark as published", func(t *testing.T) {
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published))
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published, aEvents[:2]...))
+
+		assert.Equal(t, eventIds(aEvents[2:]), eventIds(peekUnpublished(t, repo, aggregateTypeA, 100)))
+		assert.Len(t, peekUnpublished(t, repo, aggregateTypeB, 100), 2)
+
+		loaded := get(t, repo, eventsourcing.EventQueryWithAggregateId(a.aggregateId))
+		require.Len(t, loaded, 3)
+		assert.True(t, loaded[0].EventPublished)
+		assert.True(t, loaded[1].EventPublished)
+		assert.False(t, loaded[2].EventPublished)
+	})
+
+	t.Run("mark as unpublished", func(t *testing.T) {
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Unpublished, aEvents[0]))
+
+		assert.Equal(t, eventIds([]eventsourcing.EventInternal{aEvents[0], aEvents[2]}), eventIds(peekUnpublished(t, repo, aggregateTypeA, 100)))
+	})
+
+	t.Run("events saved as published", func(t *testing.T) {
+		published := a.events(1)
+		published[0].EventPublished = true
+		require.NoError(t, repo.Save(ctx, true, published...))
+
+		for _, e := range peekUnpublished(t, repo, aggregateTypeA, 100) {
+			assert.NotEqual(t, published[0].EventId, e.EventId)
+		}
+	})
+}
+
+func testOutboxBatchSize(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	s := newStream(aggregateTypeA)
+	events := s.events(5)
+	require.NoError(t, repo.Save(ctx, true, events...))
+
+	first := getUnpublished(t, repo, aggregateTypeA, 2)
+	assert.Equal(t, eventIds(events[:2]), eventIds(first))
+
+	require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published, first...))
+	assert.Equal(t, eventIds(events[2:4]), eventIds(peekUnpublished(t, repo, aggregateTypeA, 2)))
+	assert.Equal(t, eventIds(events[2:]), eventIds(peekUnpublished(t, repo, aggregateTypeA, 10)))
+}
+
+func testOutboxLeases(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	a := newStream(aggregateTypeA)
+	b := newStream(aggregateTypeA)
+	aEvents := a.events(3)
+	bEvents := b.events(2)
+	require.NoError(t, repo.Save(ctx, true, aEvents...))
+	require.NoError(t, repo.Save(ctx, true, bEvents...))
+
+	first := getUnpublished(t, repo, aggregateTypeA, 2)
+	assert.Equal(t, eventIds(aEvents[:2]), eventIds(first))
+
+	// the last event of a waits for the leased ones
+	second := getUnpublished(t, repo, aggregateTypeA, 10)
+	assert.Equal(t, eventIds(bEvents), eventIds(second))
+	assert.Empty(t, getUnpublished(t, repo, aggregateTypeA, 10))
+
+	require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published, first...))
+	assert.Equal(t, eventIds(aEvents[2:]), eventIds(getUnpublished(t, repo, aggregateTypeA, 10)))
+
+	// marking events as unpublished releases their lease
+	require.NoError(t, repo.MarkAs(ctx, eventsourcing.Unpublished, second...))
+	assert.Equal(t, eventIds(bEvents), eventIds(getUnpublished(t, repo, aggregateTypeA, 10)))
+}
+
+func testOutboxConcurrentWorkers(t *testing.T, repo eventsourcing.EventRepository) {
+	const (
+		nbWorkers    = 4
+		nbAggregates = 10
+		nbEvents     = 5
+	)
+	ctx := context.Background()
+
+	for i := 0; i < nbAggregates; i++ {
+		require.NoError(t, repo.Save(ctx, true, newStream(aggregateTypeA).events(nbEvents)...))
+	}
+
+	var (
+		mtx       sync.Mutex
+		published []eventsourcing.EventInternal
+		wg        sync.WaitGroup
+	)
+	errs := make(chan error, nbWorkers)
+	for i := 0; i < nbWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				events, err := repo.GetUnpublished(ctx, aggregateTypeA, 3)
+				if err != nil || len(events) == 0 {
+					errs <- err
+					return
+				}
+
+				mtx.Lock()
+				published = append(published, events...)
+				mtx.Unlock()
+
+				err = repo.MarkAs(ctx, eventsourcing.Published, events...)
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Len(t, published, nbAggregates*nbEvents)
+	seen := make(map[uuid.UUID]bool, len(published))
+	versions := make(map[uuid.UUID]int)
+	for _, e := range published {
+		assert.False(t, seen[e.EventId], "event(%s) published twice", e.EventId)
+		seen[e.EventId] = true
+
+		assert.Equal(t, versions[e.AggregateId]+1, e.AggregateVersion, "aggregate(%s) published out of order", e.AggregateId)
+		versions[e.AggregateId] = e.AggregateVersion
+	}
+	assert.Empty(t, getUnpublished(t, repo, aggregateTypeA, 100))
+}
+
+func testOutboxOrdering(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	a := newStream(aggregateTypeA)
+	b := newStream(aggregateTypeA)
+	// the versions of a and b are interleaved in the global stream
+	a1, b1, b2, a2 := a.next(eventCreated), b.next(eventCreated), b.next(eventUpdated), a.next(eventUpdated)
+	for _, e := range []eventsourcing.EventInternal{a1, b1, b2, a2} {
+		require.NoError(t, repo.Save(ctx, true, e))
+	}
+
+	t.Run("unpublished events by global position", func(t *testing.T) {
+		assert.Equal(t, eventIds([]eventsourcing.EventInternal{a1, b1, b2, a2}), eventIds(peekUnpublished(t, repo, aggregateTypeA, 10)))
+	})
+
+	t.Run("later events wait for earlier unpublished ones", func(t *testing.T) {
+		first := getUnpublished(t, repo, aggregateTypeA, 2)
+		assert.Equal(t, eventIds([]eventsourcing.EventInternal{a1, b1}), eventIds(first))
+		assert.Empty(t, getUnpublished(t, repo, aggregateTypeA, 10))
+
+		// b1 failed and is released, it is returned again before b2
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published, a1))
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Unpublished, b1))
+		assert.Equal(t, eventIds([]eventsourcing.EventInternal{b1, b2, a2}), eventIds(getUnpublished(t, repo, aggregateTypeA, 10)))
+	})
+}
@@ -0,0 +1,35 @@
+package eventrepository
+
+import "time"
+
+// DefaultOutboxLease is the time during which events returned by GetUnpublished are hidden from other workers
+const DefaultOutboxLease = 30 * time.Second
+
+type eventRepositoryOptions struct {
+	outboxLease   time.Duration
+	copyThreshold int
+}
+
+func newEventRepositoryOptions(opts []EventRepositoryOption) eventRepositoryOptions {
+	options := eventRepositoryOptions{
+		outboxLease:   DefaultOutboxLease,
+		copyThreshold: pgxDefaultCopyThreshold,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+type EventRepositoryOption func(*eventRepositoryOptions)
+
+// EventRepositoryWithOutboxLease sets how long unpublished events are leased to the worker which loaded them
+// the lease should be longer than the time needed to publish a batch
+func EventRepositoryWithOutboxLease(lease time.Duration) EventRepositoryOption {
+	return func(o *eventRepositoryOptions) {
+		if lease > 0 {
+			o.outboxLease = lease
+		}
+	}
+}
@@ -0,0 +1,710 @@
+package eventrepository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	fileLogSegmentExt          = ".log"
+	fileLogOutboxFile          = "outbox.state"
+	fileLogDefaultSegmentSize  = 64 << 20
+	fileLogDefaultSyncInterval = time.Second
+	fileLogFilePerm            = 0o600
+	fileLogDirPerm             = 0o750
+)
+
+// FileLogSyncPolicy defines when written records are flushed to disk
+type FileLogSyncPolicy int
+
+const (
+	// FileLogSyncAlways flushes every write before Save and MarkAs return
+	FileLogSyncAlways FileLogSyncPolicy = iota
+	// FileLogSyncInterval flushes writes periodically, a crash may lose the writes of the last interval
+	FileLogSyncInterval
+	// FileLogSyncNever leaves flushing to the operating system
+	FileLogSyncNever
+)
+
+type fileLogOptions struct {
+	syncPolicy     FileLogSyncPolicy
+	syncInterval   time.Duration
+	maxSegmentSize int64
+	outboxLease    time.Duration
+}
+
+type FileLogOption func(*fileLogOptions)
+
+// FileLogWithSyncPolicy sets when writes are flushed to disk, interval is only used by FileLogSyncInterval
+func FileLogWithSyncPolicy(policy FileLogSyncPolicy, interval time.Duration) FileLogOption {
+	return func(o *fileLogOptions) {
+		o.syncPolicy = policy
+		if interval > 0 {
+			o.syncInterval = interval
+		}
+	}
+}
+
+// FileLogWithMaxSegmentSize sets the size in bytes after which a new segment file is started
+func FileLogWithMaxSegmentSize(size int64) FileLogOption {
+	return func(o *fileLogOptions) {
+		if size > 0 {
+			o.maxSegmentSize = size
+		}
+	}
+}
+
+// FileLogWithOutboxLease sets how long unpublished events are leased to the worker which loaded them
+// leases are kept in memory, they only coordinate the publishers of the process holding the log
+func FileLogWithOutboxLease(lease time.Duration) FileLogOption {
+	return func(o *fileLogOptions) {
+		if lease > 0 {
+			o.outboxLease = lease
+		}
+	}
+}
+
+// fileLogSegment is a file holding events from the global position it is named after
+type fileLogSegment struct {
+	basePosition int64
+	file         *os.File
+	size         int64
+}
+
+// fileLogEntry locates an event in the segments, metadata is kept to filter events without reading them
+type fileLogEntry struct {
+	globalPosition   int64
+	eventId          uuid.UUID
+	eventType        eventsourcing.EventType
+	eventIssuedBy    string
+	aggregateId      uuid.UUID
+	aggregateType    eventsourcing.AggregateType
+	aggregateVersion int
+	segment          *fileLogSegment
+	offset           int64
+	size             int
+}
+
+type fileLogEventRepository struct {
+	dir     string
+	options fileLogOptions
+
+	segments []*fileLogSegment
+	// entries are ordered by global position
+	entries     []*fileLogEntry
+	byAggregate map[uuid.UUID][]*fileLogEntry
+	byEventId   map[uuid.UUID]*fileLogEntry
+	chainLinks  hashChainLinks
+
+	// outbox holds the published state of events saved with the outbox
+	outbox     map[uuid.UUID]bool
+	outboxFile *os.File
+	leases     *outboxLeases
+
+	position  int64
+	mtx       sync.RWMutex
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewFileLogEventRepository opens or creates an event log in dir
+// events are appended to checksummed segment files and indexed in memory when the log is opened
+// a torn record at the end of the log, left by a crash during a write, is truncated
+func NewFileLogEventRepository(dir string, opts ...FileLogOption) (*fileLogEventRepository, error) {
+	options := fileLogOptions{
+		syncPolicy:     FileLogSyncAlways,
+		syncInterval:   fileLogDefaultSyncInterval,
+		maxSegmentSize: fileLogDefaultSegmentSize,
+		outboxLease:    DefaultOutboxLease,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	err := os.MkdirAll(dir, fileLogDirPerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+
+	r := &fileLogEventRepository{
+		dir:         dir,
+		options:     options,
+		byAggregate: make(map[uuid.UUID][]*fileLogEntry),
+		byEventId:   make(map[uuid.UUID]*fileLogEntry),
+		chainLinks:  make(hashChainLinks),
+		outbox:      make(map[uuid.UUID]bool),
+		leases:      newOutboxLeases(options.outboxLease),
+		stop:        make(chan struct{}),
+	}
+
+	err = r.loadSegments()
+	if err == nil {
+		err = r.loadOutbox()
+	}
+	if err != nil {
+		r.closeFiles()
+		return nil, err
+	}
+
+	if options.syncPolicy == FileLogSyncInterval {
+		r.stopped.Add(1)
+		go r.syncPeriodically()
+	}
+
+	return r, nil
+}
+
+func (r *fileLogEventRepository) Save(_ context.Context, publishOutbox bool, events ...eventsourcing.EventInternal) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	buf, records, err := r.encodeEvents(events, publishOutbox)
+	if err != nil {
+		return err
+	}
+
+	segment, err := r.activeSegment(int64(len(buf)))
+	if err != nil {
+		return err
+	}
+
+	offset := segment.size
+	err = r.write(segment.file, buf)
+	if err != nil {
+		// drop what may have been partially written so that the log stays readable
+		_ = segment.file.Truncate(offset)
+		return fmt.Errorf("failed to append events to event log: %w", err)
+	}
+	segment.size += int64(len(buf))
+
+	for _, record := range records {
+		r.index(record.fileLogEventRecord, segment, offset+record.offset, record.size)
+		if record.Outbox {
+			r.outbox[record.EventId] = record.Published
+		}
+
+		log.Debug().
+			Str("event_id", record.EventId.String()).
+			Str("event_type", string(record.EventType)).
+			Str("aggregate_type", string(record.AggregateType)).
+			Str("aggregate_id", record.AggregateId.String()).
+			Msg("event repository: saving event")
+	}
+
+	return nil
+}
+
+//nolint:cyclop
+func (r *fileLogEventRepository) Get(_ context.Context, filter eventsourcing.EventQuery) ([]eventsourcing.EventInternal, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	candidates := r.entries
+	if filter.AggregateId() != nil {
+		candidates = r.byAggregate[*filter.AggregateId()]
+	}
+
+	matches := make([]*fileLogEntry, 0)
+	for _, entry := range candidates {
+		if filter.AggregateType() != nil && *filter.AggregateType() != entry.aggregateType {
+			continue
+		}
+		if filter.EventType() != nil && *filter.EventType() != entry.eventType {
+			continue
+		}
+		if filter.Published() != nil && *filter.Published() != r.outbox[entry.eventId] {
+			continue
+		}
+		if filter.IssuedBy() != nil && filter.IssuedBy().String() != entry.eventIssuedBy {
+			continue
+		}
+		if filter.UpToVersion() != nil && entry.aggregateVersion > *filter.UpToVersion() {
+			continue
+		}
+		if filter.AfterPosition() != nil && entry.globalPosition <= *filter.AfterPosition() {
+			continue
+		}
+
+		matches = append(matches, entry)
+	}
+
+	orderBy, direction := filter.OrderBy()
+	if filter.GroupBy() != nil || (orderBy != nil && *orderBy != "" && *orderBy != eventsourcing.OrderByGlobalPosition) {
+		return r.readSorted(matches, filter)
+	}
+
+	// entries are indexed by global position, only a descending order needs sorting
+	if direction != nil && strings.EqualFold(*direction, string(eventsourcing.DESC)) {
+		slices.Reverse(matches)
+	}
+
+	if filter.Limit() != nil && len(matches) > *filter.Limit() {
+		matches = matches[:*filter.Limit()]
+	}
+
+	return r.read(matches)
+}
+
+// readSorted reads the matching events before grouping and ordering them
+// as the in memory repository does, the index only holds the global position order
+func (r *fileLogEventRepository) readSorted(matches []*fileLogEntry, filter eventsourcing.EventQuery) ([]eventsourcing.EventInternal, error) {
+	events, err := r.read(matches)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]*eventsourcing.EventInternal, 0, len(events))
+	for i := range events {
+		sorted = append(sorted, &events[i])
+	}
+
+	sorted, err = inMemoryGroupBy(sorted, filter.GroupBy())
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, direction := filter.OrderBy()
+	err = inMemoryOrderBy(sorted, orderBy, direction)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Limit() != nil && len(sorted) > *filter.Limit() {
+		sorted = sorted[:*filter.Limit()]
+	}
+
+	result := make([]eventsourcing.EventInternal, 0, len(sorted))
+	for _, e := range sorted {
+		result = append(result, *e)
+	}
+
+	return result, nil
+}
+
+func (r *fileLogEventRepository) GetUnpublished(_ context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	pending := make([]*fileLogEntry, 0)
+	candidates := make([]outboxCandidate, 0)
+	for _, entry := range r.entries {
+		published, inOutbox := r.outbox[entry.eventId]
+		if inOutbox && !published && matchesAggregateType(entry.aggregateType, aggregateType) {
+			pending = append(pending, entry)
+			candidates = append(candidates, outboxCandidate{eventId: entry.eventId, aggregateId: entry.aggregateId})
+		}
+	}
+
+	claimed := r.leases.claim(candidates, batchSize, time.Now())
+	unpublished := make([]*fileLogEntry, 0, len(claimed))
+	for _, i := range claimed {
+		unpublished = append(unpublished, pending[i])
+	}
+
+	events, err := r.read(unpublished)
+	if err != nil {
+		// the events could not be returned, they must not stay leased
+		for _, entry := range unpublished {
+			r.leases.release(entry.eventId)
+		}
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *fileLogEventRepository) MarkAs(_ context.Context, asPublished bool, events ...eventsourcing.EventInternal) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	// as the pg update, only events in the outbox are marked
+	known := make([]eventsourcing.EventInternal, 0, len(events))
+	for _, e := range events {
+		if _, ok := r.outbox[e.EventId]; ok {
+			known = append(known, e)
+		}
+	}
+
+	err := r.writeOutbox(known, asPublished)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range known {
+		r.leases.release(e.EventId)
+	}
+
+	return nil
+}
+
+// Close flushes and closes the event log files
+func (r *fileLogEventRepository) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.stopped.Wait()
+
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		r.closeErr = r.sync()
+		r.closeFiles()
+	})
+
+	return r.closeErr
+}
+
+// encodedFileLogRecord is a record located relatively to the beginning of the encoded batch
+type encodedFileLogRecord struct {
+	fileLogEventRecord
+	offset int64
+	size   int
+}
+
+// encodeEvents assigns the next global positions to events and encodes them as a single buffer
+// the records of events saved with the outbox carry their initial outbox state, so that events and outbox are written at once
+func (r *fileLogEventRepository) encodeEvents(events []eventsourcing.EventInternal, publishOutbox bool) ([]byte, []encodedFileLogRecord, error) {
+	batch := make(map[uuid.UUID]bool, len(events))
+	records := make([]encodedFileLogRecord, 0, len(events))
+	buf := make([]byte, 0)
+	for i, e := range events {
+		if _, ok := r.byEventId[e.EventId]; ok || batch[e.EventId] {
+			return nil, nil, fmt.Errorf("%w: event(%s)", eventsourcing.ErrEventAlreadyExists, e.EventId)
+		}
+		batch[e.EventId] = true
+
+		record := encodedFileLogRecord{
+			fileLogEventRecord: toFileLogEventRecord(e),
+			offset:             int64(len(buf)),
+		}
+		record.GlobalPosition = r.position + int64(i) + 1
+		record.Outbox = publishOutbox
+		record.Published = publishOutbox && e.EventPublished
+
+		var err error
+		buf, err = appendFileLogRecord(buf, record.fileLogEventRecord)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode event(%s): %w", e.EventId, err)
+		}
+		record.size = len(buf) - int(record.offset)
+		records = append(records, record)
+	}
+
+	err := r.chainLinks.check(events)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buf, records, nil
+}
+
+// activeSegment returns the segment to append size bytes to, rotating the current one when it is full
+func (r *fileLogEventRepository) activeSegment(size int64) (*fileLogSegment, error) {
+	if len(r.segments) > 0 {
+		current := r.segments[len(r.segments)-1]
+		if current.size == 0 || current.size+size <= r.options.maxSegmentSize {
+			return current, nil
+		}
+
+		// the rotated segment will not be written anymore
+		err := current.file.Sync()
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync segment: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(r.segmentPath(r.position+1), os.O_CREATE|os.O_RDWR|os.O_APPEND, fileLogFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+
+	segment := &fileLogSegment{basePosition: r.position + 1, file: file}
+	r.segments = append(r.segments, segment)
+
+	return segment, nil
+}
+
+func (r *fileLogEventRepository) write(file *os.File, buf []byte) error {
+	_, err := file.Write(buf)
+	if err != nil {
+		return err
+	}
+
+	if r.options.syncPolicy == FileLogSyncAlways {
+		return file.Sync()
+	}
+
+	return nil
+}
+
+func (r *fileLogEventRepository) writeOutbox(events []eventsourcing.EventInternal, published bool) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	buf := make([]byte, 0)
+	for _, e := range events {
+		var err error
+		buf, err = appendFileLogRecord(buf, fileLogOutboxRecord{EventId: e.EventId, Published: published})
+		if err != nil {
+			return fmt.Errorf("failed to encode outbox state of event(%s): %w", e.EventId, err)
+		}
+	}
+
+	err := r.write(r.outboxFile, buf)
+	if err != nil {
+		return fmt.Errorf("failed to write outbox state: %w", err)
+	}
+
+	for _, e := range events {
+		r.outbox[e.EventId] = published
+	}
+
+	return nil
+}
+
+func (r *fileLogEventRepository) index(record fileLogEventRecord, segment *fileLogSegment, offset int64, size int) {
+	entry := &fileLogEntry{
+		globalPosition:   record.GlobalPosition,
+		eventId:          record.EventId,
+		eventType:        record.EventType,
+		eventIssuedBy:    record.EventIssuedBy,
+		aggregateId:      record.AggregateId,
+		aggregateType:    record.AggregateType,
+		aggregateVersion: record.AggregateVersion,
+		segment:          segment,
+		offset:           offset,
+		size:             size,
+	}
+
+	r.entries = append(r.entries, entry)
+	r.byAggregate[entry.aggregateId] = append(r.byAggregate[entry.aggregateId], entry)
+	r.byEventId[entry.eventId] = entry
+	r.chainLinks.add(record.toEventInternal(false))
+	r.position = record.GlobalPosition
+}
+
+func (r *fileLogEventRepository) read(entries []*fileLogEntry) ([]eventsourcing.EventInternal, error) {
+	events := make([]eventsourcing.EventInternal, 0, len(entries))
+	for _, entry := range entries {
+		data := make([]byte, entry.size)
+		_, err := entry.segment.file.ReadAt(data, entry.offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event(%s): %w", entry.eventId, err)
+		}
+
+		payload, err := readFileLogRecord(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: event(%s): %w", eventsourcing.ErrEventLogCorrupted, entry.eventId, err)
+		}
+
+		var record fileLogEventRecord
+		err = json.Unmarshal(payload, &record)
+		if err != nil {
+			return nil, fmt.Errorf("%w: event(%s): %w", eventsourcing.ErrEventLogCorrupted, entry.eventId, err)
+		}
+
+		events = append(events, record.toEventInternal(r.outbox[entry.eventId]))
+	}
+
+	return events, nil
+}
+
+// loadSegments rebuilds the index from the segment files
+func (r *fileLogEventRepository) loadSegments() error {
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*"+fileLogSegmentExt))
+	if err != nil {
+		return err
+	}
+	// segments are named after a zero padded position
+	sort.Strings(paths)
+
+	for i, path := range paths {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, fileLogFilePerm)
+		if err != nil {
+			return fmt.Errorf("failed to open segment %s: %w", path, err)
+		}
+		segment := &fileLogSegment{basePosition: r.position + 1, file: file}
+		r.segments = append(r.segments, segment)
+
+		size, torn, err := scanFileLog(file, func(offset int64, payload []byte) error {
+			var record fileLogEventRecord
+			err := json.Unmarshal(payload, &record)
+			if err != nil {
+				return err
+			}
+			r.index(record, segment, offset, fileLogHeaderSize+len(payload))
+			if record.Outbox {
+				r.outbox[record.EventId] = record.Published
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if torn {
+			if i != len(paths)-1 {
+				return fmt.Errorf("%w: segment %s is truncated at offset %d", eventsourcing.ErrEventLogCorrupted, path, size)
+			}
+			err = truncateTornRecord(file, size)
+			if err != nil {
+				return err
+			}
+		}
+		segment.size = size
+	}
+
+	return nil
+}
+
+// loadOutbox replays the outbox state changes over the initial states read from the segments and compacts them in a new state file
+func (r *fileLogEventRepository) loadOutbox() error {
+	path := filepath.Join(r.dir, fileLogOutboxFile)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, fileLogFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox state: %w", err)
+	}
+	defer file.Close()
+
+	_, _, err = scanFileLog(file, func(_ int64, payload []byte) error {
+		var record fileLogOutboxRecord
+		err := json.Unmarshal(payload, &record)
+		if err != nil {
+			return err
+		}
+		r.outbox[record.EventId] = record.Published
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the state is rewritten in position order so that a torn record is dropped
+	buf := make([]byte, 0)
+	for _, entry := range r.entries {
+		published, ok := r.outbox[entry.eventId]
+		if !ok {
+			continue
+		}
+		buf, err = appendFileLogRecord(buf, fileLogOutboxRecord{EventId: entry.eventId, Published: published})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeFileAtomically(path, buf)
+	if err != nil {
+		return fmt.Errorf("failed to compact outbox state: %w", err)
+	}
+
+	r.outboxFile, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND, fileLogFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *fileLogEventRepository) syncPeriodically() {
+	defer r.stopped.Done()
+
+	ticker := time.NewTicker(r.options.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.mtx.Lock()
+			err := r.sync()
+			r.mtx.Unlock()
+			if err != nil {
+				log.Error().Err(err).Str("dir", r.dir).Msg("event repository: failed to sync event log")
+			}
+		}
+	}
+}
+
+func (r *fileLogEventRepository) sync() error {
+	if len(r.segments) > 0 {
+		err := r.segments[len(r.segments)-1].file.Sync()
+		if err != nil {
+			return fmt.Errorf("failed to sync segment: %w", err)
+		}
+	}
+
+	if r.outboxFile != nil {
+		err := r.outboxFile.Sync()
+		if err != nil {
+			return fmt.Errorf("failed to sync outbox state: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *fileLogEventRepository) closeFiles() {
+	for _, segment := range r.segments {
+		_ = segment.file.Close()
+	}
+	if r.outboxFile != nil {
+		_ = r.outboxFile.Close()
+	}
+}
+
+func (r *fileLogEventRepository) segmentPath(basePosition int64) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%020d%s", basePosition, fileLogSegmentExt))
+}
+
+func truncateTornRecord(file *os.File, size int64) error {
+	log.Warn().Str("segment", file.Name()).Int64("offset", size).Msg("event repository: truncating torn record")
+
+	err := file.Truncate(size)
+	if err != nil {
+		return fmt.Errorf("failed to truncate torn record of %s: %w", file.Name(), err)
+	}
+
+	return file.Sync()
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileLogFilePerm)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	return os.Rename(tmp, path)
+}
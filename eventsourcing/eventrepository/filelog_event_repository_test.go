@@ -0,0 +1,181 @@
+//go:build unit
+
+package eventrepository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository/conformance"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileLogTestRepository(t *testing.T, dir string, opts ...FileLogOption) *fileLogEventRepository {
+	t.Helper()
+
+	repo, err := NewFileLogEventRepository(dir, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	return repo
+}
+
+func newFileLogTestEvents(aggregateId uuid.UUID, nb int) []eventsourcing.EventInternal {
+	events := make([]eventsourcing.EventInternal, 0, nb)
+	for i := 0; i < nb; i++ {
+		events = append(events, eventsourcing.EventInternal{
+			EventId:          uuid.New(),
+			EventIssuedAt:    time.Now().UTC(),
+			EventIssuedBy:    uuid.New().String(),
+			EventType:        eventsourcing.EventType("name-set"),
+			EventData:        []byte(`{"Name":"john"}`),
+			AggregateId:      aggregateId,
+			AggregateType:    "test",
+			AggregateVersion: i + 1,
+		})
+	}
+
+	return events
+}
+
+func TestFileLogEventRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("index and outbox are rebuilt on open", func(t *testing.T) {
+		dir := t.TempDir()
+		aggregateId := uuid.New()
+		events := newFileLogTestEvents(aggregateId, 3)
+
+		repo := newFileLogTestRepository(t, dir, FileLogWithMaxSegmentSize(1))
+		require.NoError(t, repo.Save(ctx, true, events...))
+		require.NoError(t, repo.Save(ctx, true, newFileLogTestEvents(uuid.New(), 1)...))
+		require.NoError(t, repo.MarkAs(ctx, true, events[0]))
+		require.NoError(t, repo.Close())
+
+		segments, err := filepath.Glob(filepath.Join(dir, "*"+fileLogSegmentExt))
+		require.NoError(t, err)
+		assert.Len(t, segments, 2)
+
+		repo = newFileLogTestRepository(t, dir)
+		loaded, err := repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithAggregateId(aggregateId)))
+		require.NoError(t, err)
+		require.Len(t, loaded, 3)
+		assert.Equal(t, events[2].EventId, loaded[2].EventId)
+		assert.Equal(t, int64(3), loaded[2].GlobalPosition)
+		assert.True(t, loaded[0].EventPublished)
+
+		unpublished, err := repo.GetUnpublished(ctx, "test", 10)
+		require.NoError(t, err)
+		assert.Len(t, unpublished, 3)
+
+		require.NoError(t, repo.Save(ctx, false, newFileLogTestEvents(uuid.New(), 1)...))
+		all, err := repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithAfterPosition(4)))
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, int64(5), all[0].GlobalPosition)
+	})
+
+	t.Run("torn record is truncated", func(t *testing.T) {
+		dir := t.TempDir()
+		events := newFileLogTestEvents(uuid.New(), 2)
+
+		repo := newFileLogTestRepository(t, dir)
+		require.NoError(t, repo.Save(ctx, false, events...))
+		require.NoError(t, repo.Close())
+
+		segment := filepath.Join(dir, "00000000000000000001"+fileLogSegmentExt)
+		info, err := os.Stat(segment)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(segment, info.Size()-3))
+
+		repo = newFileLogTestRepository(t, dir)
+		loaded, err := repo.Get(ctx, eventsourcing.NewEventQuery())
+		require.NoError(t, err)
+		require.Len(t, loaded, 1)
+		assert.Equal(t, events[0].EventId, loaded[0].EventId)
+
+		require.NoError(t, repo.Save(ctx, false, events[1]))
+		loaded, err = repo.Get(ctx, eventsourcing.NewEventQuery())
+		require.NoError(t, err)
+		assert.Len(t, loaded, 2)
+	})
+
+	t.Run("corrupted segment", func(t *testing.T) {
+		dir := t.TempDir()
+
+		repo := newFileLogTestRepository(t, dir, FileLogWithMaxSegmentSize(1))
+		require.NoError(t, repo.Save(ctx, false, newFileLogTestEvents(uuid.New(), 1)...))
+		require.NoError(t, repo.Save(ctx, false, newFileLogTestEvents(uuid.New(), 1)...))
+		require.NoError(t, repo.Close())
+
+		segment := filepath.Join(dir, "00000000000000000001"+fileLogSegmentExt)
+		data, err := os.ReadFile(segment)
+		require.NoError(t, err)
+		data[len(data)-2] ^= 0xff
+		require.NoError(t, os.WriteFile(segment, data, fileLogFilePerm))
+
+		_, err = NewFileLogEventRepository(dir)
+		assert.ErrorIs(t, err, eventsourcing.ErrEventLogCorrupted)
+	})
+
+	t.Run("outbox is rebuilt from the segments", func(t *testing.T) {
+		dir := t.TempDir()
+		events := newFileLogTestEvents(uuid.New(), 2)
+
+		repo := newFileLogTestRepository(t, dir)
+		// the outbox state file can not be written anymore
+		readOnly, err := os.Open(filepath.Join(dir, fileLogOutboxFile))
+		require.NoError(t, err)
+		require.NoError(t, repo.outboxFile.Close())
+		repo.outboxFile = readOnly
+		require.NoError(t, repo.Save(ctx, true, events[0]))
+		require.NoError(t, repo.Save(ctx, false, events[1]))
+		assert.Error(t, repo.MarkAs(ctx, true, events[0]))
+		require.NoError(t, repo.Close())
+
+		// a crash lost the outbox state
+		require.NoError(t, os.Truncate(filepath.Join(dir, fileLogOutboxFile), 0))
+
+		repo = newFileLogTestRepository(t, dir)
+		unpublished, err := repo.GetUnpublished(ctx, "test", 10)
+		require.NoError(t, err)
+		require.Len(t, unpublished, 1)
+		assert.Equal(t, events[0].EventId, unpublished[0].EventId)
+	})
+
+	t.Run("events saved without outbox are not marked", func(t *testing.T) {
+		dir := t.TempDir()
+		events := newFileLogTestEvents(uuid.New(), 1)
+
+		repo := newFileLogTestRepository(t, dir)
+		require.NoError(t, repo.Save(ctx, false, events...))
+		require.NoError(t, repo.MarkAs(ctx, false, events...))
+		require.NoError(t, repo.Close())
+
+		repo = newFileLogTestRepository(t, dir)
+		unpublished, err := repo.GetUnpublished(ctx, "test", 10)
+		require.NoError(t, err)
+		assert.Empty(t, unpublished)
+	})
+
+	t.Run("duplicate event id", func(t *testing.T) {
+		repo := newFileLogTestRepository(t, t.TempDir(), FileLogWithSyncPolicy(FileLogSyncInterval, time.Millisecond))
+		events := newFileLogTestEvents(uuid.New(), 1)
+		require.NoError(t, repo.Save(ctx, false, events...))
+
+		err := repo.Save(ctx, false, events...)
+		assert.ErrorIs(t, err, eventsourcing.ErrEventAlreadyExists)
+	})
+}
+
+func TestFileLogEventRepositoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) eventsourcing.EventRepository {
+		return newFileLogTestRepository(t, t.TempDir())
+	})
+}
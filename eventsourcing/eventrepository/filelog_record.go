@@ -0,0 +1,162 @@
+package eventrepository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+// records are framed as | payload length (uint32) | crc32c of the payload (uint32) | json payload |
+const (
+	fileLogHeaderSize    = 8
+	fileLogMaxRecordSize = 64 << 20
+)
+
+//nolint:gochecknoglobals
+var fileLogCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord reports a record which has not been entirely written
+var errTornRecord = errors.New("torn record") //nolint:gochecknoglobals
+
+type fileLogEventRecord struct {
+	GlobalPosition    int64                       `json:"global_position"`
+	EventId           uuid.UUID                   `json:"event_id"`
+	EventType         eventsourcing.EventType     `json:"event_type"`
+	EventIssuedAt     time.Time                   `json:"event_issued_at"`
+	EventIssuedBy     string                      `json:"event_issued_by"`
+	EventData         json.RawMessage             `json:"event_data,omitempty"`
+	AggregateId       uuid.UUID                   `json:"aggregate_id"`
+	AggregateType     eventsourcing.AggregateType `json:"aggregate_type"`
+	AggregateVersion  int                         `json:"aggregate_version"`
+	EventHash         []byte                      `json:"event_hash,omitempty"`
+	PreviousEventHash []byte                      `json:"previous_event_hash,omitempty"`
+	EventSignature    []byte                      `json:"event_signature,omitempty"`
+	// Outbox tells that the event was saved with the outbox, Published is its initial state
+	// later changes of the state are recorded in the outbox state file
+	Outbox    bool `json:"outbox,omitempty"`
+	Published bool `json:"published,omitempty"`
+}
+
+type fileLogOutboxRecord struct {
+	EventId   uuid.UUID `json:"event_id"`
+	Published bool      `json:"published"`
+}
+
+func toFileLogEventRecord(e eventsourcing.EventInternal) fileLogEventRecord {
+	return fileLogEventRecord{
+		GlobalPosition:    e.GlobalPosition,
+		EventId:           e.EventId,
+		EventType:         e.EventType,
+		EventIssuedAt:     e.EventIssuedAt,
+		EventIssuedBy:     e.EventIssuedBy,
+		EventData:         e.EventData,
+		AggregateId:       e.AggregateId,
+		AggregateType:     e.AggregateType,
+		AggregateVersion:  e.AggregateVersion,
+		EventHash:         e.EventHash,
+		PreviousEventHash: e.PreviousEventHash,
+		EventSignature:    e.EventSignature,
+	}
+}
+
+func (r fileLogEventRecord) toEventInternal(published bool) eventsourcing.EventInternal {
+	return eventsourcing.EventInternal{
+		EventId:           r.EventId,
+		EventType:         r.EventType,
+		EventIssuedAt:     r.EventIssuedAt,
+		EventIssuedBy:     r.EventIssuedBy,
+		EventData:         r.EventData,
+		EventPublished:    published,
+		AggregateId:       r.AggregateId,
+		AggregateType:     r.AggregateType,
+		AggregateVersion:  r.AggregateVersion,
+		GlobalPosition:    r.GlobalPosition,
+		EventHash:         r.EventHash,
+		PreviousEventHash: r.PreviousEventHash,
+		EventSignature:    r.EventSignature,
+	}
+}
+
+// appendFileLogRecord frames a json encoded payload and appends it to buf
+func appendFileLogRecord(buf []byte, v any) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var header [fileLogHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], crc32.Checksum(payload, fileLogCRCTable))
+
+	buf = append(buf, header[:]...)
+	return append(buf, payload...), nil
+}
+
+// readFileLogRecord reads the next record of a log, it returns io.EOF at the end of the log
+// and errTornRecord when the record is incomplete or its checksum does not match
+func readFileLogRecord(r io.Reader) ([]byte, error) {
+	var header [fileLogHeaderSize]byte
+	_, err := io.ReadFull(r, header[:])
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, errTornRecord
+	}
+
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > fileLogMaxRecordSize {
+		return nil, errTornRecord
+	}
+
+	payload := make([]byte, size)
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return nil, errTornRecord
+	}
+
+	if crc32.Checksum(payload, fileLogCRCTable) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errTornRecord
+	}
+
+	return payload, nil
+}
+
+// scanFileLog calls fn with every record of a log file and its offset
+// it returns the offset following the last valid record and whether the log ends with a torn record
+func scanFileLog(file *os.File, fn func(offset int64, payload []byte) error) (int64, bool, error) {
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, false, err
+	}
+
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	for {
+		payload, err := readFileLogRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return offset, false, nil
+		}
+		if errors.Is(err, errTornRecord) {
+			return offset, true, nil
+		}
+		if err != nil {
+			return offset, false, err
+		}
+
+		err = fn(offset, payload)
+		if err != nil {
+			return offset, false, fmt.Errorf("%w: record at offset %d of %s: %w", eventsourcing.ErrEventLogCorrupted, offset, file.Name(), err)
+		}
+		offset += int64(fileLogHeaderSize + len(payload))
+	}
+}
@@ -0,0 +1,69 @@
+package eventrepository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// hashChainIndex is the unique index of the sql schemas rejecting two chained events with the same predecessor
+	hashChainIndex = "events_hash_chain_idx"
+	// pgUniqueViolation is the sqlstate of unique violations
+	pgUniqueViolation = "23505"
+)
+
+// hashChainLinks indexes the previous hash of the chained events of every aggregate, for repositories without unique indexes
+type hashChainLinks map[uuid.UUID]map[string]bool
+
+// check fails with ErrHashChainConflict when an event is chained to a predecessor already linked to another event
+func (l hashChainLinks) check(events []eventsourcing.EventInternal) error {
+	batch := make(hashChainLinks)
+	for _, e := range events {
+		if e.EventHash == nil {
+			continue
+		}
+
+		previous := string(e.PreviousEventHash)
+		if l[e.AggregateId][previous] || batch[e.AggregateId][previous] {
+			return fmt.Errorf("%w: event(%s) of aggregate(%s)", eventsourcing.ErrHashChainConflict, e.EventId, e.AggregateId)
+		}
+		batch.add(e)
+	}
+
+	return nil
+}
+
+func (l hashChainLinks) add(e eventsourcing.EventInternal) {
+	if e.EventHash == nil {
+		return
+	}
+
+	if l[e.AggregateId] == nil {
+		l[e.AggregateId] = make(map[string]bool)
+	}
+	l[e.AggregateId][string(e.PreviousEventHash)] = true
+}
+
+// wrapHashChainConflict wraps the violations of the hash chain index with ErrHashChainConflict
+func wrapHashChainConflict(err error) error {
+	var (
+		pgErr     *pgconn.PgError
+		sqliteErr *sqlite.Error
+	)
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == hashChainIndex:
+	// sqlite only names the violated index in the message of the error
+	case errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE && strings.Contains(sqliteErr.Error(), "index '"+hashChainIndex+"'"):
+	default:
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", eventsourcing.ErrHashChainConflict, err)
+}
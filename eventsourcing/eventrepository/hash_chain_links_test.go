@@ -0,0 +1,38 @@
+//go:build unit
+
+package eventrepository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapHashChainConflict(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err      error
+		conflict bool
+	}{
+		"pg violation of the hash chain index": {
+			err:      fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: hashChainIndex}),
+			conflict: true,
+		},
+		"pg violation of another index": {
+			err: &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "events_pkey"},
+		},
+		"pg error of another kind": {
+			err: &pgconn.PgError{Code: "23503", ConstraintName: hashChainIndex},
+		},
+		"error naming the index": {
+			err: errors.New("UNIQUE constraint failed: index '" + hashChainIndex + "'"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.conflict, errors.Is(wrapHashChainConflict(tc.err), eventsourcing.ErrHashChainConflict))
+		})
+	}
+}
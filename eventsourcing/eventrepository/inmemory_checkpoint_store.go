@@ -0,0 +1,34 @@
+package eventrepository
+
+import (
+	"context"
+	"sync"
+)
+
+// inMemoryCheckpointStore implements eventsourcing.CheckpointStore, the positions are lost with the process
+type inMemoryCheckpointStore struct {
+	mtx       sync.RWMutex
+	positions map[string]int64
+}
+
+// NewInMemoryCheckpointStore creates a checkpoint store for the repositories which do not store checkpoints
+func NewInMemoryCheckpointStore() *inMemoryCheckpointStore {
+	return &inMemoryCheckpointStore{
+		positions: make(map[string]int64),
+	}
+}
+
+func (s *inMemoryCheckpointStore) GetCheckpoint(_ context.Context, name string) (int64, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.positions[name], nil
+}
+
+func (s *inMemoryCheckpointStore) SaveCheckpoint(_ context.Context, name string, position int64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.positions[name] = position
+	return nil
+}
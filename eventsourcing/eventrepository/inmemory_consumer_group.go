@@ -0,0 +1,100 @@
+package eventrepository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+)
+
+// inMemoryConsumerGroupCoordinator mirrors the semantics of the pg coordinator for the members of a single process
+type inMemoryConsumerGroupCoordinator struct {
+	mtx    sync.Mutex
+	groups map[string]*inMemoryConsumerGroup
+}
+
+type inMemoryConsumerGroup struct {
+	// members expire at the end of their lease
+	members map[string]time.Time
+	leases  map[int]inMemoryPartitionLease
+}
+
+type inMemoryPartitionLease struct {
+	member      string
+	leasedUntil time.Time
+}
+
+func NewInMemoryConsumerGroupCoordinator() *inMemoryConsumerGroupCoordinator {
+	return &inMemoryConsumerGroupCoordinator{
+		groups: make(map[string]*inMemoryConsumerGroup),
+	}
+}
+
+func (c *inMemoryConsumerGroupCoordinator) Heartbeat(_ context.Context, group string, member string, partitions int, ttl time.Duration) ([]int, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	g, ok := c.groups[group]
+	if !ok {
+		g = &inMemoryConsumerGroup{
+			members: make(map[string]time.Time),
+			leases:  make(map[int]inMemoryPartitionLease),
+		}
+		c.groups[group] = g
+	}
+
+	now := time.Now()
+	leasedUntil := now.Add(ttl)
+	g.members[member] = leasedUntil
+
+	members := make([]string, 0, len(g.members))
+	for m, expiresAt := range g.members {
+		if expiresAt.Before(now) {
+			delete(g.members, m)
+			continue
+		}
+		members = append(members, m)
+	}
+
+	assigned := eventsourcing.AssignPartitions(members, member, partitions)
+	for partition, lease := range g.leases {
+		if lease.member == member && !slices.Contains(assigned, partition) {
+			delete(g.leases, partition)
+		}
+	}
+
+	leased := make([]int, 0, len(assigned))
+	for _, partition := range assigned {
+		lease, ok := g.leases[partition]
+		if ok && lease.member != member && !lease.leasedUntil.Before(now) {
+			// the partition is released by its previous member on its next heartbeat
+			continue
+		}
+
+		g.leases[partition] = inMemoryPartitionLease{member: member, leasedUntil: leasedUntil}
+		leased = append(leased, partition)
+	}
+
+	return leased, nil
+}
+
+func (c *inMemoryConsumerGroupCoordinator) Leave(_ context.Context, group string, member string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	g, ok := c.groups[group]
+	if !ok {
+		return nil
+	}
+
+	delete(g.members, member)
+	for partition, lease := range g.leases {
+		if lease.member == member {
+			delete(g.leases, partition)
+		}
+	}
+
+	return nil
+}
@@ -1,26 +1,99 @@
 package eventrepository
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/davidterranova/cqrs/eventsourcing"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
+// inMemoryColumn mirrors a column of the events table for GroupBy and OrderBy
+type inMemoryColumn struct {
+	key     func(e *eventsourcing.EventInternal) any
+	compare func(a, b *eventsourcing.EventInternal) int
+}
+
+//nolint:gochecknoglobals
+var inMemoryColumns = map[string]inMemoryColumn{
+	"event_id": {
+		key:     func(e *eventsourcing.EventInternal) any { return e.EventId },
+		compare: func(a, b *eventsourcing.EventInternal) int { return bytes.Compare(a.EventId[:], b.EventId[:]) },
+	},
+	"event_type": {
+		key:     func(e *eventsourcing.EventInternal) any { return e.EventType },
+		compare: func(a, b *eventsourcing.EventInternal) int { return cmp.Compare(a.EventType, b.EventType) },
+	},
+	"event_issued_by": {
+		key:     func(e *eventsourcing.EventInternal) any { return e.EventIssuedBy },
+		compare: func(a, b *eventsourcing.EventInternal) int { return cmp.Compare(a.EventIssuedBy, b.EventIssuedBy) },
+	},
+	"event_issued_at": {
+		key:     func(e *eventsourcing.EventInternal) any { return e.EventIssuedAt.UnixNano() },
+		compare: func(a, b *eventsourcing.EventInternal) int { return a.EventIssuedAt.Compare(b.EventIssuedAt) },
+	},
+	"aggregate_id": {
+		key:     func(e *eventsourcing.EventInternal) any { return e.AggregateId },
+		compare: func(a, b *eventsourcing.EventInternal) int { return bytes.Compare(a.AggregateId[:], b.AggregateId[:]) },
+	},
+	"aggregate_type": {
+		key:     func(e *eventsourcing.EventInternal) any { return e.AggregateType },
+		compare: func(a, b *eventsourcing.EventInternal) int { return cmp.Compare(a.AggregateType, b.AggregateType) },
+	},
+	"aggregate_version": {
+		key: func(e *eventsourcing.EventInternal) any { return e.AggregateVersion },
+		compare: func(a, b *eventsourcing.EventInternal) int {
+			return cmp.Compare(a.AggregateVersion, b.AggregateVersion)
+		},
+	},
+	eventsourcing.OrderByGlobalPosition: {
+		key:     func(e *eventsourcing.EventInternal) any { return e.GlobalPosition },
+		compare: func(a, b *eventsourcing.EventInternal) int { return cmp.Compare(a.GlobalPosition, b.GlobalPosition) },
+	},
+}
+
+// inMemoryEventRepository mirrors the semantics of the pg repository
+// the events table is the events slice, the events_outbox table is the outbox map
 type inMemoryEventRepository struct {
-	// events are stored by aggregate id
+	*inMemoryCheckpointStore
+	// events are stored by global position
+	events []*eventsourcing.EventInternal
+	// indexes on the events
 	aggregateEvents map[uuid.UUID][]*eventsourcing.EventInternal
-	// outbox for unpublished events
-	outbox []*eventsourcing.EventInternal
-	mtx    sync.RWMutex
+	eventIds        map[uuid.UUID]*eventsourcing.EventInternal
+	// chainLinks mirrors the unique index on the predecessors of chained events
+	chainLinks hashChainLinks
+	// outbox holds the published state of events saved with the outbox
+	outbox map[uuid.UUID]bool
+	// leases of the unpublished events returned by GetUnpublished
+	leases *outboxLeases
+	// failed publication attempts of outbox events and events which failed too many times
+	failures    map[uuid.UUID]int
+	deadLetters map[uuid.UUID]*eventsourcing.DeadLetter
+	// position is the last global position assigned to an event
+	position int64
+	mtx      sync.RWMutex
 }
 
-func NewInMemoryEventRepository() eventsourcing.EventRepository {
+func NewInMemoryEventRepository(opts ...EventRepositoryOption) eventsourcing.EventRepository {
+	options := newEventRepositoryOptions(opts)
+
 	return &inMemoryEventRepository{
-		aggregateEvents: make(map[uuid.UUID][]*eventsourcing.EventInternal),
-		outbox:          make([]*eventsourcing.EventInternal, 0),
+		inMemoryCheckpointStore: NewInMemoryCheckpointStore(),
+		aggregateEvents:         make(map[uuid.UUID][]*eventsourcing.EventInternal),
+		eventIds:                make(map[uuid.UUID]*eventsourcing.EventInternal),
+		chainLinks:              make(hashChainLinks),
+		outbox:                  make(map[uuid.UUID]bool),
+		leases:                  newOutboxLeases(options.outboxLease),
+		failures:                make(map[uuid.UUID]int),
+		deadLetters:             make(map[uuid.UUID]*eventsourcing.DeadLetter),
 	}
 }
 
@@ -28,8 +101,20 @@ func (r *inMemoryEventRepository) Save(_ context.Context, publishOutbox bool, ev
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
+	// events are saved all together or not at all as in a transaction
+	batch := make(map[uuid.UUID]bool, len(events))
+	for _, e := range events {
+		if _, ok := r.eventIds[e.EventId]; ok || batch[e.EventId] {
+			return fmt.Errorf("%w: event(%s)", eventsourcing.ErrEventAlreadyExists, e.EventId)
+		}
+		batch[e.EventId] = true
+	}
+	err := r.chainLinks.check(events)
+	if err != nil {
+		return err
+	}
+
 	for _, e := range events {
-		e := e
 		log.Debug().
 			Str("event_id", e.EventId.String()).
 			Str("event_type", string(e.EventType)).
@@ -37,16 +122,20 @@ func (r *inMemoryEventRepository) Save(_ context.Context, publishOutbox bool, ev
 			Str("aggregate_id", e.AggregateId.String()).
 			Msg("event repository: saving event")
 
-		aggregateId := e.AggregateId
-		aggregateEvents, ok := r.aggregateEvents[aggregateId]
-		if !ok {
-			aggregateEvents = make([]*eventsourcing.EventInternal, 0)
-		}
-		aggregateEvents = append(aggregateEvents, &e)
-		r.aggregateEvents[aggregateId] = aggregateEvents
+		r.position++
+		stored := cloneEventInternal(e)
+		stored.GlobalPosition = r.position
+		// the published state is held by the outbox
+		stored.EventPublished = false
+
+		r.events = append(r.events, &stored)
+		r.aggregateEvents[stored.AggregateId] = append(r.aggregateEvents[stored.AggregateId], &stored)
+		r.eventIds[stored.EventId] = &stored
+		r.chainLinks.add(stored)
 
-		// outbox
-		r.outbox = append(r.outbox, &e)
+		if publishOutbox {
+			r.outbox[stored.EventId] = e.EventPublished
+		}
 	}
 
 	return nil
@@ -57,77 +146,88 @@ func (r *inMemoryEventRepository) Get(_ context.Context, filter eventsourcing.Ev
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
 
-	events := make([]eventsourcing.EventInternal, 0)
-	for _, me := range r.outbox {
-		add := true
-
-		if filter.AggregateId() != nil && *filter.AggregateId() != me.AggregateId {
-			add = false
-		}
+	candidates := r.events
+	if filter.AggregateId() != nil {
+		candidates = r.aggregateEvents[*filter.AggregateId()]
+	}
 
+	matches := make([]*eventsourcing.EventInternal, 0)
+	for _, me := range candidates {
 		if filter.AggregateType() != nil && *filter.AggregateType() != me.AggregateType {
-			add = false
+			continue
 		}
-
 		if filter.EventType() != nil && *filter.EventType() != me.EventType {
-			add = false
+			continue
 		}
-
-		if filter.Published() != nil && *filter.Published() != me.EventPublished {
-			add = false
+		if filter.Published() != nil {
+			// as the pg join, events which are not in the outbox never match
+			published, ok := r.outbox[me.EventId]
+			if !ok || published != *filter.Published() {
+				continue
+			}
 		}
-
 		if filter.IssuedBy() != nil && filter.IssuedBy().String() != me.EventIssuedBy {
-			add = false
+			continue
 		}
-
-		if filter.Limit() != nil && len(events) >= *filter.Limit() {
-			add = false
+		if filter.UpToVersion() != nil && me.AggregateVersion > *filter.UpToVersion() {
+			continue
+		}
+		if filter.AfterPosition() != nil && me.GlobalPosition <= *filter.AfterPosition() {
+			continue
 		}
 
-		// if filter.OrderBy() != nil {
-		// 	// TODO
-		// }
+		matches = append(matches, me)
+	}
 
-		// if filter.GroupBy() != nil {
-		// 	// TODO
-		// }
+	matches, err := inMemoryGroupBy(matches, filter.GroupBy())
+	if err != nil {
+		return nil, err
+	}
 
-		if filter.UpToVersion() != nil && me.AggregateVersion > *filter.UpToVersion() {
-			add = false
-		}
+	orderBy, direction := filter.OrderBy()
+	err = inMemoryOrderBy(matches, orderBy, direction)
+	if err != nil {
+		return nil, err
+	}
 
-		if add {
-			events = append(events, *me)
-		}
+	if filter.Limit() != nil && len(matches) > *filter.Limit() {
+		matches = matches[:*filter.Limit()]
 	}
 
-	return events, nil
+	return r.copyEvents(matches), nil
 }
 
 func (r *inMemoryEventRepository) GetUnpublished(_ context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
-	r.mtx.RLock()
-	defer r.mtx.RUnlock()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
 
-	nbEvents := len(r.outbox)
-	if batchSize < nbEvents {
-		nbEvents = batchSize
-	}
-
-	unPublished := make([]eventsourcing.EventInternal, 0, nbEvents)
-	for _, me := range r.outbox {
-		if !me.EventPublished && me.AggregateType == aggregateType {
-			log.Debug().
-				Str("event_type", string(me.EventType)).
-				Str("event_id", me.EventId.String()).
-				Str("aggregate_type", string(me.AggregateType)).
-				Str("aggregate_id", me.AggregateId.String()).
-				Msg("event repository: loading unpublished event")
-			unPublished = append(unPublished, *me)
+	pending := make([]*eventsourcing.EventInternal, 0)
+	candidates := make([]outboxCandidate, 0)
+	for _, me := range r.events {
+		published, ok := r.outbox[me.EventId]
+		if !ok || published || !matchesAggregateType(me.AggregateType, aggregateType) {
+			continue
 		}
+
+		pending = append(pending, me)
+		_, deadLettered := r.deadLetters[me.EventId]
+		candidates = append(candidates, outboxCandidate{eventId: me.EventId, aggregateId: me.AggregateId, deadLettered: deadLettered})
 	}
 
-	return unPublished, nil
+	claimed := r.leases.claim(candidates, batchSize, time.Now())
+	unpublished := make([]*eventsourcing.EventInternal, 0, len(claimed))
+	for _, i := range claimed {
+		me := pending[i]
+		log.Debug().
+			Str("event_type", string(me.EventType)).
+			Str("event_id", me.EventId.String()).
+			Str("aggregate_type", string(me.AggregateType)).
+			Str("aggregate_id", me.AggregateId.String()).
+			Msg("event repository: loading unpublished event")
+		unpublished = append(unpublished, me)
+	}
+
+	return r.copyEvents(unpublished), nil
 }
 
 func (r *inMemoryEventRepository) MarkAs(_ context.Context, asPublished bool, events ...eventsourcing.EventInternal) error {
@@ -139,18 +239,178 @@ func (r *inMemoryEventRepository) MarkAs(_ context.Context, asPublished bool, ev
 		Bool("published", asPublished).
 		Msg("marking events as")
 	for _, e := range events {
-		for _, me := range r.outbox {
-			if me.EventId == e.EventId {
-				log.Debug().
-					Str("event_id", me.EventId.String()).
-					Str("event_type", string(me.EventType)).
-					Str("aggregate_type", string(me.AggregateType)).
-					Bool("published", asPublished).
-					Msg("event repository: marking event as")
-				me.EventPublished = asPublished
-			}
+		// as the pg update, only events in the outbox are marked
+		if _, ok := r.outbox[e.EventId]; !ok {
+			continue
+		}
+
+		log.Debug().
+			Str("event_id", e.EventId.String()).
+			Str("event_type", string(e.EventType)).
+			Str("aggregate_type", string(e.AggregateType)).
+			Bool("published", asPublished).
+			Msg("event repository: marking event as")
+		r.outbox[e.EventId] = asPublished
+		r.leases.release(e.EventId)
+	}
+
+	return nil
+}
+
+func (r *inMemoryEventRepository) MarkFailed(_ context.Context, event eventsourcing.EventInternal, reason string, maxAttempts int) (bool, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.outbox[event.EventId]; !ok {
+		return false, nil
+	}
+
+	r.leases.release(event.EventId)
+	r.failures[event.EventId]++
+	if r.failures[event.EventId] < maxAttempts {
+		return false, nil
+	}
+
+	r.deadLetters[event.EventId] = &eventsourcing.DeadLetter{
+		Attempts:       r.failures[event.EventId],
+		Reason:         reason,
+		DeadLetteredAt: time.Now().UTC(),
+	}
+
+	return true, nil
+}
+
+func (r *inMemoryEventRepository) ListDeadLetters(_ context.Context, aggregateType *eventsourcing.AggregateType) ([]eventsourcing.DeadLetter, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	deadLetters := make([]eventsourcing.DeadLetter, 0)
+	for _, me := range r.events {
+		if _, ok := r.deadLetters[me.EventId]; !ok {
+			continue
 		}
+		if aggregateType != nil && *aggregateType != me.AggregateType {
+			continue
+		}
+
+		deadLetters = append(deadLetters, r.copyDeadLetter(me))
+	}
+
+	return deadLetters, nil
+}
+
+func (r *inMemoryEventRepository) GetDeadLetter(_ context.Context, eventId uuid.UUID) (eventsourcing.DeadLetter, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if _, ok := r.deadLetters[eventId]; !ok {
+		return eventsourcing.DeadLetter{}, fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
+	}
+
+	return r.copyDeadLetter(r.eventIds[eventId]), nil
+}
+
+func (r *inMemoryEventRepository) RetryDeadLetter(_ context.Context, eventId uuid.UUID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.deadLetters[eventId]; !ok {
+		return fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
 	}
 
+	delete(r.deadLetters, eventId)
+	delete(r.failures, eventId)
+
+	return nil
+}
+
+func (r *inMemoryEventRepository) DiscardDeadLetter(_ context.Context, eventId uuid.UUID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.deadLetters[eventId]; !ok {
+		return fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
+	}
+
+	delete(r.deadLetters, eventId)
+	delete(r.failures, eventId)
+	r.outbox[eventId] = eventsourcing.Published
+
 	return nil
 }
+
+func (r *inMemoryEventRepository) copyDeadLetter(me *eventsourcing.EventInternal) eventsourcing.DeadLetter {
+	deadLetter := *r.deadLetters[me.EventId]
+	deadLetter.Event = r.copyEvents([]*eventsourcing.EventInternal{me})[0]
+
+	return deadLetter
+}
+
+// copyEvents returns copies of stored events with their published state so that callers can not alter the store
+func (r *inMemoryEventRepository) copyEvents(events []*eventsourcing.EventInternal) []eventsourcing.EventInternal {
+	copies := make([]eventsourcing.EventInternal, 0, len(events))
+	for _, me := range events {
+		e := cloneEventInternal(*me)
+		e.EventPublished = r.outbox[me.EventId]
+		copies = append(copies, e)
+	}
+
+	return copies
+}
+
+// inMemoryGroupBy keeps the first event of each group
+func inMemoryGroupBy(events []*eventsourcing.EventInternal, groupBy *string) ([]*eventsourcing.EventInternal, error) {
+	if groupBy == nil {
+		return events, nil
+	}
+
+	column, ok := inMemoryColumns[*groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid column name: %q", *groupBy)
+	}
+
+	groups := make(map[any]bool)
+	grouped := make([]*eventsourcing.EventInternal, 0, len(events))
+	for _, e := range events {
+		key := column.key(e)
+		if groups[key] {
+			continue
+		}
+		groups[key] = true
+		grouped = append(grouped, e)
+	}
+
+	return grouped, nil
+}
+
+// inMemoryOrderBy orders events by the given column, events are ordered by global position by default
+func inMemoryOrderBy(events []*eventsourcing.EventInternal, orderBy *string, direction *string) error {
+	name := eventsourcing.OrderByGlobalPosition
+	if orderBy != nil && *orderBy != "" {
+		name = *orderBy
+	}
+
+	column, ok := inMemoryColumns[name]
+	if !ok {
+		return fmt.Errorf("invalid column name: %q", name)
+	}
+
+	desc := direction != nil && strings.EqualFold(*direction, string(eventsourcing.DESC))
+	slices.SortStableFunc(events, func(a, b *eventsourcing.EventInternal) int {
+		if desc {
+			return column.compare(b, a)
+		}
+		return column.compare(a, b)
+	})
+
+	return nil
+}
+
+func cloneEventInternal(e eventsourcing.EventInternal) eventsourcing.EventInternal {
+	e.EventData = slices.Clone(e.EventData)
+	e.EventHash = slices.Clone(e.EventHash)
+	e.PreviousEventHash = slices.Clone(e.PreviousEventHash)
+	e.EventSignature = slices.Clone(e.EventSignature)
+
+	return e
+}
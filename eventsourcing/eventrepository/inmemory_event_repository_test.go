@@ -8,15 +8,38 @@ import (
 	"time"
 
 	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository/conformance"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// unitEventRepositories returns the repositories which can be tested without external dependencies
+func unitEventRepositories(t *testing.T) map[string]func() eventsourcing.EventRepository {
+	t.Helper()
+
+	return map[string]func() eventsourcing.EventRepository{
+		"in memory": func() eventsourcing.EventRepository {
+			return NewInMemoryEventRepository()
+		},
+		"file log": func() eventsourcing.EventRepository {
+			return newFileLogTestRepository(t, t.TempDir())
+		},
+	}
+}
+
 func TestMarkAs(t *testing.T) {
+	for name, newRepo := range unitEventRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			testMarkAs(t, newRepo)
+		})
+	}
+}
+
+func testMarkAs(t *testing.T, newRepo func() eventsourcing.EventRepository) {
 	ctx := context.Background()
 	t.Run("published", func(t *testing.T) {
-		repo := NewInMemoryEventRepository()
+		repo := newRepo()
 
 		aggregateId := uuid.New()
 		issuedBy := uuid.New().String()
@@ -45,7 +68,7 @@ func TestMarkAs(t *testing.T) {
 			},
 		}
 
-		err := repo.Save(ctx, false, internalEvents...)
+		err := repo.Save(ctx, true, internalEvents...)
 		assert.NoError(t, err)
 
 		err = repo.MarkAs(ctx, true, internalEvents...)
@@ -64,3 +87,140 @@ func TestMarkAs(t *testing.T) {
 		}
 	})
 }
+
+func TestGlobalPosition(t *testing.T) {
+	for name, newRepo := range unitEventRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			testGlobalPosition(t, newRepo())
+		})
+	}
+}
+
+func testGlobalPosition(t *testing.T, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+
+	aggregateId := uuid.New()
+	issuedBy := uuid.New().String()
+	internalEvents := make([]eventsourcing.EventInternal, 0, 3)
+	for i := 0; i < 3; i++ {
+		internalEvents = append(internalEvents, eventsourcing.EventInternal{
+			EventId:          uuid.New(),
+			EventIssuedAt:    time.Now().UTC(),
+			EventIssuedBy:    issuedBy,
+			EventType:        eventsourcing.EventType("name-set"),
+			EventData:        []byte(`{}`),
+			AggregateId:      aggregateId,
+			AggregateType:    "test",
+			AggregateVersion: i,
+		})
+	}
+
+	err := repo.Save(ctx, true, internalEvents...)
+	require.NoError(t, err)
+
+	t.Run("positions are assigned in order", func(t *testing.T) {
+		events, err := repo.Get(ctx, eventsourcing.NewEventQuery())
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		for i, e := range events {
+			assert.Equal(t, int64(i+1), e.GlobalPosition)
+		}
+	})
+
+	t.Run("after position", func(t *testing.T) {
+		events, err := repo.Get(ctx, eventsourcing.NewEventQuery(
+			eventsourcing.EventQueryWithAfterPosition(1),
+		))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, int64(2), events[0].GlobalPosition)
+	})
+
+	t.Run("order by position desc", func(t *testing.T) {
+		events, err := repo.Get(ctx, eventsourcing.NewEventQuery(
+			eventsourcing.EventQueryWithOrderByPosition(eventsourcing.DESC),
+			eventsourcing.EventQueryWithLimit(2),
+		))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, int64(3), events[0].GlobalPosition)
+		assert.Equal(t, int64(2), events[1].GlobalPosition)
+	})
+}
+
+func TestInMemoryEventRepositoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) eventsourcing.EventRepository {
+		return NewInMemoryEventRepository()
+	})
+}
+
+func TestInMemoryOrderAndGroupBy(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryEventRepository()
+
+	aggregateId := uuid.New()
+	for _, version := range []int{2, 1, 3} {
+		err := repo.Save(ctx, false, eventsourcing.EventInternal{
+			EventId:          uuid.New(),
+			EventIssuedAt:    time.Now().UTC(),
+			EventType:        eventsourcing.EventType("name-set"),
+			AggregateId:      aggregateId,
+			AggregateType:    "test",
+			AggregateVersion: version,
+		})
+		require.NoError(t, err)
+	}
+
+	events, err := repo.Get(ctx, eventsourcing.NewEventQuery(
+		eventsourcing.EventQueryWithOrderBy("aggregate_version", string(eventsourcing.DESC)),
+	))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, []int{3, 2, 1}, []int{events[0].AggregateVersion, events[1].AggregateVersion, events[2].AggregateVersion})
+
+	events, err = repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithGroupBy("aggregate_id")))
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	_, err = repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithOrderBy("unknown", string(eventsourcing.ASC))))
+	assert.Error(t, err)
+}
+
+func TestOutboxLeaseExpiry(t *testing.T) {
+	const lease = 100 * time.Millisecond
+
+	repos := map[string]func() eventsourcing.EventRepository{
+		"in memory": func() eventsourcing.EventRepository {
+			return NewInMemoryEventRepository(EventRepositoryWithOutboxLease(lease))
+		},
+		"file log": func() eventsourcing.EventRepository {
+			return newFileLogTestRepository(t, t.TempDir(), FileLogWithOutboxLease(lease))
+		},
+		"sqlite": func() eventsourcing.EventRepository {
+			return newSQLiteTestRepository(t, EventRepositoryWithOutboxLease(lease))
+		},
+	}
+
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo()
+			events := newFileLogTestEvents(uuid.New(), 2)
+			require.NoError(t, repo.Save(ctx, true, events...))
+
+			leased, err := repo.GetUnpublished(ctx, "test", 10)
+			require.NoError(t, err)
+			require.Len(t, leased, 2)
+
+			unpublished, err := repo.GetUnpublished(ctx, "test", 10)
+			require.NoError(t, err)
+			assert.Empty(t, unpublished)
+
+			// events of a worker which stopped without marking them are published by another one
+			time.Sleep(lease)
+			unpublished, err = repo.GetUnpublished(ctx, "test", 10)
+			require.NoError(t, err)
+			assert.Len(t, unpublished, 2)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package eventrepository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type inMemoryInboxKey struct {
+	consumer string
+	eventId  uuid.UUID
+}
+
+// inMemoryInbox implements eventsourcing.Inbox for the consumers of a single process, events are handled one at a time
+type inMemoryInbox struct {
+	mtx       sync.Mutex
+	processed map[inMemoryInboxKey]struct{}
+}
+
+func NewInMemoryInbox() *inMemoryInbox {
+	return &inMemoryInbox{
+		processed: make(map[inMemoryInboxKey]struct{}),
+	}
+}
+
+func (i *inMemoryInbox) Process(ctx context.Context, consumer string, eventId uuid.UUID, handle func(ctx context.Context) error) (bool, error) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	key := inMemoryInboxKey{consumer: consumer, eventId: eventId}
+	if _, ok := i.processed[key]; ok {
+		return false, nil
+	}
+
+	err := handle(ctx)
+	if err != nil {
+		return false, err
+	}
+	i.processed[key] = struct{}{}
+
+	return true, nil
+}
@@ -0,0 +1,212 @@
+package eventrepository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+// inMemoryWebhookRepository mirrors the semantics of the pg webhook repository
+type inMemoryWebhookRepository struct {
+	mtx sync.RWMutex
+	// webhooks are stored by creation
+	webhooks   []*eventsourcing.Webhook
+	deliveries map[uuid.UUID][]eventsourcing.WebhookDelivery
+	// pending are stored by webhook in the order they were queued
+	pending map[uuid.UUID][]eventsourcing.WebhookPendingDelivery
+}
+
+func NewInMemoryWebhookRepository() *inMemoryWebhookRepository {
+	return &inMemoryWebhookRepository{
+		deliveries: make(map[uuid.UUID][]eventsourcing.WebhookDelivery),
+		pending:    make(map[uuid.UUID][]eventsourcing.WebhookPendingDelivery),
+	}
+}
+
+func (r *inMemoryWebhookRepository) CreateWebhook(_ context.Context, webhook eventsourcing.Webhook) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.find(webhook.Id) >= 0 {
+		return fmt.Errorf("%w: webhook(%s) already exists", eventsourcing.ErrInvalidWebhook, webhook.Id)
+	}
+
+	webhook.EventTypes = slices.Clone(webhook.EventTypes)
+	r.webhooks = append(r.webhooks, &webhook)
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) ListWebhooks(_ context.Context) ([]eventsourcing.Webhook, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	webhooks := make([]eventsourcing.Webhook, 0, len(r.webhooks))
+	for _, w := range r.webhooks {
+		webhooks = append(webhooks, copyWebhook(w))
+	}
+
+	return webhooks, nil
+}
+
+func (r *inMemoryWebhookRepository) GetWebhook(_ context.Context, id uuid.UUID) (eventsourcing.Webhook, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	idx := r.find(id)
+	if idx < 0 {
+		return eventsourcing.Webhook{}, fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, id)
+	}
+
+	return copyWebhook(r.webhooks[idx]), nil
+}
+
+func (r *inMemoryWebhookRepository) SetWebhookPaused(_ context.Context, id uuid.UUID, paused bool) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	idx := r.find(id)
+	if idx < 0 {
+		return fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, id)
+	}
+
+	r.webhooks[idx].Paused = paused
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) DeleteWebhook(_ context.Context, id uuid.UUID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	idx := r.find(id)
+	if idx < 0 {
+		return fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, id)
+	}
+
+	r.webhooks = slices.Delete(r.webhooks, idx, idx+1)
+	delete(r.deliveries, id)
+	delete(r.pending, id)
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) LogDelivery(_ context.Context, delivery eventsourcing.WebhookDelivery) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	// as the foreign key of the pg repository, the deliveries of deleted webhooks are rejected
+	if r.find(delivery.WebhookId) < 0 {
+		return fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, delivery.WebhookId)
+	}
+
+	r.deliveries[delivery.WebhookId] = append(r.deliveries[delivery.WebhookId], delivery)
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) ListDeliveries(_ context.Context, webhookId uuid.UUID, limit int) ([]eventsourcing.WebhookDelivery, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	logged := r.deliveries[webhookId]
+	deliveries := make([]eventsourcing.WebhookDelivery, 0, len(logged))
+	for i := len(logged) - 1; i >= 0 && (limit <= 0 || len(deliveries) < limit); i-- {
+		deliveries = append(deliveries, logged[i])
+	}
+
+	return deliveries, nil
+}
+
+func (r *inMemoryWebhookRepository) AddPendingDeliveries(_ context.Context, deliveries []eventsourcing.WebhookPendingDelivery) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, delivery := range deliveries {
+		if r.find(delivery.WebhookId) < 0 {
+			return fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, delivery.WebhookId)
+		}
+	}
+
+	for _, delivery := range deliveries {
+		if r.findPending(delivery.WebhookId, delivery.EventId) >= 0 {
+			continue
+		}
+
+		delivery.Payload = slices.Clone(delivery.Payload)
+		r.pending[delivery.WebhookId] = append(r.pending[delivery.WebhookId], delivery)
+	}
+
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) ListPendingDeliveries(_ context.Context, webhookId uuid.UUID, limit int) ([]eventsourcing.WebhookPendingDelivery, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	pending := r.pending[webhookId]
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	return slices.Clone(pending), nil
+}
+
+func (r *inMemoryWebhookRepository) GetPendingDelivery(_ context.Context, webhookId uuid.UUID, eventId uuid.UUID) (eventsourcing.WebhookPendingDelivery, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	idx := r.findPending(webhookId, eventId)
+	if idx < 0 {
+		return eventsourcing.WebhookPendingDelivery{}, fmt.Errorf("%w: event(%s) to webhook(%s)", eventsourcing.ErrWebhookDeliveryNotFound, eventId, webhookId)
+	}
+
+	delivery := r.pending[webhookId][idx]
+	delivery.Payload = slices.Clone(delivery.Payload)
+
+	return delivery, nil
+}
+
+func (r *inMemoryWebhookRepository) UpdatePendingDelivery(_ context.Context, delivery eventsourcing.WebhookPendingDelivery) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	idx := r.findPending(delivery.WebhookId, delivery.EventId)
+	if idx < 0 {
+		return fmt.Errorf("%w: event(%s) to webhook(%s)", eventsourcing.ErrWebhookDeliveryNotFound, delivery.EventId, delivery.WebhookId)
+	}
+
+	pending := &r.pending[delivery.WebhookId][idx]
+	pending.Attempts = delivery.Attempts
+	pending.NextAttemptAt = delivery.NextAttemptAt
+	pending.LastError = delivery.LastError
+
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) DeletePendingDelivery(_ context.Context, webhookId uuid.UUID, eventId uuid.UUID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	idx := r.findPending(webhookId, eventId)
+	if idx >= 0 {
+		r.pending[webhookId] = slices.Delete(r.pending[webhookId], idx, idx+1)
+	}
+
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) findPending(webhookId uuid.UUID, eventId uuid.UUID) int {
+	return slices.IndexFunc(r.pending[webhookId], func(d eventsourcing.WebhookPendingDelivery) bool { return d.EventId == eventId })
+}
+
+func (r *inMemoryWebhookRepository) find(id uuid.UUID) int {
+	return slices.IndexFunc(r.webhooks, func(w *eventsourcing.Webhook) bool { return w.Id == id })
+}
+
+func copyWebhook(w *eventsourcing.Webhook) eventsourcing.Webhook {
+	webhook := *w
+	webhook.EventTypes = slices.Clone(w.EventTypes)
+
+	return webhook
+}
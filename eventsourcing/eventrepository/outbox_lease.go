@@ -0,0 +1,70 @@
+package eventrepository
+
+import (
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+// outboxLeases holds the leases of the in process repositories, they are lost when the process stops
+type outboxLeases struct {
+	duration time.Duration
+	until    map[uuid.UUID]time.Time
+}
+
+func newOutboxLeases(duration time.Duration) *outboxLeases {
+	return &outboxLeases{
+		duration: duration,
+		until:    make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (l *outboxLeases) active(eventId uuid.UUID, now time.Time) bool {
+	until, ok := l.until[eventId]
+	return ok && now.Before(until)
+}
+
+func (l *outboxLeases) release(eventId uuid.UUID) {
+	delete(l.until, eventId)
+}
+
+// outboxCandidate is an unpublished event of the outbox
+type outboxCandidate struct {
+	eventId     uuid.UUID
+	aggregateId uuid.UUID
+	// deadLettered events are not published and hold back the later events of their aggregate
+	deadLettered bool
+}
+
+// claim leases up to batchSize candidates, which must be ordered by global position
+// a candidate is skipped when an earlier event of its aggregate is leased or dead lettered so that aggregates are published in order
+func (l *outboxLeases) claim(candidates []outboxCandidate, batchSize int, now time.Time) []int {
+	claimed := make([]int, 0, batchSize)
+	blocked := make(map[uuid.UUID]bool)
+	for i, c := range candidates {
+		if len(claimed) >= batchSize {
+			break
+		}
+		if blocked[c.aggregateId] {
+			continue
+		}
+		if c.deadLettered || l.active(c.eventId, now) {
+			blocked[c.aggregateId] = true
+			continue
+		}
+
+		claimed = append(claimed, i)
+	}
+
+	for _, i := range claimed {
+		l.until[candidates[i].eventId] = now.Add(l.duration)
+	}
+
+	return claimed
+}
+
+// matchesAggregateType tells if an event of aggregateType is leased by a publisher of the outbox type, which may be all the types
+func matchesAggregateType(aggregateType eventsourcing.AggregateType, outboxType eventsourcing.AggregateType) bool {
+	return outboxType == eventsourcing.AllAggregateTypes || aggregateType == outboxType
+}
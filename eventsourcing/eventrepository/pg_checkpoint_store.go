@@ -0,0 +1,29 @@
+package eventrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (r pgEventRepository) GetCheckpoint(ctx context.Context, name string) (int64, error) {
+	var positions []int64
+	err := r.db.WithContext(ctx).Raw(sqlGetCheckpointQuery(gormPlaceholder), name).Scan(&positions).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to get checkpoint(%s): %w", name, err)
+	}
+	if len(positions) == 0 {
+		return 0, nil
+	}
+
+	return positions[0], nil
+}
+
+func (r pgEventRepository) SaveCheckpoint(ctx context.Context, name string, position int64) error {
+	err := r.db.WithContext(ctx).Exec(sqlSaveCheckpointQuery(gormPlaceholder), name, position, time.Now().UTC()).Error
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint(%s): %w", name, err)
+	}
+
+	return nil
+}
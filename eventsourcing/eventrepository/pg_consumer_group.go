@@ -0,0 +1,161 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+)
+
+const pgConsumerGroupLockPrefix = "events_consumer_group:"
+
+// pgConsumerGroupCoordinator stores the members of consumer groups and the leases of their partitions in pg
+// the heartbeats of a group are serialized by an advisory lock, leases are computed with the clock of the database
+type pgConsumerGroupCoordinator struct {
+	db *sql.DB
+}
+
+// NewPGConsumerGroupCoordinator creates a coordinator on db, which must have been opened with the pgx driver (see pg.OpenDB)
+// the tables are created by the migrations of the pg package
+func NewPGConsumerGroupCoordinator(db *sql.DB) *pgConsumerGroupCoordinator {
+	return &pgConsumerGroupCoordinator{db: db}
+}
+
+func (c *pgConsumerGroupCoordinator) Heartbeat(ctx context.Context, group string, member string, partitions int, ttl time.Duration) ([]int, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", pgConsumerGroupLockPrefix+group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock consumer group: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO events_consumer_group_members (group_name, member_id, expires_at) VALUES ($1, $2, now() + make_interval(secs => $3))
+		ON CONFLICT (group_name, member_id) DO UPDATE SET expires_at = excluded.expires_at`,
+		group, member, ttl.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew membership: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM events_consumer_group_members WHERE group_name = $1 AND expires_at < now()", group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire members: %w", err)
+	}
+
+	members, err := c.members(ctx, tx, group)
+	if err != nil {
+		return nil, err
+	}
+	assigned := make([]int32, 0, partitions)
+	for _, partition := range eventsourcing.AssignPartitions(members, member, partitions) {
+		assigned = append(assigned, int32(partition))
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO events_consumer_group_partitions (group_name, partition_id) SELECT $1, generate_series(0, $2 - 1)
+		ON CONFLICT (group_name, partition_id) DO NOTHING`,
+		group, partitions,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create partitions: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE events_consumer_group_partitions SET member_id = NULL, leased_until = NULL
+		WHERE group_name = $1 AND member_id = $2 AND NOT (partition_id = ANY($3::int[]))`,
+		group, member, assigned,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release partitions: %w", err)
+	}
+
+	// the partitions still leased by their previous member are leased by a later heartbeat, once released or expired
+	rows, err := tx.QueryContext(
+		ctx,
+		`UPDATE events_consumer_group_partitions SET member_id = $2, leased_until = now() + make_interval(secs => $4)
+		WHERE group_name = $1 AND partition_id = ANY($3::int[])
+		AND (member_id IS NULL OR member_id = $2 OR leased_until < now())
+		RETURNING partition_id`,
+		group, member, assigned, ttl.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease partitions: %w", err)
+	}
+	defer rows.Close()
+
+	leased := make([]int, 0, len(assigned))
+	for rows.Next() {
+		var partition int
+		err = rows.Scan(&partition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lease partitions: %w", err)
+		}
+		leased = append(leased, partition)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lease partitions: %w", err)
+	}
+	slices.Sort(leased)
+
+	return leased, tx.Commit()
+}
+
+func (c *pgConsumerGroupCoordinator) members(ctx context.Context, tx *sql.Tx, group string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT member_id FROM events_consumer_group_members WHERE group_name = $1", group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]string, 0)
+	for rows.Next() {
+		var member string
+		err = rows.Scan(&member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list members: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (c *pgConsumerGroupCoordinator) Leave(ctx context.Context, group string, member string) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", pgConsumerGroupLockPrefix+group)
+	if err != nil {
+		return fmt.Errorf("failed to lock consumer group: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE events_consumer_group_partitions SET member_id = NULL, leased_until = NULL WHERE group_name = $1 AND member_id = $2",
+		group, member,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release partitions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM events_consumer_group_members WHERE group_name = $1 AND member_id = $2", group, member)
+	if err != nil {
+		return fmt.Errorf("failed to leave consumer group: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,51 @@
+//go:build integration
+
+package eventrepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGConsumerGroupCoordinator(t *testing.T) {
+	ctx := context.Background()
+
+	sqlDB, err := testDB(t).DB()
+	require.NoError(t, err)
+	coordinator := eventrepository.NewPGConsumerGroupCoordinator(sqlDB)
+	group := "group-" + uuid.NewString()
+
+	t.Run("a single member leases all the partitions", func(t *testing.T) {
+		partitions, err := coordinator.Heartbeat(ctx, group, "a", 4, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2, 3}, partitions)
+	})
+
+	t.Run("partitions move to a new member once released", func(t *testing.T) {
+		partitions, err := coordinator.Heartbeat(ctx, group, "b", 4, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, partitions)
+
+		partitions, err = coordinator.Heartbeat(ctx, group, "a", 4, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 2}, partitions)
+
+		partitions, err = coordinator.Heartbeat(ctx, group, "b", 4, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 3}, partitions)
+	})
+
+	t.Run("partitions of a leaving member are leased by the others", func(t *testing.T) {
+		require.NoError(t, coordinator.Leave(ctx, group, "b"))
+
+		partitions, err := coordinator.Heartbeat(ctx, group, "a", 4, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2, 3}, partitions)
+	})
+}
@@ -0,0 +1,111 @@
+package eventrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (r pgEventRepository) MarkFailed(ctx context.Context, event eventsourcing.EventInternal, reason string, maxAttempts int) (bool, error) {
+	deadLettered := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var outbox []struct {
+			FailedAttempts int
+			AggregateType  string
+		}
+		err := tx.Raw(sqlMarkFailedQuery(gormPlaceholder), event.EventId).Scan(&outbox).Error
+		if err != nil {
+			return fmt.Errorf("failed to count failed attempt of event(%s): %w", event.EventId, err)
+		}
+		// as MarkAs, events which are not in the outbox are ignored
+		if len(outbox) == 0 || outbox[0].FailedAttempts < maxAttempts {
+			return nil
+		}
+
+		deadLettered = true
+		err = tx.Exec(
+			sqlInsertDeadLetterQuery(gormPlaceholder),
+			event.EventId, outbox[0].AggregateType, outbox[0].FailedAttempts, reason, time.Now().UTC(),
+		).Error
+		if err != nil {
+			return fmt.Errorf("failed to dead letter event(%s): %w", event.EventId, err)
+		}
+
+		return nil
+	})
+
+	return deadLettered, err
+}
+
+func (r pgEventRepository) ListDeadLetters(ctx context.Context, aggregateType *eventsourcing.AggregateType) ([]eventsourcing.DeadLetter, error) {
+	query := r.db.WithContext(ctx).
+		Joins("JOIN events ON events.event_id = events_dead_letter.event_id").
+		Preload("Event.Outbox").
+		Order("events.global_position ASC")
+	if aggregateType != nil {
+		query = query.Where("events_dead_letter.aggregate_type = ?", string(*aggregateType))
+	}
+
+	var pgDeadLetters []pgDeadLetter
+	err := query.Find(&pgDeadLetters).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	deadLetters := make([]eventsourcing.DeadLetter, 0, len(pgDeadLetters))
+	for _, d := range pgDeadLetters {
+		deadLetters = append(deadLetters, fromPgDeadLetter(d))
+	}
+
+	return deadLetters, nil
+}
+
+func (r pgEventRepository) GetDeadLetter(ctx context.Context, eventId uuid.UUID) (eventsourcing.DeadLetter, error) {
+	var d pgDeadLetter
+	err := r.db.WithContext(ctx).
+		Preload("Event.Outbox").
+		Where("event_id = ?", eventId).
+		First(&d).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return eventsourcing.DeadLetter{}, fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
+	}
+	if err != nil {
+		return eventsourcing.DeadLetter{}, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	return fromPgDeadLetter(d), nil
+}
+
+func (r pgEventRepository) RetryDeadLetter(ctx context.Context, eventId uuid.UUID) error {
+	return r.removeDeadLetter(ctx, eventId, eventsourcing.Unpublished)
+}
+
+func (r pgEventRepository) DiscardDeadLetter(ctx context.Context, eventId uuid.UUID) error {
+	return r.removeDeadLetter(ctx, eventId, eventsourcing.Published)
+}
+
+// removeDeadLetter deletes the dead letter and sets the published state of the event
+func (r pgEventRepository) removeDeadLetter(ctx context.Context, eventId uuid.UUID, published bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(sqlDeleteDeadLetterQuery(gormPlaceholder), eventId)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete dead letter: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
+		}
+
+		err := tx.Exec(sqlResetOutboxQuery(gormPlaceholder), published, eventId).Error
+		if err != nil {
+			return fmt.Errorf("failed to reset outbox: %w", err)
+		}
+
+		return nil
+	})
+}
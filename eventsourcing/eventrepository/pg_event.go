@@ -18,6 +18,11 @@ type pgEvent struct {
 	AggregateId      uuid.UUID                   `gorm:"type:uuid;column:aggregate_id"`
 	AggregateType    eventsourcing.AggregateType `gorm:"type:varchar(255);column:aggregate_type"`
 	AggregateVersion int                         `gorm:"column:aggregate_version"`
+	GlobalPosition   int64                       `gorm:"column:global_position;->"`
+
+	EventHash         []byte `gorm:"type:bytea;column:event_hash"`
+	PreviousEventHash []byte `gorm:"type:bytea;column:previous_event_hash"`
+	EventSignature    []byte `gorm:"type:bytea;column:event_signature"`
 
 	Outbox pgEventOutbox `gorm:"foreignKey:EventId;references:EventId"`
 }
@@ -27,16 +32,40 @@ func (pgEvent) TableName() string {
 }
 
 type pgEventOutbox struct {
-	EventId          uuid.UUID `gorm:"type:uuid;primaryKey;column:event_id"`
-	Published        bool      `gorm:"column:published"`
-	AggregateType    string    `gorm:"type:varchar(255);column:aggregate_type"`
-	AggregateVersion int       `gorm:"column:aggregate_version"`
+	EventId          uuid.UUID  `gorm:"type:uuid;primaryKey;column:event_id"`
+	Published        bool       `gorm:"column:published"`
+	AggregateType    string     `gorm:"type:varchar(255);column:aggregate_type"`
+	AggregateVersion int        `gorm:"column:aggregate_version"`
+	LeasedUntil      *time.Time `gorm:"column:leased_until"`
 }
 
 func (pgEventOutbox) TableName() string {
 	return "events_outbox"
 }
 
+type pgDeadLetter struct {
+	EventId        uuid.UUID `gorm:"type:uuid;primaryKey;column:event_id"`
+	AggregateType  string    `gorm:"type:varchar(255);column:aggregate_type"`
+	Attempts       int       `gorm:"column:attempts"`
+	Reason         string    `gorm:"column:reason"`
+	DeadLetteredAt time.Time `gorm:"column:dead_lettered_at"`
+
+	Event pgEvent `gorm:"foreignKey:EventId;references:EventId"`
+}
+
+func (pgDeadLetter) TableName() string {
+	return "events_dead_letter"
+}
+
+func fromPgDeadLetter(d pgDeadLetter) eventsourcing.DeadLetter {
+	return eventsourcing.DeadLetter{
+		Event:          fromPgEvent(d.Event),
+		Attempts:       d.Attempts,
+		Reason:         d.Reason,
+		DeadLetteredAt: d.DeadLetteredAt,
+	}
+}
+
 func toPgEvent(e eventsourcing.EventInternal) *pgEvent {
 	return &pgEvent{
 		EventId:          e.EventId,
@@ -47,6 +76,10 @@ func toPgEvent(e eventsourcing.EventInternal) *pgEvent {
 		AggregateId:      e.AggregateId,
 		AggregateType:    e.AggregateType,
 		AggregateVersion: e.AggregateVersion,
+
+		EventHash:         e.EventHash,
+		PreviousEventHash: e.PreviousEventHash,
+		EventSignature:    e.EventSignature,
 	}
 }
 
@@ -70,5 +103,10 @@ func fromPgEvent(pgEvent pgEvent) eventsourcing.EventInternal {
 		AggregateId:      pgEvent.AggregateId,
 		AggregateType:    pgEvent.AggregateType,
 		AggregateVersion: pgEvent.AggregateVersion,
+		GlobalPosition:   pgEvent.GlobalPosition,
+
+		EventHash:         pgEvent.EventHash,
+		PreviousEventHash: pgEvent.PreviousEventHash,
+		EventSignature:    pgEvent.EventSignature,
 	}
 }
@@ -3,20 +3,24 @@ package eventrepository
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/davidterranova/cqrs/eventsourcing"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type pgEventRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	options eventRepositoryOptions
 }
 
-func NewPGEventRepository(db *gorm.DB) *pgEventRepository {
+func NewPGEventRepository(db *gorm.DB, opts ...EventRepositoryOption) *pgEventRepository {
 	return &pgEventRepository{
-		db: db,
+		db:      db,
+		options: newEventRepositoryOptions(opts),
 	}
 }
 
@@ -42,7 +46,7 @@ func (r pgEventRepository) Save(ctx context.Context, publishOutbox bool, events
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		err := tx.Create(pgEvents).Error
 		if err != nil {
-			return fmt.Errorf("failed to create events in event_store table: %w", err)
+			return fmt.Errorf("failed to create events in event_store table: %w", wrapHashChainConflict(err))
 		}
 
 		for _, event := range events {
@@ -66,23 +70,29 @@ func (r pgEventRepository) Get(ctx context.Context, filter eventsourcing.EventQu
 	var pgEvents []pgEvent
 	query := r.db.WithContext(ctx).
 		Model(&pgEvent{}).
-		Scopes(
-			issuedByScope(filter.IssuedBy()),
-			eventTypeScope(filter.EventType()),
-			aggregateTypeScope(filter.AggregateType()),
-			aggregateIdScope(filter.AggregateId()),
-			upToVersionScope(filter.UpToVersion()),
-			publishedScope(filter.Published()),
-		)
+		Scopes(filterScopes(filter)...).
+		Scopes(orderByScope(filter.OrderBy()))
 
 	if filter.Limit() != nil {
 		query = query.Limit(*filter.Limit())
 	}
 	if filter.GroupBy() != nil {
-		query = query.Group(*filter.GroupBy())
+		column, err := sqlColumn(*filter.GroupBy())
+		if err != nil {
+			return nil, err
+		}
+
+		// the first event of each group is kept, as the in memory repository does
+		groups := r.db.WithContext(ctx).
+			Model(&pgEvent{}).
+			Scopes(filterScopes(filter)...).
+			Select("MIN(events.global_position)").
+			Group(column)
+		query = query.Where("events.global_position IN (?)", groups)
 	}
 
 	err := query.
+		Preload("Outbox").
 		Find(&pgEvents).
 		Error
 	if err != nil {
@@ -98,30 +108,21 @@ func (r pgEventRepository) Get(ctx context.Context, filter eventsourcing.EventQu
 }
 
 func (r pgEventRepository) GetUnpublished(ctx context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
-	var pgOutboxEntries []uuid.UUID
-	err := r.db.
-		WithContext(ctx).
-		Model(&pgEventOutbox{}).
-		Where("published = ?", false).
-		Where("aggregate_type = ?", aggregateType).
-		Group("event_id").
-		Order("aggregate_version ASC").
-		Limit(batchSize).
-		Pluck("event_id", &pgOutboxEntries).
-		Error
-	if err != nil {
-		return nil, fmt.Errorf("failed to load unpublished events from outbox: %w", err)
-	}
-
-	if len(pgOutboxEntries) == 0 {
-		return nil, nil
-	}
-
 	var unpublishedEvents []pgEvent
-	err = r.db.
-		WithContext(ctx).
-		Where("event_id IN ?", pgOutboxEntries).
-		Find(&unpublishedEvents).Error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(pgOutboxLockQuery(gormPlaceholder, aggregateType == eventsourcing.AllAggregateTypes), pgOutboxLockArgs(aggregateType)...).Error
+		if err != nil {
+			return fmt.Errorf("failed to lock outbox: %w", err)
+		}
+
+		return tx.
+			Raw(
+				pgLeaseUnpublishedQuery("events.*", gormPlaceholder, aggregateType == eventsourcing.AllAggregateTypes),
+				pgLeaseUnpublishedArgs(r.options.outboxLease, aggregateType, batchSize)...,
+			).
+			Scan(&unpublishedEvents).
+			Error
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to load unpublished events: %w", err)
 	}
@@ -149,10 +150,27 @@ func (r pgEventRepository) MarkAs(ctx context.Context, asPublished bool, events
 	}
 
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return tx.Model(&pgEventOutbox{}).Where("event_id IN ?", eventIds).Update("published", asPublished).Error
+		return tx.
+			Model(&pgEventOutbox{}).
+			Where("event_id IN ?", eventIds).
+			Updates(map[string]any{"published": asPublished, "leased_until": nil}).
+			Error
 	})
 }
 
+// filterScopes returns the scopes matching the event query
+func filterScopes(filter eventsourcing.EventQuery) []func(db *gorm.DB) *gorm.DB {
+	return []func(db *gorm.DB) *gorm.DB{
+		issuedByScope(filter.IssuedBy()),
+		eventTypeScope(filter.EventType()),
+		aggregateTypeScope(filter.AggregateType()),
+		aggregateIdScope(filter.AggregateId()),
+		upToVersionScope(filter.UpToVersion()),
+		publishedScope(filter.Published()),
+		afterPositionScope(filter.AfterPosition()),
+	}
+}
+
 func issuedByScope(user eventsourcing.User) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if user == nil {
@@ -169,8 +187,8 @@ func publishedScope(published *bool) func(db *gorm.DB) *gorm.DB {
 			return db
 		}
 
-		// return db.InnerJoins("Outbox", db.Where(&pgEventOutbox{Published: *published})) //! according to doc, this should work but does not https://gorm.io/docs/query.html
-		return db.InnerJoins("Join events_outbox Outbox ON Outbox.event_id = events.event_id WHERE Outbox.published = ?", published)
+		// the condition belongs to the join so that it can be combined with the other scopes
+		return db.Joins("JOIN events_outbox Outbox ON Outbox.event_id = events.event_id AND Outbox.published = ?", *published)
 	}
 }
 
@@ -213,3 +231,32 @@ func upToVersionScope(version *int) func(db *gorm.DB) *gorm.DB {
 		return db.Where("events.aggregate_version <= ?", *version)
 	}
 }
+
+func afterPositionScope(position *int64) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if position == nil {
+			return db
+		}
+
+		return db.Where("events.global_position > ?", *position)
+	}
+}
+
+// orderByScope orders events by the given column, events are ordered by global position by default
+func orderByScope(orderBy *string, direction *string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		column := eventsourcing.OrderByGlobalPosition
+		if orderBy != nil && *orderBy != "" {
+			column = *orderBy
+		}
+
+		return db.Order(clause.OrderByColumn{
+			Column: clause.Column{Table: "events", Name: column},
+			Desc:   direction != nil && strings.EqualFold(*direction, string(eventsourcing.DESC)),
+		})
+	}
+}
+
+func gormPlaceholder(int) string {
+	return "?"
+}
@@ -0,0 +1,133 @@
+//go:build integration
+
+package eventrepository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -tags integration -run ^$ -bench . ./eventsourcing/eventrepository
+func BenchmarkPGEventRepository(b *testing.B) {
+	ctx := context.Background()
+	db := testDB(b)
+	log.Logger = log.Logger.Level(zerolog.WarnLevel)
+
+	sqlDB, err := db.DB()
+	require.NoError(b, err)
+	pgxRepo, err := eventrepository.NewPGXEventRepository(ctx, sqlDB)
+	require.NoError(b, err)
+	defer pgxRepo.Close()
+
+	repos := []struct {
+		name string
+		repo eventsourcing.EventRepository
+	}{
+		{name: "gorm", repo: eventrepository.NewPGEventRepository(db)},
+		{name: "pgx", repo: pgxRepo},
+	}
+
+	for _, r := range repos {
+		for _, batchSize := range []int{1, 10, 500} {
+			b.Run(fmt.Sprintf("%s/save/batch-%d", r.name, batchSize), func(b *testing.B) {
+				benchmarkSave(b, r.repo, batchSize)
+			})
+		}
+
+		b.Run(r.name+"/get-aggregate", func(b *testing.B) {
+			benchmarkGetAggregate(b, r.repo)
+		})
+
+		b.Run(r.name+"/outbox-polling", func(b *testing.B) {
+			benchmarkOutboxPolling(b, r.repo)
+		})
+	}
+}
+
+func benchmarkSave(b *testing.B, repo eventsourcing.EventRepository, batchSize int) {
+	ctx := context.Background()
+	batches := make([][]eventsourcing.EventInternal, 0, b.N)
+	for i := 0; i < b.N; i++ {
+		batches = append(batches, newBenchmarkEvents(uuid.New(), batchSize))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := repo.Save(ctx, true, batches[i]...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "events/s")
+}
+
+func benchmarkGetAggregate(b *testing.B, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	aggregateId := uuid.New()
+	require.NoError(b, repo.Save(ctx, false, newBenchmarkEvents(aggregateId, 50)...))
+	query := eventsourcing.NewEventQuery(eventsourcing.EventQueryWithAggregateId(aggregateId))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		events, err := repo.Get(ctx, query)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(events) != 50 {
+			b.Fatalf("expected 50 events, got %d", len(events))
+		}
+	}
+}
+
+func benchmarkOutboxPolling(b *testing.B, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
+	aggregateType := eventsourcing.AggregateType("benchmark-" + uuid.NewString())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		events := newBenchmarkEvents(uuid.New(), 10)
+		for j := range events {
+			events[j].AggregateType = aggregateType
+		}
+		require.NoError(b, repo.Save(ctx, true, events...))
+		b.StartTimer()
+
+		unpublished, err := repo.GetUnpublished(ctx, aggregateType, 100)
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = repo.MarkAs(ctx, eventsourcing.Published, unpublished...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func newBenchmarkEvents(aggregateId uuid.UUID, nb int) []eventsourcing.EventInternal {
+	issuedBy := uuid.New().String()
+	events := make([]eventsourcing.EventInternal, 0, nb)
+	for i := 0; i < nb; i++ {
+		events = append(events, eventsourcing.EventInternal{
+			EventId:          uuid.New(),
+			EventIssuedAt:    time.Now().UTC(),
+			EventIssuedBy:    issuedBy,
+			EventType:        eventsourcing.EventType("name-set"),
+			EventData:        []byte(`{"Name": "john", "Email": "john@example.com"}`),
+			AggregateId:      aggregateId,
+			AggregateType:    "benchmark",
+			AggregateVersion: i + 1,
+		})
+	}
+
+	return events
+}
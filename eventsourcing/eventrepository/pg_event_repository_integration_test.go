@@ -9,6 +9,7 @@ import (
 
 	"github.com/davidterranova/cqrs/eventsourcing"
 	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository/conformance"
 	"github.com/davidterranova/cqrs/pg"
 	"github.com/google/uuid"
 	"github.com/kelseyhightower/envconfig"
@@ -22,13 +23,31 @@ import (
 func TestPGEventRepository(t *testing.T) {
 	ctx := context.Background()
 	db := testDB(t)
+	log.Logger = log.Logger.Level(zerolog.InfoLevel)
+
+	t.Run("gorm", func(t *testing.T) {
+		testPGEventRepository(t, db, eventrepository.NewPGEventRepository(db))
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+
+		// a copy threshold of 2 writes the batches of this test with COPY
+		repo, err := eventrepository.NewPGXEventRepository(ctx, sqlDB, eventrepository.PGXEventRepositoryWithCopyThreshold(2))
+		require.NoError(t, err)
+		defer repo.Close()
+
+		testPGEventRepository(t, db, repo)
+	})
+}
+
+func testPGEventRepository(t *testing.T, db *gorm.DB, repo eventsourcing.EventRepository) {
+	ctx := context.Background()
 
 	err := db.Exec("TRUNCATE TABLE events CASCADE;").Error
 	require.NoError(t, err)
 
-	repo := eventrepository.NewPGEventRepository(db)
-	log.Logger = log.Logger.Level(zerolog.InfoLevel)
-
 	unpublishedAggregateId := uuid.New()
 	issuedBy := uuid.New().String()
 	unpublishedEvents := []eventsourcing.EventInternal{
@@ -156,11 +175,38 @@ func TestPGEventRepository(t *testing.T) {
 			assert.Len(t, events, 1)
 		})
 
+		t.Run("find events by global position", func(t *testing.T) {
+			events, err := repo.Get(
+				ctx,
+				eventsourcing.NewEventQuery(
+					eventsourcing.EventQueryWithOrderByPosition(eventsourcing.ASC),
+				),
+			)
+			require.NoError(t, err)
+			require.Len(t, events, 4)
+			for i := 1; i < len(events); i++ {
+				assert.Greater(t, events[i].GlobalPosition, events[i-1].GlobalPosition)
+			}
+
+			after, err := repo.Get(
+				ctx,
+				eventsourcing.NewEventQuery(
+					eventsourcing.EventQueryWithAfterPosition(events[1].GlobalPosition),
+				),
+			)
+			assert.NoError(t, err)
+			assert.Len(t, after, 2)
+		})
+
 		t.Run("get unpublished events", func(t *testing.T) {
 			events, err := repo.GetUnpublished(ctx, "test", 1)
 			assert.NoError(t, err)
 			assert.Len(t, events, 1)
 
+			// release the lease so that the event is loaded again
+			err = repo.MarkAs(ctx, eventsourcing.Unpublished, events...)
+			assert.NoError(t, err)
+
 			events, err = repo.GetUnpublished(ctx, "test", 10)
 			assert.NoError(t, err)
 			assert.Len(t, events, 2)
@@ -178,7 +224,7 @@ func TestPGEventRepository(t *testing.T) {
 	})
 }
 
-func testDB(t *testing.T) *gorm.DB {
+func testDBConfig(t testing.TB) pg.DBConfig {
 	t.Helper()
 
 	var dbCon pg.DBConfig
@@ -189,10 +235,51 @@ func testDB(t *testing.T) *gorm.DB {
 		}
 	}
 
-	db, err := pg.Open(dbCon)
+	return dbCon
+}
+
+func testDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := pg.Open(testDBConfig(t))
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	return db
 }
+
+func TestPGEventRepositoryConformance(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	log.Logger = log.Logger.Level(zerolog.InfoLevel)
+
+	truncate := func(t *testing.T) {
+		t.Helper()
+
+		err := db.Exec("TRUNCATE TABLE events CASCADE;").Error
+		require.NoError(t, err)
+	}
+
+	t.Run("gorm", func(t *testing.T) {
+		conformance.Run(t, func(t *testing.T) eventsourcing.EventRepository {
+			truncate(t)
+			return eventrepository.NewPGEventRepository(db)
+		})
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+
+		conformance.Run(t, func(t *testing.T) eventsourcing.EventRepository {
+			truncate(t)
+
+			repo, err := eventrepository.NewPGXEventRepository(ctx, sqlDB, eventrepository.PGXEventRepositoryWithCopyThreshold(3))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = repo.Close() })
+
+			return repo
+		})
+	})
+}
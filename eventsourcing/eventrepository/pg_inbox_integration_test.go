@@ -0,0 +1,39 @@
+//go:build integration
+
+package eventrepository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGInbox(t *testing.T) {
+	ctx := context.Background()
+
+	sqlDB, err := testDB(t).DB()
+	require.NoError(t, err)
+	inbox := eventrepository.NewPGInbox(sqlDB)
+	consumer := "consumer-" + uuid.NewString()
+	eventId := uuid.New()
+
+	processed, err := inbox.Process(ctx, consumer, eventId, func(context.Context) error { return errors.New("failed") })
+	assert.Error(t, err)
+	assert.False(t, processed)
+
+	processed, err = inbox.Process(ctx, consumer, eventId, func(ctx context.Context) error {
+		assert.NotNil(t, eventrepository.InboxTx(ctx))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	processed, err = inbox.Process(ctx, consumer, eventId, func(context.Context) error { return nil })
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
@@ -0,0 +1,78 @@
+package eventrepository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+)
+
+// pgOutboxLockQuery serializes the publishers of an aggregate type while they lease events
+// without it two publishers could lease consecutive events of an aggregate in concurrent transactions
+// publishers of all the aggregate types hold the outbox lock exclusively, publishers of a single type share it
+func pgOutboxLockQuery(placeholder func(int) string, allTypes bool) string {
+	if allTypes {
+		return "SELECT pg_advisory_xact_lock(hashtext('" + PGOutboxChannel + "'))"
+	}
+
+	return "SELECT pg_advisory_xact_lock_shared(hashtext('" + PGOutboxChannel + "')), pg_advisory_xact_lock(hashtext(" + placeholder(1) + "))"
+}
+
+func pgOutboxLockArgs(aggregateType eventsourcing.AggregateType) []any {
+	if aggregateType == eventsourcing.AllAggregateTypes {
+		return nil
+	}
+
+	return []any{PGOutboxChannel + ":" + string(aggregateType)}
+}
+
+// pgLeaseUnpublishedQuery leases unpublished events and returns them ordered by global position
+// parameters are the lease duration in seconds, the aggregate type unless all types are leased and the batch size, see pgLeaseUnpublishedArgs
+// an event is not leased while an earlier event of its aggregate is leased or dead lettered so that aggregates are published in order
+// rows locked by a concurrent MarkAs are waited for rather than skipped, skipping them would lease the following events of their aggregate
+func pgLeaseUnpublishedQuery(columns string, placeholder func(int) string, allTypes bool) string {
+	aggregateTypeFilter, batchSize := "", placeholder(2)
+	if !allTypes {
+		aggregateTypeFilter, batchSize = " AND outbox.aggregate_type = "+placeholder(2), placeholder(3)
+	}
+
+	return fmt.Sprintf(`WITH leased AS (
+		UPDATE events_outbox SET leased_until = now() + make_interval(secs => %[1]s)
+		WHERE event_id IN (
+			SELECT outbox.event_id FROM events_outbox outbox
+			JOIN events ON events.event_id = outbox.event_id
+			WHERE outbox.published = FALSE%[2]s
+			AND (outbox.leased_until IS NULL OR outbox.leased_until <= now())
+			AND NOT EXISTS (SELECT 1 FROM events_dead_letter dead_letter WHERE dead_letter.event_id = outbox.event_id)
+			AND NOT EXISTS (
+				SELECT 1 FROM events_outbox earlier_outbox
+				JOIN events earlier ON earlier.event_id = earlier_outbox.event_id
+				WHERE earlier.aggregate_id = events.aggregate_id
+				AND earlier.global_position < events.global_position
+				AND earlier_outbox.published = FALSE
+				AND (
+					earlier_outbox.leased_until > now()
+					OR EXISTS (SELECT 1 FROM events_dead_letter dead_letter WHERE dead_letter.event_id = earlier_outbox.event_id)
+				)
+			)
+			ORDER BY events.global_position ASC
+			LIMIT %[3]s
+			FOR UPDATE OF outbox
+		)
+		RETURNING event_id
+	)
+	SELECT %[4]s FROM events
+	JOIN events_outbox outbox ON outbox.event_id = events.event_id
+	WHERE events.event_id IN (SELECT event_id FROM leased)
+	ORDER BY events.global_position ASC`,
+		placeholder(1), aggregateTypeFilter, batchSize, columns,
+	)
+}
+
+func pgLeaseUnpublishedArgs(lease time.Duration, aggregateType eventsourcing.AggregateType, batchSize int) []any {
+	if aggregateType == eventsourcing.AllAggregateTypes {
+		return []any{lease.Seconds(), batchSize}
+	}
+
+	return []any{lease.Seconds(), string(aggregateType), batchSize}
+}
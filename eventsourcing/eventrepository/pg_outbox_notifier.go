@@ -0,0 +1,116 @@
+package eventrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PGOutboxChannel is the channel notified by the events_outbox trigger, the payload is the aggregate type
+	PGOutboxChannel = "events_outbox"
+
+	pgOutboxReconnectDelay = 5 * time.Second
+)
+
+type pgOutboxNotifier struct {
+	connString     string
+	reconnectDelay time.Duration
+}
+
+// NewPGOutboxNotifier creates a notifier listening to the events_outbox channel
+// every Listen holds its own connection, outside of the pool used by the repository
+func NewPGOutboxNotifier(connString string) *pgOutboxNotifier {
+	return &pgOutboxNotifier{
+		connString:     connString,
+		reconnectDelay: pgOutboxReconnectDelay,
+	}
+}
+
+func (n *pgOutboxNotifier) Listen(ctx context.Context, aggregateType eventsourcing.AggregateType) (<-chan struct{}, error) {
+	conn, err := n.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wakeUps := make(chan struct{}, 1)
+	go n.listen(ctx, conn, aggregateType, wakeUps)
+
+	return wakeUps, nil
+}
+
+func (n *pgOutboxNotifier) connect(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, n.connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{PGOutboxChannel}.Sanitize())
+	if err != nil {
+		_ = conn.Close(ctx)
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// listen forwards the notifications of the aggregate type, or of every type, and reconnects when the connection is lost
+func (n *pgOutboxNotifier) listen(ctx context.Context, conn *pgx.Conn, aggregateType eventsourcing.AggregateType, wakeUps chan<- struct{}) {
+	defer close(wakeUps)
+	defer func() {
+		if conn != nil {
+			_ = conn.Close(context.Background())
+		}
+	}()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			if aggregateType == eventsourcing.AllAggregateTypes || notification.Payload == string(aggregateType) {
+				wakeUp(wakeUps)
+			}
+			continue
+		}
+
+		log.Ctx(ctx).Warn().Err(err).Str("aggregate_type", string(aggregateType)).Msg("outbox notifier: connection lost")
+		_ = conn.Close(context.Background())
+		conn = n.reconnect(ctx)
+		if conn == nil {
+			return
+		}
+
+		// notifications sent while disconnected are lost
+		wakeUp(wakeUps)
+	}
+}
+
+func (n *pgOutboxNotifier) reconnect(ctx context.Context) *pgx.Conn {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(n.reconnectDelay):
+		}
+
+		conn, err := n.connect(ctx)
+		if err == nil {
+			return conn
+		}
+		log.Ctx(ctx).Warn().Err(err).Msg("outbox notifier: failed to reconnect")
+	}
+}
+
+// wakeUp coalesces notifications which have not been consumed yet
+func wakeUp(wakeUps chan<- struct{}) {
+	select {
+	case wakeUps <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package eventrepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGOutboxNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := testDB(t)
+	repo := eventrepository.NewPGEventRepository(db)
+	notifier := eventrepository.NewPGOutboxNotifier(string(testDBConfig(t).ConnString))
+
+	wakeUps, err := notifier.Listen(ctx, "notified")
+	require.NoError(t, err)
+
+	event := eventsourcing.EventInternal{
+		EventId:          uuid.New(),
+		EventIssuedAt:    time.Now().UTC(),
+		EventIssuedBy:    uuid.New().String(),
+		EventType:        "created",
+		EventData:        []byte(`{}`),
+		AggregateId:      uuid.New(),
+		AggregateType:    "notified",
+		AggregateVersion: 1,
+	}
+
+	t.Run("outbox inserts are notified", func(t *testing.T) {
+		require.NoError(t, repo.Save(ctx, true, event))
+
+		select {
+		case <-wakeUps:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received")
+		}
+	})
+
+	t.Run("events marked as unpublished are notified", func(t *testing.T) {
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Published, event))
+		require.NoError(t, repo.MarkAs(ctx, eventsourcing.Unpublished, event))
+
+		select {
+		case <-wakeUps:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received")
+		}
+	})
+
+	t.Run("channel is closed with the context", func(t *testing.T) {
+		cancel()
+
+		for range wakeUps {
+		}
+	})
+}
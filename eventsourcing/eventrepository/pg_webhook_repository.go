@@ -0,0 +1,335 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+const (
+	pgWebhookColumns  = "webhook_id, url, secret, event_types, paused, created_at"
+	pgDeliveryColumns = "delivery_id, webhook_id, event_id, event_type, attempt, status_code, error, duration_ms, delivered_at"
+	pgPendingColumns  = "webhook_id, event_id, event_type, payload, created_at, attempts, next_attempt_at, last_error"
+)
+
+// pgWebhookRepository stores the webhooks and their deliveries in pg
+type pgWebhookRepository struct {
+	db *sql.DB
+}
+
+// NewPGWebhookRepository creates a webhook repository on db, which must have been opened with the pgx driver (see pg.OpenDB)
+// the tables are created by the migrations of the pg package
+func NewPGWebhookRepository(db *sql.DB) *pgWebhookRepository {
+	return &pgWebhookRepository{db: db}
+}
+
+func (r *pgWebhookRepository) CreateWebhook(ctx context.Context, webhook eventsourcing.Webhook) error {
+	eventTypes, err := json.Marshal(webhookEventTypes(webhook.EventTypes))
+	if err != nil {
+		return fmt.Errorf("failed to marshal event types: %w", err)
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		"INSERT INTO events_webhooks ("+pgWebhookColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		webhook.Id, webhook.Url, webhook.Secret, string(eventTypes), webhook.Paused, webhook.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook(%s): %w", webhook.Id, err)
+	}
+
+	return nil
+}
+
+func (r *pgWebhookRepository) ListWebhooks(ctx context.Context) ([]eventsourcing.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+pgWebhookColumns+" FROM events_webhooks ORDER BY created_at ASC, webhook_id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]eventsourcing.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *pgWebhookRepository) GetWebhook(ctx context.Context, id uuid.UUID) (eventsourcing.Webhook, error) {
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, "SELECT "+pgWebhookColumns+" FROM events_webhooks WHERE webhook_id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return eventsourcing.Webhook{}, fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, id)
+	}
+	if err != nil {
+		return eventsourcing.Webhook{}, fmt.Errorf("failed to get webhook(%s): %w", id, err)
+	}
+
+	return webhook, nil
+}
+
+func (r *pgWebhookRepository) SetWebhookPaused(ctx context.Context, id uuid.UUID, paused bool) error {
+	return r.exec(ctx, id, "UPDATE events_webhooks SET paused = $2 WHERE webhook_id = $1", paused)
+}
+
+// DeleteWebhook deletes the webhook, its deliveries and pending deliveries are deleted in cascade
+func (r *pgWebhookRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return r.exec(ctx, id, "DELETE FROM events_webhooks WHERE webhook_id = $1")
+}
+
+func (r *pgWebhookRepository) exec(ctx context.Context, id uuid.UUID, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, append([]any{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook(%s): %w", id, err)
+	}
+	nb, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update webhook(%s): %w", id, err)
+	}
+	if nb == 0 {
+		return fmt.Errorf("%w: webhook(%s)", eventsourcing.ErrWebhookNotFound, id)
+	}
+
+	return nil
+}
+
+func (r *pgWebhookRepository) LogDelivery(ctx context.Context, delivery eventsourcing.WebhookDelivery) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO events_webhook_deliveries ("+pgDeliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		delivery.Id,
+		delivery.WebhookId,
+		delivery.EventId,
+		string(delivery.EventType),
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.Duration.Milliseconds(),
+		delivery.DeliveredAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log delivery of event(%s) to webhook(%s): %w", delivery.EventId, delivery.WebhookId, err)
+	}
+
+	return nil
+}
+
+func (r *pgWebhookRepository) ListDeliveries(ctx context.Context, webhookId uuid.UUID, limit int) ([]eventsourcing.WebhookDelivery, error) {
+	query := "SELECT " + pgDeliveryColumns + " FROM events_webhook_deliveries WHERE webhook_id = $1 ORDER BY delivered_at DESC, attempt DESC"
+	args := []any{webhookId}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries of webhook(%s): %w", webhookId, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]eventsourcing.WebhookDelivery, 0)
+	for rows.Next() {
+		var (
+			delivery   eventsourcing.WebhookDelivery
+			eventType  string
+			durationMs int64
+		)
+		err = rows.Scan(
+			&delivery.Id,
+			&delivery.WebhookId,
+			&delivery.EventId,
+			&eventType,
+			&delivery.Attempt,
+			&delivery.StatusCode,
+			&delivery.Error,
+			&durationMs,
+			&delivery.DeliveredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deliveries of webhook(%s): %w", webhookId, err)
+		}
+		delivery.EventType = eventsourcing.EventType(eventType)
+		delivery.Duration = time.Duration(durationMs) * time.Millisecond
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// AddPendingDeliveries queues the deliveries in a transaction, in the order of the slice
+func (r *pgWebhookRepository) AddPendingDeliveries(ctx context.Context, deliveries []eventsourcing.WebhookPendingDelivery) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, delivery := range deliveries {
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO events_webhook_pending ("+pgPendingColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (webhook_id, event_id) DO NOTHING",
+			delivery.WebhookId,
+			delivery.EventId,
+			string(delivery.EventType),
+			delivery.Payload,
+			delivery.CreatedAt.UTC(),
+			delivery.Attempts,
+			pgNullTime(delivery.NextAttemptAt),
+			delivery.LastError,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to queue delivery of event(%s) to webhook(%s): %w", delivery.EventId, delivery.WebhookId, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit pending deliveries: %w", err)
+	}
+
+	return nil
+}
+
+func (r *pgWebhookRepository) ListPendingDeliveries(ctx context.Context, webhookId uuid.UUID, limit int) ([]eventsourcing.WebhookPendingDelivery, error) {
+	query := "SELECT " + pgPendingColumns + " FROM events_webhook_pending WHERE webhook_id = $1 ORDER BY pending_id ASC"
+	args := []any{webhookId}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending deliveries of webhook(%s): %w", webhookId, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]eventsourcing.WebhookPendingDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanPendingDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pending deliveries of webhook(%s): %w", webhookId, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *pgWebhookRepository) GetPendingDelivery(ctx context.Context, webhookId uuid.UUID, eventId uuid.UUID) (eventsourcing.WebhookPendingDelivery, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		"SELECT "+pgPendingColumns+" FROM events_webhook_pending WHERE webhook_id = $1 AND event_id = $2",
+		webhookId, eventId,
+	)
+	delivery, err := scanPendingDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return delivery, fmt.Errorf("%w: event(%s) to webhook(%s)", eventsourcing.ErrWebhookDeliveryNotFound, eventId, webhookId)
+	}
+	if err != nil {
+		return delivery, fmt.Errorf("failed to get pending delivery of event(%s) to webhook(%s): %w", eventId, webhookId, err)
+	}
+
+	return delivery, nil
+}
+
+func (r *pgWebhookRepository) UpdatePendingDelivery(ctx context.Context, delivery eventsourcing.WebhookPendingDelivery) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		"UPDATE events_webhook_pending SET attempts = $3, next_attempt_at = $4, last_error = $5 WHERE webhook_id = $1 AND event_id = $2",
+		delivery.WebhookId, delivery.EventId, delivery.Attempts, pgNullTime(delivery.NextAttemptAt), delivery.LastError,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update pending delivery of event(%s) to webhook(%s): %w", delivery.EventId, delivery.WebhookId, err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update pending delivery of event(%s) to webhook(%s): %w", delivery.EventId, delivery.WebhookId, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w: event(%s) to webhook(%s)", eventsourcing.ErrWebhookDeliveryNotFound, delivery.EventId, delivery.WebhookId)
+	}
+
+	return nil
+}
+
+func (r *pgWebhookRepository) DeletePendingDelivery(ctx context.Context, webhookId uuid.UUID, eventId uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM events_webhook_pending WHERE webhook_id = $1 AND event_id = $2", webhookId, eventId)
+	if err != nil {
+		return fmt.Errorf("failed to delete pending delivery of event(%s) to webhook(%s): %w", eventId, webhookId, err)
+	}
+
+	return nil
+}
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (eventsourcing.Webhook, error) {
+	var (
+		webhook    eventsourcing.Webhook
+		eventTypes []byte
+	)
+	err := row.Scan(&webhook.Id, &webhook.Url, &webhook.Secret, &eventTypes, &webhook.Paused, &webhook.CreatedAt)
+	if err != nil {
+		return webhook, err
+	}
+
+	err = json.Unmarshal(eventTypes, &webhook.EventTypes)
+	if err != nil {
+		return webhook, fmt.Errorf("invalid event types of webhook(%s): %w", webhook.Id, err)
+	}
+	if len(webhook.EventTypes) == 0 {
+		webhook.EventTypes = nil
+	}
+
+	return webhook, nil
+}
+
+func scanPendingDelivery(row interface{ Scan(dest ...any) error }) (eventsourcing.WebhookPendingDelivery, error) {
+	var (
+		delivery      eventsourcing.WebhookPendingDelivery
+		eventType     string
+		nextAttemptAt sql.NullTime
+	)
+	err := row.Scan(
+		&delivery.WebhookId,
+		&delivery.EventId,
+		&eventType,
+		&delivery.Payload,
+		&delivery.CreatedAt,
+		&delivery.Attempts,
+		&nextAttemptAt,
+		&delivery.LastError,
+	)
+	if err != nil {
+		return delivery, err
+	}
+	delivery.EventType = eventsourcing.EventType(eventType)
+	delivery.NextAttemptAt = nextAttemptAt.Time
+
+	return delivery, nil
+}
+
+// pgNullTime stores the zero time as NULL
+func pgNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// webhookEventTypes stores the webhooks receiving every type with an empty array rather than null
+func webhookEventTypes(eventTypes []eventsourcing.EventType) []eventsourcing.EventType {
+	if eventTypes == nil {
+		return []eventsourcing.EventType{}
+	}
+
+	return eventTypes
+}
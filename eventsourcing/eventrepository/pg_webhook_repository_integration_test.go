@@ -0,0 +1,102 @@
+//go:build integration
+
+package eventrepository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGWebhookRepository(t *testing.T) {
+	ctx := context.Background()
+
+	sqlDB, err := testDB(t).DB()
+	require.NoError(t, err)
+	repo := eventrepository.NewPGWebhookRepository(sqlDB)
+
+	webhook := eventsourcing.Webhook{
+		Id:         uuid.New(),
+		Url:        "https://example.com/hooks",
+		Secret:     "secret",
+		EventTypes: []eventsourcing.EventType{"user.created"},
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+	require.NoError(t, repo.CreateWebhook(ctx, webhook))
+
+	stored, err := repo.GetWebhook(ctx, webhook.Id)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Url, stored.Url)
+	assert.Equal(t, webhook.Secret, stored.Secret)
+	assert.Equal(t, webhook.EventTypes, stored.EventTypes)
+	assert.False(t, stored.Paused)
+
+	require.NoError(t, repo.SetWebhookPaused(ctx, webhook.Id, true))
+	stored, err = repo.GetWebhook(ctx, webhook.Id)
+	require.NoError(t, err)
+	assert.True(t, stored.Paused)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		require.NoError(t, repo.LogDelivery(ctx, eventsourcing.WebhookDelivery{
+			Id:          uuid.New(),
+			WebhookId:   webhook.Id,
+			EventId:     uuid.New(),
+			EventType:   "user.created",
+			Attempt:     attempt,
+			StatusCode:  200,
+			Duration:    10 * time.Millisecond,
+			DeliveredAt: time.Now().UTC().Add(time.Duration(attempt) * time.Second),
+		}))
+	}
+	deliveries, err := repo.ListDeliveries(ctx, webhook.Id, 1)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.Equal(t, 10*time.Millisecond, deliveries[0].Duration)
+
+	pending := []eventsourcing.WebhookPendingDelivery{
+		{WebhookId: webhook.Id, EventId: uuid.New(), EventType: "user.created", Payload: []byte(`{"n":1}`), CreatedAt: time.Now().UTC()},
+		{WebhookId: webhook.Id, EventId: uuid.New(), EventType: "user.created", Payload: []byte(`{"n":2}`), CreatedAt: time.Now().UTC()},
+	}
+	require.NoError(t, repo.AddPendingDeliveries(ctx, pending))
+	// the deliveries already pending keep their place
+	require.NoError(t, repo.AddPendingDeliveries(ctx, pending[:1]))
+	queued, err := repo.ListPendingDeliveries(ctx, webhook.Id, 0)
+	require.NoError(t, err)
+	require.Len(t, queued, 2)
+	assert.Equal(t, pending[0].EventId, queued[0].EventId)
+	assert.Equal(t, pending[1].Payload, queued[1].Payload)
+	assert.Zero(t, queued[0].Attempts)
+	assert.True(t, queued[0].NextAttemptAt.IsZero())
+
+	failed := queued[0]
+	failed.Attempts = 2
+	failed.NextAttemptAt = time.Now().Add(time.Minute).UTC().Truncate(time.Microsecond)
+	failed.LastError = "unexpected status 503"
+	require.NoError(t, repo.UpdatePendingDelivery(ctx, failed))
+	updated, err := repo.GetPendingDelivery(ctx, webhook.Id, failed.EventId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Attempts)
+	assert.True(t, failed.NextAttemptAt.Equal(updated.NextAttemptAt))
+	assert.Equal(t, failed.LastError, updated.LastError)
+	_, err = repo.GetPendingDelivery(ctx, webhook.Id, uuid.New())
+	assert.ErrorIs(t, err, eventsourcing.ErrWebhookDeliveryNotFound)
+	assert.ErrorIs(t, repo.UpdatePendingDelivery(ctx, eventsourcing.WebhookPendingDelivery{WebhookId: webhook.Id, EventId: uuid.New()}), eventsourcing.ErrWebhookDeliveryNotFound)
+
+	require.NoError(t, repo.DeletePendingDelivery(ctx, webhook.Id, pending[0].EventId))
+	queued, err = repo.ListPendingDeliveries(ctx, webhook.Id, 0)
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	assert.Equal(t, pending[1].EventId, queued[0].EventId)
+
+	require.NoError(t, repo.DeleteWebhook(ctx, webhook.Id))
+	_, err = repo.GetWebhook(ctx, webhook.Id)
+	assert.ErrorIs(t, err, eventsourcing.ErrWebhookNotFound)
+	assert.ErrorIs(t, repo.DeleteWebhook(ctx, webhook.Id), eventsourcing.ErrWebhookNotFound)
+}
@@ -0,0 +1,381 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// batches of at least pgxDefaultCopyThreshold events are written with COPY instead of a multi-row INSERT
+	pgxDefaultCopyThreshold = 100
+	// postgres accepts at most 65535 bind parameters per statement
+	pgxMaxInsertParameters = 65535
+
+	pgxEventColumns = `events.global_position, events.event_id, events.event_type, events.event_issued_by, events.event_issued_at,
+	events.aggregate_id, events.aggregate_type, events.aggregate_version, events.event_data,
+	events.event_hash, events.previous_event_hash, events.event_signature, COALESCE(outbox.published, FALSE)`
+)
+
+//nolint:gochecknoglobals
+var (
+	pgxEventTable        = pgx.Identifier{"events"}
+	pgxEventTableColumns = []string{
+		"event_id", "event_type", "event_issued_by", "event_issued_at",
+		"aggregate_id", "aggregate_type", "aggregate_version", "event_data",
+		"event_hash", "previous_event_hash", "event_signature",
+	}
+	pgxOutboxTable        = pgx.Identifier{"events_outbox"}
+	pgxOutboxTableColumns = []string{"event_id", "published", "aggregate_type", "aggregate_version"}
+)
+
+// PGXEventRepositoryWithCopyThreshold sets the number of events from which a batch is written with COPY
+func PGXEventRepositoryWithCopyThreshold(threshold int) EventRepositoryOption {
+	return func(o *eventRepositoryOptions) {
+		if threshold > 0 {
+			o.copyThreshold = threshold
+		}
+	}
+}
+
+// pgxEventRepository is a postgres repository on database/sql and the pgx driver
+// it shares the schema of pgEventRepository and can replace it where GORM is too costly
+type pgxEventRepository struct {
+	sqlDeadLetterRepository
+	sqlCheckpointStore
+	db      *sql.DB
+	options eventRepositoryOptions
+
+	lockOutbox        *sql.Stmt
+	lockAllOutbox     *sql.Stmt
+	getUnpublished    *sql.Stmt
+	getAllUnpublished *sql.Stmt
+	markAs            *sql.Stmt
+}
+
+// NewPGXEventRepository prepares the outbox statements on db, which must have been opened with the pgx driver (see pg.OpenDB)
+func NewPGXEventRepository(ctx context.Context, db *sql.DB, opts ...EventRepositoryOption) (*pgxEventRepository, error) {
+	r := &pgxEventRepository{
+		sqlDeadLetterRepository: sqlDeadLetterRepository{
+			db:          db,
+			placeholder: pgxPlaceholder,
+			columns:     pgxEventColumns,
+			scanEvent:   scanPGXEvent,
+			encodeTime:  func(t time.Time) any { return t },
+			decodeTime:  decodePGXTime,
+		},
+		sqlCheckpointStore: sqlCheckpointStore{
+			db:          db,
+			placeholder: pgxPlaceholder,
+			encodeTime:  func(t time.Time) any { return t },
+		},
+		db:      db,
+		options: newEventRepositoryOptions(opts),
+	}
+
+	statements := []struct {
+		name  string
+		stmt  **sql.Stmt
+		query string
+	}{
+		{"outbox lock", &r.lockOutbox, pgOutboxLockQuery(pgxPlaceholder, false)},
+		{"outbox lock", &r.lockAllOutbox, pgOutboxLockQuery(pgxPlaceholder, true)},
+		{"unpublished events", &r.getUnpublished, pgLeaseUnpublishedQuery(pgxEventColumns, pgxPlaceholder, false)},
+		{"unpublished events", &r.getAllUnpublished, pgLeaseUnpublishedQuery(pgxEventColumns, pgxPlaceholder, true)},
+		{"mark as", &r.markAs, "UPDATE events_outbox SET published = $1, leased_until = NULL WHERE event_id = ANY($2::uuid[])"},
+	}
+	for _, s := range statements {
+		stmt, err := db.PrepareContext(ctx, s.query)
+		if err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to prepare %s statement: %w", s.name, err)
+		}
+		*s.stmt = stmt
+	}
+
+	return r, nil
+}
+
+// Close releases the prepared statements, the database is left open
+func (r *pgxEventRepository) Close() error {
+	var err error
+	for _, stmt := range []*sql.Stmt{r.lockOutbox, r.lockAllOutbox, r.getUnpublished, r.getAllUnpublished, r.markAs} {
+		if stmt == nil {
+			continue
+		}
+		if closeErr := stmt.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func (r *pgxEventRepository) Save(ctx context.Context, publishOutbox bool, events ...eventsourcing.EventInternal) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T, the database must be opened with the pgx driver", driverConn)
+		}
+
+		return pgx.BeginFunc(ctx, stdConn.Conn(), func(tx pgx.Tx) error {
+			err := r.saveEvents(ctx, tx, events)
+			if err != nil {
+				return fmt.Errorf("failed to create events in event_store table: %w", wrapHashChainConflict(err))
+			}
+
+			for _, event := range events {
+				log.Debug().Str("type", event.EventType.String()).Interface("event", event).Msg("stored event")
+			}
+
+			if !publishOutbox {
+				return nil
+			}
+
+			err = r.saveOutbox(ctx, tx, events)
+			if err != nil {
+				return fmt.Errorf("failed to create events in event_outbox table: %w", err)
+			}
+
+			return nil
+		})
+	})
+}
+
+func (r *pgxEventRepository) Get(ctx context.Context, filter eventsourcing.EventQuery) ([]eventsourcing.EventInternal, error) {
+	query, args, err := buildSQLEventQuery(filter, pgxPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+pgxEventColumns+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events from event_store table: %w", err)
+	}
+
+	events, err := scanPGXEvents(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events from event_store table: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *pgxEventRepository) GetUnpublished(ctx context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
+	unpublishedEvents, err := r.leaseUnpublished(ctx, aggregateType, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unpublished events: %w", err)
+	}
+
+	for _, event := range unpublishedEvents {
+		log.Debug().
+			Str("event_type", event.EventType.String()).
+			Str("event_id", event.EventId.String()).
+			Str("aggregate_type", string(event.AggregateType)).
+			Str("aggregate_id", event.AggregateId.String()).
+			Msg("loaded unpublished event")
+	}
+
+	return unpublishedEvents, nil
+}
+
+func (r *pgxEventRepository) MarkAs(ctx context.Context, asPublished bool, events ...eventsourcing.EventInternal) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	eventIds := make([]pgtype.UUID, 0, len(events))
+	for _, event := range events {
+		eventIds = append(eventIds, pgtype.UUID{Bytes: event.EventId, Valid: true})
+	}
+
+	_, err := r.markAs.ExecContext(ctx, asPublished, eventIds)
+	if err != nil {
+		return fmt.Errorf("failed to mark events in event_outbox table: %w", err)
+	}
+
+	return nil
+}
+
+func (r *pgxEventRepository) leaseUnpublished(ctx context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	lockOutbox, getUnpublished := r.lockOutbox, r.getUnpublished
+	if aggregateType == eventsourcing.AllAggregateTypes {
+		lockOutbox, getUnpublished = r.lockAllOutbox, r.getAllUnpublished
+	}
+
+	_, err = tx.StmtContext(ctx, lockOutbox).ExecContext(ctx, pgOutboxLockArgs(aggregateType)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+
+	rows, err := tx.StmtContext(ctx, getUnpublished).QueryContext(ctx, pgLeaseUnpublishedArgs(r.options.outboxLease, aggregateType, batchSize)...)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := scanPGXEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, tx.Commit()
+}
+
+func (r *pgxEventRepository) saveEvents(ctx context.Context, tx pgx.Tx, events []eventsourcing.EventInternal) error {
+	rows := make([][]any, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []any{
+			pgtype.UUID{Bytes: event.EventId, Valid: true},
+			event.EventType.String(),
+			event.EventIssuedBy,
+			event.EventIssuedAt,
+			pgtype.UUID{Bytes: event.AggregateId, Valid: true},
+			string(event.AggregateType),
+			event.AggregateVersion,
+			pgxJSON(event.EventData),
+			event.EventHash,
+			event.PreviousEventHash,
+			event.EventSignature,
+		})
+	}
+
+	return r.insert(ctx, tx, pgxEventTable, pgxEventTableColumns, rows)
+}
+
+func (r *pgxEventRepository) saveOutbox(ctx context.Context, tx pgx.Tx, events []eventsourcing.EventInternal) error {
+	rows := make([][]any, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []any{
+			pgtype.UUID{Bytes: event.EventId, Valid: true},
+			event.EventPublished,
+			string(event.AggregateType),
+			event.AggregateVersion,
+		})
+	}
+
+	return r.insert(ctx, tx, pgxOutboxTable, pgxOutboxTableColumns, rows)
+}
+
+// insert writes rows with COPY for large batches and with multi-row INSERT statements otherwise
+func (r *pgxEventRepository) insert(ctx context.Context, tx pgx.Tx, table pgx.Identifier, columns []string, rows [][]any) error {
+	if len(rows) >= r.options.copyThreshold {
+		_, err := tx.CopyFrom(ctx, table, columns, pgx.CopyFromRows(rows))
+		return err
+	}
+
+	maxRows := pgxMaxInsertParameters / len(columns)
+	for len(rows) > 0 {
+		chunk := rows
+		if len(chunk) > maxRows {
+			chunk = chunk[:maxRows]
+		}
+		rows = rows[len(chunk):]
+
+		query := "INSERT INTO " + table.Sanitize() + " (" + pgx.Identifier(columns).Sanitize() + ") VALUES "
+		args := make([]any, 0, len(chunk)*len(columns))
+		for i, row := range chunk {
+			if i > 0 {
+				query += ", "
+			}
+			query += "(" + sqlPlaceholders(pgxPlaceholder, len(args)+1, len(row)) + ")"
+			args = append(args, row...)
+		}
+
+		_, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanPGXEvents(rows *sql.Rows) ([]eventsourcing.EventInternal, error) {
+	defer rows.Close()
+
+	var events []eventsourcing.EventInternal
+	for rows.Next() {
+		event, err := scanPGXEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// scanPGXEvent scans the event columns followed by the extra destinations
+func scanPGXEvent(rows *sql.Rows, extra ...any) (eventsourcing.EventInternal, error) {
+	var event eventsourcing.EventInternal
+	var eventData []byte
+	dest := []any{
+		&event.GlobalPosition,
+		&event.EventId,
+		&event.EventType,
+		&event.EventIssuedBy,
+		&event.EventIssuedAt,
+		&event.AggregateId,
+		&event.AggregateType,
+		&event.AggregateVersion,
+		&eventData,
+		&event.EventHash,
+		&event.PreviousEventHash,
+		&event.EventSignature,
+		&event.EventPublished,
+	}
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return event, fmt.Errorf("failed to scan event: %w", err)
+	}
+	event.EventData = eventData
+
+	return event, nil
+}
+
+func decodePGXTime(v any) (time.Time, error) {
+	t, ok := v.(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unexpected time type %T", v)
+	}
+
+	return t, nil
+}
+
+// pgxJSON returns nil for an empty payload so that it is stored as NULL rather than an invalid jsonb
+func pgxJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return string(data)
+}
+
+func pgxPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
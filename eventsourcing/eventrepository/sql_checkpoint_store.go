@@ -0,0 +1,48 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// sqlCheckpointStore implements eventsourcing.CheckpointStore for the repositories using database/sql
+type sqlCheckpointStore struct {
+	db          *sql.DB
+	placeholder func(n int) string
+	encodeTime  func(t time.Time) any
+}
+
+func sqlGetCheckpointQuery(placeholder func(n int) string) string {
+	return "SELECT position FROM events_checkpoints WHERE name = " + placeholder(1)
+}
+
+func sqlSaveCheckpointQuery(placeholder func(n int) string) string {
+	return "INSERT INTO events_checkpoints (name, position, updated_at) VALUES (" +
+		sqlPlaceholders(placeholder, 1, 3) +
+		") ON CONFLICT (name) DO UPDATE SET position = excluded.position, updated_at = excluded.updated_at"
+}
+
+func (s sqlCheckpointStore) GetCheckpoint(ctx context.Context, name string) (int64, error) {
+	var position int64
+	err := s.db.QueryRowContext(ctx, sqlGetCheckpointQuery(s.placeholder), name).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get checkpoint(%s): %w", name, err)
+	}
+
+	return position, nil
+}
+
+func (s sqlCheckpointStore) SaveCheckpoint(ctx context.Context, name string, position int64) error {
+	_, err := s.db.ExecContext(ctx, sqlSaveCheckpointQuery(s.placeholder), name, position, s.encodeTime(time.Now().UTC()))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint(%s): %w", name, err)
+	}
+
+	return nil
+}
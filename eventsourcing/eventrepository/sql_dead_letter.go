@@ -0,0 +1,181 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+)
+
+// sqlDeadLetterRepository implements eventsourcing.DeadLetterRepository for the repositories using database/sql
+type sqlDeadLetterRepository struct {
+	db          *sql.DB
+	placeholder func(n int) string
+	// columns are the event columns, scanEvent scans them followed by the extra destinations
+	columns   string
+	scanEvent func(rows *sql.Rows, extra ...any) (eventsourcing.EventInternal, error)
+	// timestamps are not stored with the same type by every database
+	encodeTime func(t time.Time) any
+	decodeTime func(v any) (time.Time, error)
+}
+
+func sqlMarkFailedQuery(placeholder func(n int) string) string {
+	return "UPDATE events_outbox SET failed_attempts = failed_attempts + 1, leased_until = NULL WHERE event_id = " + placeholder(1) +
+		" RETURNING failed_attempts, aggregate_type"
+}
+
+func sqlInsertDeadLetterQuery(placeholder func(n int) string) string {
+	return "INSERT INTO events_dead_letter (event_id, aggregate_type, attempts, reason, dead_lettered_at) VALUES (" +
+		sqlPlaceholders(placeholder, 1, 5) +
+		") ON CONFLICT (event_id) DO UPDATE SET attempts = excluded.attempts, reason = excluded.reason, dead_lettered_at = excluded.dead_lettered_at"
+}
+
+func sqlDeleteDeadLetterQuery(placeholder func(n int) string) string {
+	return "DELETE FROM events_dead_letter WHERE event_id = " + placeholder(1)
+}
+
+// sqlResetOutboxQuery sets the published state of an event back from the dead letters
+// in pg, updating published notifies the publishers of the aggregate type
+func sqlResetOutboxQuery(placeholder func(n int) string) string {
+	return "UPDATE events_outbox SET published = " + placeholder(1) + ", failed_attempts = 0, leased_until = NULL WHERE event_id = " + placeholder(2)
+}
+
+func (r sqlDeadLetterRepository) MarkFailed(ctx context.Context, event eventsourcing.EventInternal, reason string, maxAttempts int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		attempts      int
+		aggregateType string
+	)
+	err = tx.QueryRowContext(ctx, sqlMarkFailedQuery(r.placeholder), event.EventId).Scan(&attempts, &aggregateType)
+	if errors.Is(err, sql.ErrNoRows) {
+		// as MarkAs, events which are not in the outbox are ignored
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to count failed attempt of event(%s): %w", event.EventId, err)
+	}
+
+	deadLettered := attempts >= maxAttempts
+	if deadLettered {
+		_, err = tx.ExecContext(
+			ctx,
+			sqlInsertDeadLetterQuery(r.placeholder),
+			event.EventId, aggregateType, attempts, reason, r.encodeTime(time.Now().UTC()),
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to dead letter event(%s): %w", event.EventId, err)
+		}
+	}
+
+	return deadLettered, tx.Commit()
+}
+
+func (r sqlDeadLetterRepository) ListDeadLetters(ctx context.Context, aggregateType *eventsourcing.AggregateType) ([]eventsourcing.DeadLetter, error) {
+	var (
+		where string
+		args  []any
+	)
+	if aggregateType != nil {
+		where = " WHERE dead_letter.aggregate_type = " + r.placeholder(1)
+		args = append(args, string(*aggregateType))
+	}
+
+	deadLetters, err := r.query(ctx, where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	return deadLetters, nil
+}
+
+func (r sqlDeadLetterRepository) GetDeadLetter(ctx context.Context, eventId uuid.UUID) (eventsourcing.DeadLetter, error) {
+	deadLetters, err := r.query(ctx, " WHERE dead_letter.event_id = "+r.placeholder(1), eventId)
+	if err != nil {
+		return eventsourcing.DeadLetter{}, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if len(deadLetters) == 0 {
+		return eventsourcing.DeadLetter{}, fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
+	}
+
+	return deadLetters[0], nil
+}
+
+func (r sqlDeadLetterRepository) RetryDeadLetter(ctx context.Context, eventId uuid.UUID) error {
+	return r.remove(ctx, eventId, eventsourcing.Unpublished)
+}
+
+func (r sqlDeadLetterRepository) DiscardDeadLetter(ctx context.Context, eventId uuid.UUID) error {
+	return r.remove(ctx, eventId, eventsourcing.Published)
+}
+
+// remove deletes the dead letter and sets the published state of the event
+func (r sqlDeadLetterRepository) remove(ctx context.Context, eventId uuid.UUID, published bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, sqlDeleteDeadLetterQuery(r.placeholder), eventId)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	nb, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if nb == 0 {
+		return fmt.Errorf("%w: event(%s)", eventsourcing.ErrDeadLetterNotFound, eventId)
+	}
+
+	_, err = tx.ExecContext(ctx, sqlResetOutboxQuery(r.placeholder), published, eventId)
+	if err != nil {
+		return fmt.Errorf("failed to reset outbox: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r sqlDeadLetterRepository) query(ctx context.Context, where string, args ...any) ([]eventsourcing.DeadLetter, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT "+r.columns+`, dead_letter.attempts, dead_letter.reason, dead_letter.dead_lettered_at FROM events
+		JOIN events_outbox outbox ON outbox.event_id = events.event_id
+		JOIN events_dead_letter dead_letter ON dead_letter.event_id = events.event_id`+where+`
+		ORDER BY events.global_position ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadLetters := make([]eventsourcing.DeadLetter, 0)
+	for rows.Next() {
+		var (
+			deadLetter     eventsourcing.DeadLetter
+			deadLetteredAt any
+		)
+		deadLetter.Event, err = r.scanEvent(rows, &deadLetter.Attempts, &deadLetter.Reason, &deadLetteredAt)
+		if err != nil {
+			return nil, err
+		}
+		deadLetter.DeadLetteredAt, err = r.decodeTime(deadLetteredAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dead lettered at(%v): %w", deadLetteredAt, err)
+		}
+
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, rows.Err()
+}
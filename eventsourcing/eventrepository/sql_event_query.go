@@ -0,0 +1,127 @@
+package eventrepository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+)
+
+//nolint:gochecknoglobals
+var sqlColumnName = regexp.MustCompile(`^[a-z_]+$`)
+
+// sqlEventQuery builds the FROM, WHERE, ORDER BY and LIMIT clauses of an event query
+// for the repositories using database/sql, placeholder returns the bind parameter of the nth argument
+type sqlEventQuery struct {
+	placeholder func(n int) string
+	args        []any
+}
+
+func buildSQLEventQuery(filter eventsourcing.EventQuery, placeholder func(n int) string) (string, []any, error) {
+	q := &sqlEventQuery{placeholder: placeholder}
+
+	query, where := q.filter(filter)
+	if filter.GroupBy() != nil {
+		column, err := sqlColumn(*filter.GroupBy())
+		if err != nil {
+			return "", nil, err
+		}
+
+		// the first event of each group is kept, as the in memory repository does
+		groupQuery, groupWhere := q.filter(filter)
+		if len(groupWhere) > 0 {
+			groupQuery += " WHERE " + strings.Join(groupWhere, " AND ")
+		}
+		where = append(where, "events.global_position IN (SELECT MIN(events.global_position)"+groupQuery+" GROUP BY "+column+")")
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	orderBy, err := sqlOrderBy(filter.OrderBy())
+	if err != nil {
+		return "", nil, err
+	}
+	query += orderBy
+
+	if filter.Limit() != nil {
+		query += " LIMIT " + q.bind(*filter.Limit())
+	}
+
+	return query, q.args, nil
+}
+
+// filter returns the FROM clause and the WHERE conditions matching the event query
+func (q *sqlEventQuery) filter(filter eventsourcing.EventQuery) (string, []string) {
+	join := "LEFT JOIN events_outbox outbox ON outbox.event_id = events.event_id"
+	if filter.Published() != nil {
+		join = "JOIN events_outbox outbox ON outbox.event_id = events.event_id AND outbox.published = " + q.bind(*filter.Published())
+	}
+
+	var where []string
+	if filter.IssuedBy() != nil {
+		where = append(where, "events.event_issued_by = "+q.bind(filter.IssuedBy().String()))
+	}
+	if filter.EventType() != nil {
+		where = append(where, "events.event_type = "+q.bind(filter.EventType().String()))
+	}
+	if filter.AggregateType() != nil {
+		where = append(where, "events.aggregate_type = "+q.bind(string(*filter.AggregateType())))
+	}
+	if filter.AggregateId() != nil {
+		where = append(where, "events.aggregate_id = "+q.bind(filter.AggregateId().String()))
+	}
+	if filter.UpToVersion() != nil {
+		where = append(where, "events.aggregate_version <= "+q.bind(*filter.UpToVersion()))
+	}
+	if filter.AfterPosition() != nil {
+		where = append(where, "events.global_position > "+q.bind(*filter.AfterPosition()))
+	}
+
+	return " FROM events " + join, where
+}
+
+func (q *sqlEventQuery) bind(arg any) string {
+	q.args = append(q.args, arg)
+	return q.placeholder(len(q.args))
+}
+
+// sqlColumn validates a column name coming from an event query before it is interpolated in a statement
+func sqlColumn(column string) (string, error) {
+	if !sqlColumnName.MatchString(column) {
+		return "", fmt.Errorf("invalid column name: %q", column)
+	}
+
+	return "events." + column, nil
+}
+
+// sqlOrderBy orders events by the given column, events are ordered by global position by default
+func sqlOrderBy(orderBy *string, direction *string) (string, error) {
+	column := eventsourcing.OrderByGlobalPosition
+	if orderBy != nil && *orderBy != "" {
+		column = *orderBy
+	}
+
+	column, err := sqlColumn(column)
+	if err != nil {
+		return "", err
+	}
+
+	if direction != nil && strings.EqualFold(*direction, string(eventsourcing.DESC)) {
+		return " ORDER BY " + column + " DESC", nil
+	}
+
+	return " ORDER BY " + column + " ASC", nil
+}
+
+// sqlPlaceholders returns the bind parameters of nb arguments starting from the nth one
+func sqlPlaceholders(placeholder func(n int) string, from int, nb int) string {
+	placeholders := make([]string, 0, nb)
+	for i := 0; i < nb; i++ {
+		placeholders = append(placeholders, placeholder(from+i))
+	}
+
+	return strings.Join(placeholders, ", ")
+}
@@ -0,0 +1,104 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sqlInbox implements eventsourcing.Inbox, the events are recorded in events_inbox in the transaction handling them
+type sqlInbox struct {
+	db          *sql.DB
+	placeholder func(n int) string
+	encodeTime  func(t time.Time) any
+}
+
+type inboxTxKey struct{}
+
+// NewPGInbox creates an inbox on db, which must have been opened with the pgx driver (see pg.OpenDB)
+// the table is created by the migrations of the pg package
+func NewPGInbox(db *sql.DB) *sqlInbox {
+	return &sqlInbox{
+		db:          db,
+		placeholder: pgxPlaceholder,
+		encodeTime:  func(t time.Time) any { return t },
+	}
+}
+
+// NewSQLiteInbox creates an inbox on a sqlite database, the table is created by the migrations of the sqlite package
+func NewSQLiteInbox(db *sql.DB) *sqlInbox {
+	return &sqlInbox{
+		db:          db,
+		placeholder: sqlitePlaceholder,
+		encodeTime:  func(t time.Time) any { return t.UnixMilli() },
+	}
+}
+
+// InboxTx returns the transaction recording the event handled with ctx, nil outside of Process
+// consumers storing their state in the same database use it to update their state and record the event atomically
+func InboxTx(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(inboxTxKey{}).(*sql.Tx)
+	return tx
+}
+
+func sqlInsertInboxQuery(placeholder func(n int) string) string {
+	return "INSERT INTO events_inbox (consumer, event_id, processed_at) VALUES (" +
+		sqlPlaceholders(placeholder, 1, 3) +
+		") ON CONFLICT (consumer, event_id) DO NOTHING"
+}
+
+// Process records the event and calls handle in a transaction, which is rolled back when handle fails
+// a concurrent delivery of the same event waits for the transaction and is then skipped
+func (i *sqlInbox) Process(ctx context.Context, consumer string, eventId uuid.UUID, handle func(ctx context.Context) error) (bool, error) {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	processed, err := i.MarkProcessed(ctx, tx, consumer, eventId)
+	if err != nil || !processed {
+		return false, err
+	}
+
+	err = handle(context.WithValue(ctx, inboxTxKey{}, tx))
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to commit processed event(%s): %w", eventId, err)
+	}
+
+	return true, nil
+}
+
+// MarkProcessed records the event in tx, for the consumers handling events in their own transaction
+// it returns false when the consumer already processed the event, the event is recorded once tx is committed
+func (i *sqlInbox) MarkProcessed(ctx context.Context, tx *sql.Tx, consumer string, eventId uuid.UUID) (bool, error) {
+	result, err := tx.ExecContext(ctx, sqlInsertInboxQuery(i.placeholder), consumer, eventId.String(), i.encodeTime(time.Now().UTC()))
+	if err != nil {
+		return false, fmt.Errorf("failed to record event(%s) of consumer(%s): %w", eventId, consumer, err)
+	}
+
+	nb, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record event(%s) of consumer(%s): %w", eventId, consumer, err)
+	}
+
+	return nb == 1, nil
+}
+
+// Purge deletes the events processed before, they are processed again if delivered once more
+func (i *sqlInbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := i.db.ExecContext(ctx, "DELETE FROM events_inbox WHERE processed_at < "+i.placeholder(1), i.encodeTime(before.UTC()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge inbox: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,74 @@
+//go:build unit
+
+package eventrepository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteInbox(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteTestRepository(t)
+	inbox := NewSQLiteInbox(repo.db)
+
+	_, err := repo.db.ExecContext(ctx, "CREATE TABLE projection (event_id TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+	project := func(eventId uuid.UUID, failure error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			_, err := InboxTx(ctx).ExecContext(ctx, "INSERT INTO projection (event_id) VALUES (?)", eventId.String())
+			if err != nil {
+				return err
+			}
+
+			return failure
+		}
+	}
+	projected := func(t *testing.T) int {
+		t.Helper()
+
+		var nb int
+		require.NoError(t, repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM projection").Scan(&nb))
+		return nb
+	}
+
+	eventId := uuid.New()
+
+	t.Run("failed events are not recorded", func(t *testing.T) {
+		processed, err := inbox.Process(ctx, "projection", eventId, project(eventId, errors.New("failed to project")))
+		assert.Error(t, err)
+		assert.False(t, processed)
+		assert.Equal(t, 0, projected(t))
+	})
+
+	t.Run("events are processed once per consumer", func(t *testing.T) {
+		processed, err := inbox.Process(ctx, "projection", eventId, project(eventId, nil))
+		require.NoError(t, err)
+		assert.True(t, processed)
+
+		processed, err = inbox.Process(ctx, "projection", eventId, project(eventId, nil))
+		require.NoError(t, err)
+		assert.False(t, processed)
+		assert.Equal(t, 1, projected(t))
+
+		processed, err = inbox.Process(ctx, "other", eventId, func(context.Context) error { return nil })
+		require.NoError(t, err)
+		assert.True(t, processed)
+	})
+
+	t.Run("purged events are processed again", func(t *testing.T) {
+		nb, err := inbox.Purge(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), nb)
+
+		processed, err := inbox.Process(ctx, "other", eventId, func(context.Context) error { return nil })
+		require.NoError(t, err)
+		assert.True(t, processed)
+	})
+}
@@ -0,0 +1,334 @@
+package eventrepository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const sqliteEventColumns = `events.global_position, events.event_id, events.event_type, events.event_issued_by, events.event_issued_at,
+	events.aggregate_id, events.aggregate_type, events.aggregate_version, events.event_data,
+	events.event_hash, events.previous_event_hash, events.event_signature, COALESCE(outbox.published, 0)`
+
+// sqliteLeaseUnpublishedQuery leases unpublished events, as the pg query an event is not leased while an earlier event of its aggregate is leased or dead lettered
+// parameters are numbered: ?1 the end of the lease, ?2 the aggregate type, ?3 the current time and ?4 the batch size, times are unix milliseconds
+// an empty aggregate type leases the events of all the types
+const sqliteLeaseUnpublishedQuery = `UPDATE events_outbox SET leased_until = ?1
+	WHERE event_id IN (
+		SELECT outbox.event_id FROM events_outbox outbox
+		JOIN events ON events.event_id = outbox.event_id
+		WHERE outbox.published = 0 AND (?2 = '' OR outbox.aggregate_type = ?2)
+		AND (outbox.leased_until IS NULL OR outbox.leased_until <= ?3)
+		AND NOT EXISTS (SELECT 1 FROM events_dead_letter dead_letter WHERE dead_letter.event_id = outbox.event_id)
+		AND NOT EXISTS (
+			SELECT 1 FROM events_outbox earlier_outbox
+			JOIN events earlier ON earlier.event_id = earlier_outbox.event_id
+			WHERE earlier.aggregate_id = events.aggregate_id
+			AND earlier.global_position < events.global_position
+			AND earlier_outbox.published = 0
+			AND (
+				earlier_outbox.leased_until > ?3
+				OR EXISTS (SELECT 1 FROM events_dead_letter dead_letter WHERE dead_letter.event_id = earlier_outbox.event_id)
+			)
+		)
+		ORDER BY events.global_position ASC
+		LIMIT ?4
+	)
+	RETURNING event_id`
+
+type sqliteEventRepository struct {
+	sqlDeadLetterRepository
+	sqlCheckpointStore
+	db      *sql.DB
+	options eventRepositoryOptions
+}
+
+// NewSQLiteEventRepository creates a repository storing events in a sqlite database
+// the schema is created by the migrations of the sqlite package
+func NewSQLiteEventRepository(db *sql.DB, opts ...EventRepositoryOption) *sqliteEventRepository {
+	return &sqliteEventRepository{
+		sqlDeadLetterRepository: sqlDeadLetterRepository{
+			db:          db,
+			placeholder: sqlitePlaceholder,
+			columns:     sqliteEventColumns,
+			scanEvent:   scanSQLiteEvent,
+			encodeTime:  encodeSQLiteTime,
+			decodeTime:  decodeSQLiteTime,
+		},
+		sqlCheckpointStore: sqlCheckpointStore{
+			db:          db,
+			placeholder: sqlitePlaceholder,
+			encodeTime:  encodeSQLiteTime,
+		},
+		db:      db,
+		options: newEventRepositoryOptions(opts),
+	}
+}
+
+func (r sqliteEventRepository) Save(ctx context.Context, publishOutbox bool, events ...eventsourcing.EventInternal) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, event := range events {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO events (event_id, event_type, event_issued_by, event_issued_at, aggregate_id, aggregate_type, aggregate_version, event_data, event_hash, previous_event_hash, event_signature)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.EventId.String(),
+			event.EventType.String(),
+			event.EventIssuedBy,
+			encodeSQLiteTime(event.EventIssuedAt),
+			event.AggregateId.String(),
+			string(event.AggregateType),
+			event.AggregateVersion,
+			[]byte(event.EventData),
+			event.EventHash,
+			event.PreviousEventHash,
+			event.EventSignature,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create events in event_store table: %w", wrapHashChainConflict(err))
+		}
+	}
+
+	if publishOutbox {
+		for _, event := range events {
+			_, err = tx.ExecContext(
+				ctx,
+				`INSERT INTO events_outbox (event_id, published, aggregate_type, aggregate_version) VALUES (?, ?, ?, ?)`,
+				event.EventId.String(),
+				event.EventPublished,
+				string(event.AggregateType),
+				event.AggregateVersion,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create events in event_outbox table: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r sqliteEventRepository) Get(ctx context.Context, filter eventsourcing.EventQuery) ([]eventsourcing.EventInternal, error) {
+	query, args, err := buildSQLEventQuery(filter, sqlitePlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := r.query(ctx, "SELECT "+sqliteEventColumns+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events from event_store table: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r sqliteEventRepository) GetUnpublished(ctx context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
+	unpublishedEvents, err := r.leaseUnpublished(ctx, aggregateType, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unpublished events: %w", err)
+	}
+
+	for _, event := range unpublishedEvents {
+		log.Debug().
+			Str("event_type", event.EventType.String()).
+			Str("event_id", event.EventId.String()).
+			Str("aggregate_type", string(event.AggregateType)).
+			Str("aggregate_id", event.AggregateId.String()).
+			Msg("loaded unpublished event")
+	}
+
+	return unpublishedEvents, nil
+}
+
+func (r sqliteEventRepository) MarkAs(ctx context.Context, asPublished bool, events ...eventsourcing.EventInternal) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(events)+1)
+	args = append(args, asPublished)
+	for _, event := range events {
+		args = append(args, event.EventId.String())
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		"UPDATE events_outbox SET published = ?, leased_until = NULL WHERE event_id IN ("+sqlPlaceholders(sqlitePlaceholder, 2, len(events))+")",
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark events in event_outbox table: %w", err)
+	}
+
+	return nil
+}
+
+func (r sqliteEventRepository) leaseUnpublished(ctx context.Context, aggregateType eventsourcing.AggregateType, batchSize int) ([]eventsourcing.EventInternal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	rows, err := tx.QueryContext(
+		ctx,
+		sqliteLeaseUnpublishedQuery,
+		now.Add(r.options.outboxLease).UnixMilli(),
+		string(aggregateType),
+		now.UnixMilli(),
+		batchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventIds []any
+	for rows.Next() {
+		var eventId string
+		err = rows.Scan(&eventId)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan leased event id: %w", err)
+		}
+		eventIds = append(eventIds, eventId)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(eventIds) == 0 {
+		return nil, nil
+	}
+
+	events, err := sqliteQueryEvents(
+		ctx,
+		tx,
+		"SELECT "+sqliteEventColumns+` FROM events
+		JOIN events_outbox outbox ON outbox.event_id = events.event_id
+		WHERE events.event_id IN (`+sqlPlaceholders(sqlitePlaceholder, 1, len(eventIds))+`)
+		ORDER BY events.global_position ASC`,
+		eventIds...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, tx.Commit()
+}
+
+func (r sqliteEventRepository) query(ctx context.Context, query string, args ...any) ([]eventsourcing.EventInternal, error) {
+	return sqliteQueryEvents(ctx, r.db, query, args...)
+}
+
+type sqliteQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func sqliteQueryEvents(ctx context.Context, db sqliteQueryer, query string, args ...any) ([]eventsourcing.EventInternal, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []eventsourcing.EventInternal
+	for rows.Next() {
+		event, err := scanSQLiteEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// scanSQLiteEvent scans the event columns followed by the extra destinations
+func scanSQLiteEvent(rows *sql.Rows, extra ...any) (eventsourcing.EventInternal, error) {
+	var (
+		event                              eventsourcing.EventInternal
+		eventId, aggregateId, eventType    string
+		aggregateType, issuedAt            string
+		eventData, eventHash, previousHash []byte
+		eventSignature                     []byte
+	)
+
+	dest := []any{
+		&event.GlobalPosition,
+		&eventId,
+		&eventType,
+		&event.EventIssuedBy,
+		&issuedAt,
+		&aggregateId,
+		&aggregateType,
+		&event.AggregateVersion,
+		&eventData,
+		&eventHash,
+		&previousHash,
+		&eventSignature,
+		&event.EventPublished,
+	}
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return event, fmt.Errorf("failed to scan event: %w", err)
+	}
+
+	event.EventId, err = uuid.Parse(eventId)
+	if err != nil {
+		return event, fmt.Errorf("invalid event id(%s): %w", eventId, err)
+	}
+	event.AggregateId, err = uuid.Parse(aggregateId)
+	if err != nil {
+		return event, fmt.Errorf("invalid aggregate id(%s): %w", aggregateId, err)
+	}
+	event.EventIssuedAt, err = time.Parse(time.RFC3339Nano, issuedAt)
+	if err != nil {
+		return event, fmt.Errorf("invalid event issued at(%s): %w", issuedAt, err)
+	}
+
+	event.EventType = eventsourcing.EventType(eventType)
+	event.AggregateType = eventsourcing.AggregateType(aggregateType)
+	event.EventData = eventData
+	event.EventHash = eventHash
+	event.PreviousEventHash = previousHash
+	event.EventSignature = eventSignature
+
+	return event, nil
+}
+
+func sqlitePlaceholder(int) string {
+	return "?"
+}
+
+// sqliteTimeFormat keeps the trailing zeros of the fraction of seconds so that
+// times stored as text are ordered as the time they represent
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+func encodeSQLiteTime(t time.Time) any {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+func decodeSQLiteTime(v any) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unexpected time type %T", v)
+	}
+
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,127 @@
+//go:build unit
+
+package eventrepository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository/conformance"
+	"github.com/davidterranova/cqrs/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteTestRepository(t *testing.T, opts ...EventRepositoryOption) *sqliteEventRepository {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.db")
+	require.NoError(t, sqlite.Migrate(path))
+
+	db, err := sqlite.Open(sqlite.DBConfig{Path: path, BusyTimeout: time.Second, MaxOpenConnections: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return NewSQLiteEventRepository(db, opts...)
+}
+
+func TestSQLiteEventRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteTestRepository(t)
+
+	aggregateId := uuid.New()
+	issuedAt := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	events := []eventsourcing.EventInternal{
+		{
+			EventId:          uuid.New(),
+			EventIssuedAt:    issuedAt,
+			EventIssuedBy:    uuid.New().String(),
+			EventType:        eventsourcing.EventType("created"),
+			EventData:        []byte(`{}`),
+			AggregateId:      aggregateId,
+			AggregateType:    "test",
+			AggregateVersion: 1,
+			EventHash:        []byte{0x01},
+		},
+		{
+			EventId:           uuid.New(),
+			EventIssuedAt:     issuedAt,
+			EventIssuedBy:     uuid.New().String(),
+			EventType:         eventsourcing.EventType("name-set"),
+			EventData:         []byte(`{"Name":"john"}`),
+			AggregateId:       aggregateId,
+			AggregateType:     "test",
+			AggregateVersion:  2,
+			EventHash:         []byte{0x02},
+			PreviousEventHash: []byte{0x01},
+			EventSignature:    []byte{0x03},
+		},
+	}
+	require.NoError(t, repo.Save(ctx, true, events...))
+
+	t.Run("get", func(t *testing.T) {
+		loaded, err := repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithAggregateId(aggregateId)))
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+
+		assert.Equal(t, int64(1), loaded[0].GlobalPosition)
+		assert.Equal(t, int64(2), loaded[1].GlobalPosition)
+		assert.Equal(t, events[1].EventId, loaded[1].EventId)
+		assert.Equal(t, issuedAt, loaded[1].EventIssuedAt)
+		assert.JSONEq(t, string(events[1].EventData), string(loaded[1].EventData))
+		assert.Equal(t, events[1].PreviousEventHash, loaded[1].PreviousEventHash)
+		assert.Equal(t, events[1].EventSignature, loaded[1].EventSignature)
+	})
+
+	t.Run("filters and order", func(t *testing.T) {
+		loaded, err := repo.Get(ctx, eventsourcing.NewEventQuery(
+			eventsourcing.EventQueryWithAfterPosition(0),
+			eventsourcing.EventQueryWithOrderByPosition(eventsourcing.DESC),
+			eventsourcing.EventQueryWithLimit(1),
+		))
+		require.NoError(t, err)
+		require.Len(t, loaded, 1)
+		assert.Equal(t, events[1].EventId, loaded[0].EventId)
+
+		loaded, err = repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithUpToVersion(1)))
+		require.NoError(t, err)
+		require.Len(t, loaded, 1)
+		assert.Equal(t, events[0].EventId, loaded[0].EventId)
+	})
+
+	t.Run("outbox", func(t *testing.T) {
+		unpublished, err := repo.GetUnpublished(ctx, "test", 10)
+		require.NoError(t, err)
+		require.Len(t, unpublished, 2)
+		assert.Equal(t, events[0].EventId, unpublished[0].EventId)
+
+		require.NoError(t, repo.MarkAs(ctx, true, unpublished[0]))
+		// marking the second event as unpublished releases its lease
+		require.NoError(t, repo.MarkAs(ctx, false, unpublished[1]))
+
+		unpublished, err = repo.GetUnpublished(ctx, "test", 10)
+		require.NoError(t, err)
+		require.Len(t, unpublished, 1)
+		assert.Equal(t, events[1].EventId, unpublished[0].EventId)
+
+		published, err := repo.Get(ctx, eventsourcing.NewEventQuery(eventsourcing.EventQueryWithPublished(true)))
+		require.NoError(t, err)
+		require.Len(t, published, 1)
+		assert.True(t, published[0].EventPublished)
+	})
+
+	t.Run("duplicate event id", func(t *testing.T) {
+		err := repo.Save(ctx, false, events[0])
+		assert.Error(t, err)
+	})
+}
+
+func TestSQLiteEventRepositoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) eventsourcing.EventRepository {
+		return newSQLiteTestRepository(t)
+	})
+}
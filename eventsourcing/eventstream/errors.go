@@ -0,0 +1,8 @@
+package eventstream
+
+import "errors"
+
+var (
+	ErrStreamClosed        = errors.New("event stream closed")
+	ErrSubscriberQueueFull = errors.New("subscriber queue is full")
+)
@@ -2,64 +2,243 @@ package eventstream
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/davidterranova/cqrs/eventsourcing"
 	"github.com/rs/zerolog/log"
 )
 
+// BackpressurePolicy defines what Publish does when the queue of a subscriber is full
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock waits for the subscriber to make room in its queue
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDrop drops the event for the subscriber, the other subscribers still receive it
+	BackpressureDrop
+	// BackpressureError makes Publish return ErrSubscriberQueueFull, the subscribers which had room received the event
+	BackpressureError
+)
+
+type inMemoryOptions struct {
+	backpressure BackpressurePolicy
+}
+
+type InMemoryOption func(*inMemoryOptions)
+
+// InMemoryWithBackpressure sets the policy applied when a subscriber is too slow, defaults to BackpressureBlock
+func InMemoryWithBackpressure(policy BackpressurePolicy) InMemoryOption {
+	return func(o *inMemoryOptions) {
+		o.backpressure = policy
+	}
+}
+
 type eventStream[T eventsourcing.Aggregate] struct {
-	stream      chan eventsourcing.Event[T]
-	subscribers []eventsourcing.SubscribeFn[T]
+	buffer  int
+	options inMemoryOptions
+
 	mtx         sync.RWMutex
-	ctx         context.Context
+	subscribers map[*Subscription[T]]struct{}
+	closed      bool
+	// closing is closed first so that blocked publishers give up before the stream is locked
+	closing   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+	// done is closed once the subscribers handled the events queued before the stream was closed
+	done chan struct{}
+}
+
+// Subscription is the handle of a subscriber of an in-memory stream
+type Subscription[T eventsourcing.Aggregate] struct {
+	stream          *eventStream[T]
+	sub             eventsourcing.SubscribeFn[T]
+	filter          eventsourcing.SubscriptionFilter
+	queue           chan eventsourcing.Event[T]
+	unsubscribed    chan struct{}
+	unsubscribeOnce sync.Once
 }
 
 // NewInMemoryPubSub creates a new in-memory event stream
-// buffer is the size of the channel buffer
-func NewInMemoryPubSub[T eventsourcing.Aggregate](ctx context.Context, buffer int) *eventStream[T] {
+// every subscriber has its own queue of buffer events and goroutine, so that a slow subscriber does not hold back the others
+// the stream is closed when ctx is done
+func NewInMemoryPubSub[T eventsourcing.Aggregate](ctx context.Context, buffer int, opts ...InMemoryOption) *eventStream[T] {
+	var options inMemoryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	p := &eventStream[T]{
-		ctx:         ctx,
-		stream:      make(chan eventsourcing.Event[T], buffer),
-		subscribers: make([]eventsourcing.SubscribeFn[T], 0),
+		buffer:      buffer,
+		options:     options,
+		subscribers: make(map[*Subscription[T]]struct{}),
+		closing:     make(chan struct{}),
+		done:        make(chan struct{}),
 	}
-	go p.Run()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.Close()
+		case <-p.closing:
+		}
+	}()
 
 	return p
 }
 
+// Publish queues the events for every subscriber, it fails with ErrStreamClosed once the stream is closed
 func (p *eventStream[T]) Publish(events ...eventsourcing.Event[T]) error {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.closed {
+		return ErrStreamClosed
+	}
+
 	for _, event := range events {
 		log.Debug().
 			Str("type", event.EventType().String()).
 			Interface("event", event).
 			Msg("publishing event")
-		p.stream <- event
+
+		for s := range p.subscribers {
+			if !s.filter.Match(event) {
+				continue
+			}
+
+			err := p.enqueue(s, event)
+			if err != nil {
+				return fmt.Errorf("failed to publish event(%s): %w", event.Id(), err)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (p *eventStream[T]) Run() {
-	func() {
-		for {
-			select {
-			case event := <-p.stream:
-				p.mtx.RLock()
-				for _, sub := range p.subscribers {
-					sub(event)
-				}
-				p.mtx.RUnlock()
-			case <-p.ctx.Done():
-				close(p.stream)
-				return
-			}
+func (p *eventStream[T]) enqueue(s *Subscription[T], event eventsourcing.Event[T]) error {
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+	}
+
+	switch p.options.backpressure {
+	case BackpressureDrop:
+		log.Warn().
+			Str("event_id", event.Id().String()).
+			Str("type", event.EventType().String()).
+			Msg("event stream: subscriber queue is full, event dropped")
+		return nil
+	case BackpressureError:
+		return ErrSubscriberQueueFull
+	default:
+		select {
+		case s.queue <- event:
+			return nil
+		case <-s.unsubscribed:
+			return nil
+		case <-p.closing:
+			return ErrStreamClosed
 		}
-	}()
+	}
 }
 
-func (p *eventStream[T]) Subscribe(sub eventsourcing.SubscribeFn[T]) {
+func (p *eventStream[T]) Subscribe(sub eventsourcing.SubscribeFn[T], opts ...eventsourcing.SubscribeOption) {
+	_ = p.SubscribeWithHandle(sub, opts...)
+}
+
+// SubscribeWithHandle subscribes sub and returns its subscription, it is nil when the stream is closed
+// events which do not match the options are not queued for the subscriber
+func (p *eventStream[T]) SubscribeWithHandle(sub eventsourcing.SubscribeFn[T], opts ...eventsourcing.SubscribeOption) *Subscription[T] {
 	p.mtx.Lock()
-	p.subscribers = append(p.subscribers, sub)
-	p.mtx.Unlock()
+	defer p.mtx.Unlock()
+
+	if p.closed {
+		return nil
+	}
+
+	s := &Subscription[T]{
+		stream:       p,
+		sub:          sub,
+		filter:       eventsourcing.NewSubscriptionFilter(opts...),
+		queue:        make(chan eventsourcing.Event[T], p.buffer),
+		unsubscribed: make(chan struct{}),
+	}
+	p.subscribers[s] = struct{}{}
+
+	p.wg.Add(1)
+	go s.run(&p.wg)
+
+	return s
+}
+
+// Close stops accepting events, the subscribers handle the events already queued before Done is closed
+// it does not wait for the subscribers so that it can be called by one of them, it can be called several times
+func (p *eventStream[T]) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closing)
+
+		p.mtx.Lock()
+		p.closed = true
+		for s := range p.subscribers {
+			close(s.queue)
+		}
+		p.subscribers = nil
+		p.mtx.Unlock()
+
+		go func() {
+			p.wg.Wait()
+			close(p.done)
+		}()
+	})
+}
+
+// Done is closed once the stream is closed and its subscribers handled the events queued before
+func (p *eventStream[T]) Done() <-chan struct{} {
+	return p.done
+}
+
+// Unsubscribe stops the delivery of events, the events queued for the subscriber are dropped
+func (s *Subscription[T]) Unsubscribe() {
+	s.unsubscribeOnce.Do(func() {
+		close(s.unsubscribed)
+
+		s.stream.mtx.Lock()
+		defer s.stream.mtx.Unlock()
+		if _, ok := s.stream.subscribers[s]; ok {
+			delete(s.stream.subscribers, s)
+			close(s.queue)
+		}
+	})
+}
+
+func (s *Subscription[T]) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for event := range s.queue {
+		select {
+		case <-s.unsubscribed:
+			continue
+		default:
+		}
+
+		s.deliver(event)
+	}
+}
+
+// deliver isolates the panics of the subscriber so that it keeps receiving the next events
+func (s *Subscription[T]) deliver(event eventsourcing.Event[T]) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Interface("panic", r).
+				Str("event_id", event.Id().String()).
+				Str("type", event.EventType().String()).
+				Msg("event stream: subscriber panicked")
+		}
+	}()
+
+	s.sub(event)
 }
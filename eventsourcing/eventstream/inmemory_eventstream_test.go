@@ -0,0 +1,234 @@
+//go:build unit
+
+package eventstream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAggregateType eventsourcing.AggregateType = "test"
+
+type testAggregate struct {
+	*eventsourcing.AggregateBase[testAggregate]
+}
+
+func (testAggregate) AggregateType() eventsourcing.AggregateType {
+	return testAggregateType
+}
+
+type evtTest struct {
+	*eventsourcing.EventBase[testAggregate]
+}
+
+func (evtTest) Apply(*testAggregate) error {
+	return nil
+}
+
+func newTestEvents(nb int) []eventsourcing.Event[testAggregate] {
+	aggregateId := uuid.New()
+	events := make([]eventsourcing.Event[testAggregate], 0, nb)
+	for i := 0; i < nb; i++ {
+		events = append(events, evtTest{
+			EventBase: eventsourcing.NewEventBase[testAggregate](testAggregateType, i+1, "test", aggregateId, nil),
+		})
+	}
+
+	return events
+}
+
+type recorder struct {
+	mtx    sync.Mutex
+	events []eventsourcing.Event[testAggregate]
+}
+
+func (r *recorder) handle(e eventsourcing.Event[testAggregate]) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) count() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return len(r.events)
+}
+
+func TestInMemoryPubSub(t *testing.T) {
+	t.Run("slow subscribers do not hold back the others", func(t *testing.T) {
+		stream := NewInMemoryPubSub[testAggregate](context.Background(), 10)
+		defer stream.Close()
+
+		release := make(chan struct{})
+		stream.Subscribe(func(eventsourcing.Event[testAggregate]) { <-release })
+		fast := &recorder{}
+		stream.Subscribe(fast.handle)
+
+		events := newTestEvents(5)
+		require.NoError(t, stream.Publish(events...))
+		assert.Eventually(t, func() bool { return fast.count() == 5 }, time.Second, 10*time.Millisecond)
+		for i, e := range fast.events {
+			assert.Equal(t, events[i].Id(), e.Id())
+		}
+		close(release)
+	})
+
+	t.Run("panicking subscribers keep receiving events", func(t *testing.T) {
+		stream := NewInMemoryPubSub[testAggregate](context.Background(), 10)
+		defer stream.Close()
+
+		events := newTestEvents(2)
+		received := &recorder{}
+		stream.Subscribe(func(e eventsourcing.Event[testAggregate]) {
+			received.handle(e)
+			if e.Id() == events[0].Id() {
+				panic("subscriber failure")
+			}
+		})
+
+		require.NoError(t, stream.Publish(events...))
+		assert.Eventually(t, func() bool { return received.count() == 2 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		stream := NewInMemoryPubSub[testAggregate](context.Background(), 10)
+		defer stream.Close()
+
+		unsubscribed := &recorder{}
+		subscription := stream.SubscribeWithHandle(unsubscribed.handle)
+		subscribed := &recorder{}
+		stream.Subscribe(subscribed.handle)
+
+		subscription.Unsubscribe()
+		subscription.Unsubscribe()
+		require.NoError(t, stream.Publish(newTestEvents(1)...))
+		assert.Eventually(t, func() bool { return subscribed.count() == 1 }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, 0, unsubscribed.count())
+	})
+
+	t.Run("filtered subscriptions", func(t *testing.T) {
+		stream := NewInMemoryPubSub[testAggregate](context.Background(), 60, InMemoryWithBackpressure(BackpressureError))
+		defer stream.Close()
+
+		// a blocked subscriber only fills its queue with the events it subscribed to
+		release := make(chan struct{})
+		defer close(release)
+		stream.Subscribe(func(eventsourcing.Event[testAggregate]) { <-release }, eventsourcing.SubscribeToEventTypes("other"))
+
+		events := newTestEvents(3)
+		received := &recorder{}
+		stream.Subscribe(
+			received.handle,
+			eventsourcing.SubscribeToEventTypes("test"),
+			eventsourcing.SubscribeWithPredicate(func(e eventsourcing.EventHeader) bool { return e.AggregateVersion() != 2 }),
+		)
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, stream.Publish(events...))
+		}
+		assert.Eventually(t, func() bool { return received.count() == 40 }, time.Second, 10*time.Millisecond)
+		received.mtx.Lock()
+		defer received.mtx.Unlock()
+		for _, e := range received.events {
+			assert.NotEqual(t, 2, e.AggregateVersion())
+		}
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			policy BackpressurePolicy
+			err    error
+		}{
+			"drop":  {policy: BackpressureDrop},
+			"error": {policy: BackpressureError, err: ErrSubscriberQueueFull},
+		} {
+			t.Run(name, func(t *testing.T) {
+				stream := NewInMemoryPubSub[testAggregate](context.Background(), 1, InMemoryWithBackpressure(tc.policy))
+				defer stream.Close()
+
+				release := make(chan struct{})
+				stream.Subscribe(func(eventsourcing.Event[testAggregate]) { <-release })
+				defer close(release)
+
+				// the subscriber holds the first event and its queue holds the second one
+				require.NoError(t, stream.Publish(newTestEvents(1)...))
+				time.Sleep(20 * time.Millisecond)
+				require.NoError(t, stream.Publish(newTestEvents(1)...))
+
+				err := stream.Publish(newTestEvents(1)...)
+				if tc.err == nil {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, tc.err)
+				}
+			})
+		}
+
+		t.Run("block gives up when the stream is closed", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			stream := NewInMemoryPubSub[testAggregate](ctx, 1)
+
+			release := make(chan struct{})
+			stream.Subscribe(func(eventsourcing.Event[testAggregate]) { <-release })
+
+			published := make(chan error, 1)
+			go func() { published <- stream.Publish(newTestEvents(3)...) }()
+			time.Sleep(20 * time.Millisecond)
+
+			cancel()
+			select {
+			case err := <-published:
+				assert.ErrorIs(t, err, ErrStreamClosed)
+			case <-time.After(time.Second):
+				t.Fatal("publish was not interrupted by the stream closing")
+			}
+			close(release)
+		})
+	})
+
+	t.Run("publish after close", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := NewInMemoryPubSub[testAggregate](ctx, 10)
+		received := &recorder{}
+		stream.Subscribe(received.handle)
+
+		require.NoError(t, stream.Publish(newTestEvents(3)...))
+		stream.Close()
+		cancel()
+
+		// queued events are delivered before Done is closed
+		<-stream.Done()
+		assert.Equal(t, 3, received.count())
+		assert.ErrorIs(t, stream.Publish(newTestEvents(1)...), ErrStreamClosed)
+		assert.Nil(t, stream.SubscribeWithHandle(received.handle))
+	})
+
+	t.Run("a subscriber closes the stream", func(t *testing.T) {
+		stream := NewInMemoryPubSub[testAggregate](context.Background(), 10)
+		received := &recorder{}
+		stream.Subscribe(func(e eventsourcing.Event[testAggregate]) {
+			received.handle(e)
+			stream.Close()
+		})
+
+		require.NoError(t, stream.Publish(newTestEvents(3)...))
+		select {
+		case <-stream.Done():
+		case <-time.After(time.Second):
+			t.Fatal("the stream closed by its subscriber did not stop")
+		}
+		stream.Close()
+
+		// the events queued before the stream was closed are still delivered
+		assert.Equal(t, 3, received.count())
+	})
+}
@@ -0,0 +1,80 @@
+package eventstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/rs/zerolog/log"
+)
+
+type syncEventStream[T eventsourcing.Aggregate] struct {
+	subscribers []syncSubscriber[T]
+	mtx         sync.RWMutex
+}
+
+type syncSubscriber[T eventsourcing.Aggregate] struct {
+	sub    eventsourcing.SubscribeFnV2[T]
+	filter eventsourcing.SubscriptionFilter
+}
+
+// NewSyncPubSub creates an in-memory event stream delivering events to its subscribers in the publishing goroutine
+// Publish returns once every subscriber acknowledged the events, with the errors of the subscribers which did not
+// events are published in order: the events following a negatively acknowledged one are not delivered
+// and the error is an *eventsourcing.PublishError reporting that event
+func NewSyncPubSub[T eventsourcing.Aggregate]() *syncEventStream[T] {
+	return &syncEventStream[T]{
+		subscribers: make([]syncSubscriber[T], 0),
+	}
+}
+
+func (p *syncEventStream[T]) Publish(ctx context.Context, events ...eventsourcing.Event[T]) error {
+	p.mtx.RLock()
+	subscribers := p.subscribers
+	p.mtx.RUnlock()
+
+	for _, event := range events {
+		log.Ctx(ctx).Debug().
+			Str("type", event.EventType().String()).
+			Interface("event", event).
+			Msg("publishing event")
+
+		var errs []error
+		for _, s := range subscribers {
+			if !s.filter.Match(event) {
+				continue
+			}
+
+			err := deliver(ctx, s.sub, event)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if len(errs) > 0 {
+			return &eventsourcing.PublishError{EventId: event.Id(), Err: errors.Join(errs...)}
+		}
+	}
+
+	return nil
+}
+
+func (p *syncEventStream[T]) Subscribe(sub eventsourcing.SubscribeFnV2[T], opts ...eventsourcing.SubscribeOption) {
+	p.mtx.Lock()
+	p.subscribers = append(p.subscribers, syncSubscriber[T]{sub: sub, filter: eventsourcing.NewSubscriptionFilter(opts...)})
+	p.mtx.Unlock()
+}
+
+// deliver waits for the subscriber to acknowledge the event, which it may do after returning
+func deliver[T eventsourcing.Aggregate](ctx context.Context, sub eventsourcing.SubscribeFnV2[T], event eventsourcing.Event[T]) error {
+	settled := make(chan error, 1)
+	sub(ctx, eventsourcing.NewMessage(event, func(err error) { settled <- err }))
+
+	select {
+	case err := <-settled:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
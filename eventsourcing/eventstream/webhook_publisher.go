@@ -0,0 +1,464 @@
+package eventstream
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// WebhookSignatureHeader holds "sha256=" followed by the hex HMAC-SHA256 of the timestamp, a dot and the body, see SignWebhookPayload
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader holds the unix time of the delivery attempt, endpoints should reject old timestamps to prevent replays
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookIdHeader        = "X-Webhook-Id"
+	// WebhookEventIdHeader identifies the event, events may be delivered more than once and endpoints should skip duplicates
+	WebhookEventIdHeader   = "X-Event-Id"
+	WebhookEventTypeHeader = "X-Event-Type"
+
+	DefaultWebhookTimeout      = 10 * time.Second
+	DefaultWebhookMaxAttempts  = 5
+	DefaultWebhookPollInterval = time.Second
+
+	webhookSignaturePrefix = "sha256="
+	// webhookResponseLimit bounds the part of the response kept in the delivery log
+	webhookResponseLimit = 512
+	// webhookPendingBatchSize is the number of pending deliveries read at once for a webhook
+	webhookPendingBatchSize = 100
+)
+
+// WebhookPayload is the JSON body of a delivery, Data is the event as stored by the event store
+type WebhookPayload struct {
+	EventId          uuid.UUID       `json:"event_id"`
+	EventType        string          `json:"event_type"`
+	EventIssuedAt    time.Time       `json:"event_issued_at"`
+	EventIssuedBy    string          `json:"event_issued_by,omitempty"`
+	AggregateId      uuid.UUID       `json:"aggregate_id"`
+	AggregateType    string          `json:"aggregate_type"`
+	AggregateVersion int             `json:"aggregate_version"`
+	Data             json.RawMessage `json:"data"`
+}
+
+type webhookOptions struct {
+	client          *http.Client
+	maxAttempts     int
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	pollInterval    time.Duration
+}
+
+type WebhookOption func(*webhookOptions)
+
+// WebhookWithHTTPClient sets the client sending the deliveries, defaults to a client timing out after DefaultWebhookTimeout
+func WebhookWithHTTPClient(client *http.Client) WebhookOption {
+	return func(o *webhookOptions) {
+		if client != nil {
+			o.client = client
+		}
+	}
+}
+
+// WebhookWithRetries sets the number of attempts to deliver an event and the exponential backoff between them
+// a delivery is held once its attempts are exhausted, with the next deliveries of the webhook, until it is retried or dropped
+func WebhookWithRetries(maxAttempts int, initialInterval time.Duration, maxInterval time.Duration) WebhookOption {
+	return func(o *webhookOptions) {
+		if maxAttempts > 0 {
+			o.maxAttempts = maxAttempts
+		}
+		if initialInterval > 0 {
+			o.initialInterval = initialInterval
+		}
+		if maxInterval > 0 {
+			o.maxInterval = maxInterval
+		}
+	}
+}
+
+// WebhookWithPollInterval sets how often Run looks for pending deliveries which are due, defaults to DefaultWebhookPollInterval
+func WebhookWithPollInterval(pollInterval time.Duration) WebhookOption {
+	return func(o *webhookOptions) {
+		if pollInterval > 0 {
+			o.pollInterval = pollInterval
+		}
+	}
+}
+
+type webhookPublisher[T eventsourcing.Aggregate] struct {
+	repo    eventsourcing.WebhookRepository
+	options webhookOptions
+	// wakeUp is signaled by the publications so that Run delivers the new events without waiting for the poll interval
+	wakeUp chan struct{}
+
+	mtx sync.Mutex
+	// delivering holds the webhooks whose deliveries are in progress, a webhook is delivered by a single worker to keep the order
+	delivering map[uuid.UUID]bool
+}
+
+// NewWebhookPublisher creates a publisher POSTing the events to the webhooks of repo which are not paused and accept their type
+// Publish only queues the events as pending deliveries in repo, it fails when they could not be stored
+// the deliveries are sent by Run, or DeliverPending, in order to each webhook so that a failing webhook holds back neither
+// the publication nor the other webhooks, every attempt is logged in repo
+// a delivery rejected with a client error other than 408 and 429 is dropped, other failures are retried with a backoff
+// until the attempts are exhausted, the delivery is then held until it is retried or dropped from the admin API
+func NewWebhookPublisher[T eventsourcing.Aggregate](repo eventsourcing.WebhookRepository, opts ...WebhookOption) *webhookPublisher[T] {
+	options := webhookOptions{
+		client:          &http.Client{Timeout: DefaultWebhookTimeout},
+		maxAttempts:     DefaultWebhookMaxAttempts,
+		initialInterval: 500 * time.Millisecond,
+		maxInterval:     30 * time.Second,
+		pollInterval:    DefaultWebhookPollInterval,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &webhookPublisher[T]{
+		repo:       repo,
+		options:    options,
+		wakeUp:     make(chan struct{}, 1),
+		delivering: make(map[uuid.UUID]bool),
+	}
+}
+
+// SignWebhookPayload returns the value of the signature header of a delivery
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature is used by endpoints to check that a delivery was signed with secret
+func VerifyWebhookSignature(secret string, signature string, timestamp int64, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(SignWebhookPayload(secret, timestamp, body)))
+}
+
+func (p *webhookPublisher[T]) Publish(events ...eventsourcing.Event[T]) error {
+	return p.PublishContext(context.Background(), events...)
+}
+
+// PublishContext queues the events as pending deliveries of the webhooks and wakes Run up, it does not wait for the deliveries
+// the events published again by the outbox are only queued once
+func (p *webhookPublisher[T]) PublishContext(ctx context.Context, events ...eventsourcing.Event[T]) error {
+	webhooks, err := p.repo.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	payloads := make([][]byte, 0, len(events))
+	for _, event := range events {
+		payload, err := newWebhookPayload(event)
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, payload)
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]eventsourcing.WebhookPendingDelivery, 0, len(events))
+	for _, webhook := range webhooks {
+		if webhook.Paused {
+			continue
+		}
+
+		for i, event := range events {
+			if webhook.Accepts(event.EventType()) {
+				deliveries = append(deliveries, eventsourcing.WebhookPendingDelivery{
+					WebhookId: webhook.Id,
+					EventId:   event.Id(),
+					EventType: event.EventType(),
+					Payload:   payloads[i],
+					CreatedAt: now,
+				})
+			}
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	err = p.repo.AddPendingDeliveries(ctx, deliveries)
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	select {
+	case p.wakeUp <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run delivers the pending deliveries until ctx is done, whenever events are published and every poll interval
+// the webhooks are delivered concurrently, Run waits for the deliveries in progress before returning
+func (p *webhookPublisher[T]) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(p.options.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := p.startDeliveries(ctx, &wg, func(webhookId uuid.UUID, err error) {
+			log.Ctx(ctx).Warn().
+				Err(err).
+				Str("webhook_id", webhookId.String()).
+				Msg("webhook publisher: failed to deliver pending deliveries")
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("webhook publisher: failed to list webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-p.wakeUp:
+		}
+	}
+}
+
+// DeliverPending delivers the pending deliveries which are due, as a single round of Run, and waits for them
+// it returns the errors of the webhooks whose pending deliveries could not be read or updated
+func (p *webhookPublisher[T]) DeliverPending(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+	)
+	err := p.startDeliveries(ctx, &wg, func(webhookId uuid.UUID, err error) {
+		mtx.Lock()
+		errs = append(errs, fmt.Errorf("failed to deliver to webhook(%s): %w", webhookId, err))
+		mtx.Unlock()
+	})
+	wg.Wait()
+
+	return errors.Join(append(errs, err)...)
+}
+
+// startDeliveries starts delivering the webhooks which are not paused nor already being delivered
+func (p *webhookPublisher[T]) startDeliveries(ctx context.Context, wg *sync.WaitGroup, onError func(uuid.UUID, error)) error {
+	webhooks, err := p.repo.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	for _, webhook := range webhooks {
+		if webhook.Paused || !p.claim(webhook.Id) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(webhook eventsourcing.Webhook) {
+			defer wg.Done()
+			defer p.release(webhook.Id)
+
+			err := p.deliverTo(ctx, webhook)
+			if err != nil && ctx.Err() == nil {
+				onError(webhook.Id, err)
+			}
+		}(webhook)
+	}
+
+	return nil
+}
+
+func (p *webhookPublisher[T]) claim(webhookId uuid.UUID) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.delivering[webhookId] {
+		return false
+	}
+	p.delivering[webhookId] = true
+
+	return true
+}
+
+func (p *webhookPublisher[T]) release(webhookId uuid.UUID) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	delete(p.delivering, webhookId)
+}
+
+// deliverTo sends the pending deliveries of the webhook in order, until one fails or is not due yet
+func (p *webhookPublisher[T]) deliverTo(ctx context.Context, webhook eventsourcing.Webhook) error {
+	for {
+		pending, err := p.repo.ListPendingDeliveries(ctx, webhook.Id, webhookPendingBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range pending {
+			if delivery.Attempts >= p.options.maxAttempts || time.Now().Before(delivery.NextAttemptAt) {
+				return nil
+			}
+
+			delivered, err := p.deliver(ctx, webhook, delivery)
+			if err != nil || !delivered {
+				return err
+			}
+		}
+		if len(pending) < webhookPendingBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver posts the delivery once, it is removed from the pending deliveries when the webhook acknowledges or rejects it
+// client errors other than 408 and 429 reject the delivery, other failures are attempted again after a backoff
+func (p *webhookPublisher[T]) deliver(ctx context.Context, webhook eventsourcing.Webhook, delivery eventsourcing.WebhookPendingDelivery) (bool, error) {
+	start := time.Now()
+	statusCode, failure := p.post(ctx, webhook, delivery)
+	if failure != nil && ctx.Err() != nil {
+		// interrupted attempts are not counted
+		return false, nil
+	}
+	p.logDelivery(ctx, delivery, delivery.Attempts+1, statusCode, time.Since(start), failure)
+
+	if failure == nil || isPermanentWebhookFailure(statusCode) {
+		if failure != nil {
+			log.Ctx(ctx).Warn().
+				Err(failure).
+				Str("webhook_id", webhook.Id.String()).
+				Str("event_id", delivery.EventId.String()).
+				Msg("webhook publisher: delivery rejected, dropping it")
+		}
+
+		err := p.repo.DeletePendingDelivery(ctx, webhook.Id, delivery.EventId)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	delivery.Attempts++
+	delivery.LastError = failure.Error()
+	delivery.NextAttemptAt = time.Now().Add(p.retryInterval(delivery.Attempts)).UTC()
+	if delivery.Attempts >= p.options.maxAttempts {
+		log.Ctx(ctx).Warn().
+			Err(failure).
+			Str("webhook_id", webhook.Id.String()).
+			Str("event_id", delivery.EventId.String()).
+			Int("attempts", delivery.Attempts).
+			Msg("webhook publisher: delivery attempts exhausted, holding the deliveries of the webhook")
+	}
+
+	return false, p.repo.UpdatePendingDelivery(ctx, delivery)
+}
+
+// retryInterval is the exponential backoff after the given number of failed attempts
+func (p *webhookPublisher[T]) retryInterval(attempts int) time.Duration {
+	interval := p.options.initialInterval
+	for i := 1; i < attempts && interval < p.options.maxInterval; i++ {
+		interval *= 2
+	}
+
+	return min(interval, p.options.maxInterval)
+}
+
+func isPermanentWebhookFailure(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests
+}
+
+func newWebhookPayload[T eventsourcing.Aggregate](event eventsourcing.Event[T]) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event(%s): %w", event.Id(), err)
+	}
+
+	payload := WebhookPayload{
+		EventId:          event.Id(),
+		EventType:        event.EventType().String(),
+		EventIssuedAt:    event.IssuedAt(),
+		AggregateId:      event.AggregateId(),
+		AggregateType:    string(event.AggregateType()),
+		AggregateVersion: event.AggregateVersion(),
+		Data:             data,
+	}
+	if event.IssuedBy() != nil {
+		payload.EventIssuedBy = event.IssuedBy().String()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload of event(%s): %w", event.Id(), err)
+	}
+
+	return body, nil
+}
+
+func (p *webhookPublisher[T]) post(ctx context.Context, webhook eventsourcing.Webhook, delivery eventsourcing.WebhookPendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIdHeader, webhook.Id.String())
+	req.Header.Set(WebhookEventIdHeader, delivery.EventId.String())
+	req.Header.Set(WebhookEventTypeHeader, delivery.EventType.String())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := p.options.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		response, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(response))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
+
+// logDelivery records the attempt, a failure to log does not fail the delivery
+func (p *webhookPublisher[T]) logDelivery(ctx context.Context, pending eventsourcing.WebhookPendingDelivery, attempt int, statusCode int, duration time.Duration, failure error) {
+	now := time.Now().UTC()
+	delivery := eventsourcing.WebhookDelivery{
+		Id:          uuid.New(),
+		WebhookId:   pending.WebhookId,
+		EventId:     pending.EventId,
+		EventType:   pending.EventType,
+		Attempt:     attempt,
+		StatusCode:  statusCode,
+		Duration:    duration,
+		DeliveredAt: now,
+	}
+	if failure != nil {
+		delivery.Error = failure.Error()
+	}
+
+	err := p.repo.LogDelivery(context.WithoutCancel(ctx), delivery)
+	if err != nil {
+		log.Ctx(ctx).Warn().
+			Err(err).
+			Str("webhook_id", pending.WebhookId.String()).
+			Str("event_id", pending.EventId.String()).
+			Msg("webhook publisher: failed to log delivery")
+	}
+}
@@ -0,0 +1,272 @@
+//go:build unit
+
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookEndpoint records the deliveries it accepts, it answers the statuses queued in failures first and fails while down
+type webhookEndpoint struct {
+	t        *testing.T
+	secret   string
+	mtx      sync.Mutex
+	failures []int
+	down     bool
+	payloads []WebhookPayload
+}
+
+func (e *webhookEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(e.t, err)
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+	require.NoError(e.t, err)
+	assert.True(e.t, VerifyWebhookSignature(e.secret, r.Header.Get(WebhookSignatureHeader), timestamp, body))
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if e.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if len(e.failures) > 0 {
+		w.WriteHeader(e.failures[0])
+		e.failures = e.failures[1:]
+		return
+	}
+
+	var payload WebhookPayload
+	require.NoError(e.t, json.Unmarshal(body, &payload))
+	assert.Equal(e.t, r.Header.Get(WebhookEventIdHeader), payload.EventId.String())
+	e.payloads = append(e.payloads, payload)
+}
+
+func (e *webhookEndpoint) setDown(down bool) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.down = down
+}
+
+func (e *webhookEndpoint) received() []uuid.UUID {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	ids := make([]uuid.UUID, 0, len(e.payloads))
+	for _, p := range e.payloads {
+		ids = append(ids, p.EventId)
+	}
+
+	return ids
+}
+
+func registerWebhook(t *testing.T, repo eventsourcing.WebhookRepository, endpoint *webhookEndpoint, eventTypes ...eventsourcing.EventType) eventsourcing.Webhook {
+	t.Helper()
+
+	server := httptest.NewServer(endpoint)
+	t.Cleanup(server.Close)
+
+	webhook := eventsourcing.Webhook{
+		Id:         uuid.New(),
+		Url:        server.URL,
+		Secret:     endpoint.secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, repo.CreateWebhook(context.Background(), webhook))
+
+	return webhook
+}
+
+func eventIds(events []eventsourcing.Event[testAggregate]) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id())
+	}
+
+	return ids
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("events are queued then delivered signed and filtered by type", func(t *testing.T) {
+		repo := eventrepository.NewInMemoryWebhookRepository()
+		all := &webhookEndpoint{t: t, secret: "all"}
+		allWebhook := registerWebhook(t, repo, all)
+		filtered := &webhookEndpoint{t: t, secret: "filtered"}
+		filteredWebhook := registerWebhook(t, repo, filtered, "other")
+		paused := &webhookEndpoint{t: t, secret: "paused"}
+		pausedWebhook := registerWebhook(t, repo, paused)
+		require.NoError(t, repo.SetWebhookPaused(ctx, pausedWebhook.Id, true))
+
+		events := newTestEvents(3)
+		publisher := NewWebhookPublisher[testAggregate](repo)
+		require.NoError(t, publisher.Publish(events...))
+		assert.Empty(t, all.received())
+		assert.Equal(t, eventIds(events), pendingEventIds(t, repo, allWebhook.Id))
+		assert.Empty(t, pendingEventIds(t, repo, filteredWebhook.Id))
+		assert.Empty(t, pendingEventIds(t, repo, pausedWebhook.Id))
+
+		require.NoError(t, publisher.DeliverPending(ctx))
+		assert.Equal(t, eventIds(events), all.received())
+		assert.Empty(t, filtered.received())
+		assert.Empty(t, paused.received())
+		assert.Equal(t, testAggregateType, eventsourcing.AggregateType(all.payloads[0].AggregateType))
+		assert.Empty(t, pendingEventIds(t, repo, allWebhook.Id))
+	})
+
+	t.Run("failed deliveries are retried after a backoff and logged", func(t *testing.T) {
+		repo := eventrepository.NewInMemoryWebhookRepository()
+		endpoint := &webhookEndpoint{t: t, secret: "secret", failures: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		webhook := registerWebhook(t, repo, endpoint)
+
+		events := newTestEvents(1)
+		publisher := NewWebhookPublisher[testAggregate](repo, WebhookWithRetries(3, time.Millisecond, time.Millisecond))
+		require.NoError(t, publisher.PublishContext(ctx, events...))
+		require.NoError(t, publisher.DeliverPending(ctx))
+		assert.Empty(t, endpoint.received())
+
+		pending, err := repo.GetPendingDelivery(ctx, webhook.Id, events[0].Id())
+		require.NoError(t, err)
+		assert.Equal(t, 1, pending.Attempts)
+		assert.False(t, pending.NextAttemptAt.IsZero())
+		assert.Contains(t, pending.LastError, "503")
+
+		for i := 0; i < 2; i++ {
+			time.Sleep(5 * time.Millisecond)
+			require.NoError(t, publisher.DeliverPending(ctx))
+		}
+		assert.Equal(t, eventIds(events), endpoint.received())
+
+		deliveries, err := repo.ListDeliveries(ctx, webhook.Id, 0)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 3)
+		assert.True(t, deliveries[0].Succeeded())
+		assert.Equal(t, 3, deliveries[0].Attempt)
+		assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, deliveries[1].StatusCode)
+		assert.Equal(t, http.StatusServiceUnavailable, deliveries[2].StatusCode)
+		assert.False(t, deliveries[2].Succeeded())
+	})
+
+	t.Run("rejected events are dropped and logged without holding back the next ones", func(t *testing.T) {
+		repo := eventrepository.NewInMemoryWebhookRepository()
+		endpoint := &webhookEndpoint{t: t, secret: "secret", failures: []int{http.StatusBadRequest}}
+		webhook := registerWebhook(t, repo, endpoint)
+
+		events := newTestEvents(2)
+		publisher := NewWebhookPublisher[testAggregate](repo, WebhookWithRetries(3, time.Millisecond, time.Millisecond))
+		require.NoError(t, publisher.Publish(events...))
+		require.NoError(t, publisher.DeliverPending(ctx))
+
+		assert.Equal(t, eventIds(events[1:]), endpoint.received())
+		assert.Empty(t, pendingEventIds(t, repo, webhook.Id))
+		deliveries, err := repo.ListDeliveries(ctx, webhook.Id, 0)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, events[0].Id(), deliveries[1].EventId)
+		assert.Equal(t, http.StatusBadRequest, deliveries[1].StatusCode)
+		assert.False(t, deliveries[1].Succeeded())
+	})
+
+	t.Run("exhausted deliveries hold back the next ones until retried", func(t *testing.T) {
+		repo := eventrepository.NewInMemoryWebhookRepository()
+		endpoint := &webhookEndpoint{t: t, secret: "secret", down: true}
+		webhook := registerWebhook(t, repo, endpoint)
+		publisher := NewWebhookPublisher[testAggregate](repo, WebhookWithRetries(2, time.Millisecond, time.Millisecond))
+
+		events := newTestEvents(2)
+		require.NoError(t, publisher.PublishContext(ctx, events...))
+		for i := 0; i < 3; i++ {
+			require.NoError(t, publisher.DeliverPending(ctx))
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		deliveries, err := repo.ListDeliveries(ctx, webhook.Id, 0)
+		require.NoError(t, err)
+		assert.Len(t, deliveries, 2)
+		pending, err := repo.GetPendingDelivery(ctx, webhook.Id, events[0].Id())
+		require.NoError(t, err)
+		assert.Equal(t, 2, pending.Attempts)
+
+		endpoint.setDown(false)
+		require.NoError(t, publisher.DeliverPending(ctx))
+		assert.Empty(t, endpoint.received())
+		assert.Equal(t, eventIds(events), pendingEventIds(t, repo, webhook.Id))
+
+		pending.Attempts = 0
+		pending.NextAttemptAt = time.Time{}
+		require.NoError(t, repo.UpdatePendingDelivery(ctx, pending))
+		require.NoError(t, publisher.DeliverPending(ctx))
+		assert.Equal(t, eventIds(events), endpoint.received())
+		assert.Empty(t, pendingEventIds(t, repo, webhook.Id))
+	})
+
+	t.Run("run delivers the published events and a failing webhook does not hold back the others", func(t *testing.T) {
+		repo := eventrepository.NewInMemoryWebhookRepository()
+		broken := &webhookEndpoint{t: t, secret: "broken", down: true}
+		brokenWebhook := registerWebhook(t, repo, broken)
+		healthy := &webhookEndpoint{t: t, secret: "healthy"}
+		registerWebhook(t, repo, healthy)
+		publisher := NewWebhookPublisher[testAggregate](
+			repo,
+			WebhookWithRetries(1000, time.Millisecond, time.Millisecond),
+			WebhookWithPollInterval(10*time.Millisecond),
+		)
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- publisher.Run(runCtx) }()
+
+		first := newTestEvents(2)
+		require.NoError(t, publisher.PublishContext(ctx, first...))
+		second := newTestEvents(1)
+		require.NoError(t, publisher.PublishContext(ctx, second...))
+
+		all := append(eventIds(first), eventIds(second)...)
+		assert.Eventually(t, func() bool { return len(healthy.received()) == len(all) }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, all, healthy.received())
+		assert.Empty(t, broken.received())
+		assert.Equal(t, all, pendingEventIds(t, repo, brokenWebhook.Id))
+
+		// the pending deliveries are delivered in order once the webhook is back
+		broken.setDown(false)
+		assert.Eventually(t, func() bool { return len(broken.received()) == len(all) }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, all, broken.received())
+
+		cancel()
+		require.NoError(t, <-done)
+		assert.Empty(t, pendingEventIds(t, repo, brokenWebhook.Id))
+	})
+}
+
+func pendingEventIds(t *testing.T, repo eventsourcing.WebhookRepository, webhookId uuid.UUID) []uuid.UUID {
+	t.Helper()
+
+	pending, err := repo.ListPendingDeliveries(context.Background(), webhookId, 0)
+	require.NoError(t, err)
+
+	ids := make([]uuid.UUID, 0, len(pending))
+	for _, d := range pending {
+		ids = append(ids, d.EventId)
+	}
+
+	return ids
+}
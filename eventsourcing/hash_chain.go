@@ -0,0 +1,246 @@
+package eventsourcing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+
+	"github.com/google/uuid"
+)
+
+const defaultHashChainBatchSize = 500
+
+// HashChainReason describes why a link of a hash chain is broken
+type HashChainReason string
+
+const (
+	HashChainMissingHash      HashChainReason = "missing hash"
+	HashChainPreviousMismatch HashChainReason = "previous hash does not match the previous event"
+	HashChainHashMismatch     HashChainReason = "hash does not match the event content"
+)
+
+// ComputeEventHash returns the sha256 hash of an event chained to the hash of the previous event of the same aggregate
+// the hash covers the event metadata, its payload and the previous hash
+// the global position is not covered as it is assigned by the repository
+func ComputeEventHash(e EventInternal, previousHash []byte) ([]byte, error) {
+	data, err := canonicalJSON(e.EventData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize event(%s) data: %w", e.EventId, err)
+	}
+
+	h := sha256.New()
+	writeHashField(h, e.EventId[:])
+	writeHashField(h, []byte(e.EventType))
+	writeHashField(h, []byte(e.EventIssuedBy))
+	// timestamps are stored with a microsecond precision
+	writeHashInt(h, e.EventIssuedAt.UnixMicro())
+	writeHashField(h, []byte(e.AggregateType))
+	writeHashField(h, e.AggregateId[:])
+	writeHashInt(h, int64(e.AggregateVersion))
+	writeHashField(h, data)
+	writeHashField(h, previousHash)
+
+	return h.Sum(nil), nil
+}
+
+// ChainEvents sets the hash of events which are expected to follow each other in the same aggregate stream
+// previousHash is the hash of the last stored event of the aggregate, nil for a new aggregate
+func ChainEvents(previousHash []byte, events []EventInternal) error {
+	for i := range events {
+		eventHash, err := ComputeEventHash(events[i], previousHash)
+		if err != nil {
+			return err
+		}
+
+		events[i].PreviousEventHash = previousHash
+		events[i].EventHash = eventHash
+		previousHash = eventHash
+	}
+
+	return nil
+}
+
+// HashChainBreak locates the first broken link found in a hash chain
+type HashChainBreak struct {
+	EventId          uuid.UUID
+	AggregateType    AggregateType
+	AggregateId      uuid.UUID
+	AggregateVersion int
+	GlobalPosition   int64
+	Reason           HashChainReason
+}
+
+func (b HashChainBreak) Error() string {
+	return fmt.Sprintf("hash chain broken at event(%s) of aggregate(%s#%s) version %d: %s", b.EventId, b.AggregateType, b.AggregateId, b.AggregateVersion, b.Reason)
+}
+
+// HashChainReport is the result of a hash chain verification
+type HashChainReport struct {
+	NbEvents     int
+	NbAggregates int
+	// FirstBreak is nil when the chain is valid
+	FirstBreak *HashChainBreak
+}
+
+func (r HashChainReport) Valid() bool {
+	return r.FirstBreak == nil
+}
+
+// HashChainVerifier walks the events stored in a repository and checks their hash chain
+type HashChainVerifier struct {
+	repo      EventRepository
+	batchSize int
+}
+
+// NewHashChainVerifier creates a verifier loading events by batches of batchSize when walking the whole store
+func NewHashChainVerifier(repo EventRepository, batchSize int) *HashChainVerifier {
+	if batchSize <= 0 {
+		batchSize = defaultHashChainBatchSize
+	}
+
+	return &HashChainVerifier{
+		repo:      repo,
+		batchSize: batchSize,
+	}
+}
+
+// VerifyAggregate checks the hash chain of a single aggregate stream
+func (v *HashChainVerifier) VerifyAggregate(ctx context.Context, aggregateId uuid.UUID) (HashChainReport, error) {
+	events, err := v.repo.Get(
+		ctx,
+		NewEventQuery(
+			EventQueryWithAggregateId(aggregateId),
+			EventQueryWithOrderByPosition(ASC),
+		),
+	)
+	if err != nil {
+		return HashChainReport{}, fmt.Errorf("failed to load events of aggregate(%s): %w", aggregateId, err)
+	}
+
+	chain := newHashChainWalker()
+	for _, e := range events {
+		if chain.next(e) {
+			break
+		}
+	}
+
+	return chain.report, nil
+}
+
+// VerifyAll checks the hash chain of every aggregate stream walking the whole store by global position
+func (v *HashChainVerifier) VerifyAll(ctx context.Context) (HashChainReport, error) {
+	chain := newHashChainWalker()
+	afterPosition := int64(0)
+
+	for {
+		events, err := v.repo.Get(
+			ctx,
+			NewEventQuery(
+				EventQueryWithAfterPosition(afterPosition),
+				EventQueryWithOrderByPosition(ASC),
+				EventQueryWithLimit(v.batchSize),
+			),
+		)
+		if err != nil {
+			return chain.report, fmt.Errorf("failed to load events after position %d: %w", afterPosition, err)
+		}
+
+		for _, e := range events {
+			if chain.next(e) {
+				return chain.report, nil
+			}
+			afterPosition = e.GlobalPosition
+		}
+
+		if len(events) < v.batchSize {
+			return chain.report, nil
+		}
+	}
+}
+
+type hashChainWalker struct {
+	lastHashes map[uuid.UUID][]byte
+	report     HashChainReport
+}
+
+func newHashChainWalker() *hashChainWalker {
+	return &hashChainWalker{
+		lastHashes: make(map[uuid.UUID][]byte),
+	}
+}
+
+// next verifies the next event of the walk and returns true when the chain is broken
+func (w *hashChainWalker) next(e EventInternal) bool {
+	w.report.NbEvents++
+	previousHash, known := w.lastHashes[e.AggregateId]
+	if !known {
+		w.report.NbAggregates++
+	}
+
+	reason := w.check(e, previousHash)
+	if reason != "" {
+		w.report.FirstBreak = &HashChainBreak{
+			EventId:          e.EventId,
+			AggregateType:    e.AggregateType,
+			AggregateId:      e.AggregateId,
+			AggregateVersion: e.AggregateVersion,
+			GlobalPosition:   e.GlobalPosition,
+			Reason:           reason,
+		}
+		return true
+	}
+
+	w.lastHashes[e.AggregateId] = e.EventHash
+	return false
+}
+
+func (w *hashChainWalker) check(e EventInternal, previousHash []byte) HashChainReason {
+	if len(e.EventHash) == 0 {
+		return HashChainMissingHash
+	}
+
+	if !bytes.Equal(e.PreviousEventHash, previousHash) {
+		return HashChainPreviousMismatch
+	}
+
+	expected, err := ComputeEventHash(e, previousHash)
+	if err != nil || !bytes.Equal(expected, e.EventHash) {
+		return HashChainHashMismatch
+	}
+
+	return ""
+}
+
+func writeHashField(h hash.Hash, b []byte) {
+	writeHashInt(h, int64(len(b)))
+	_, _ = h.Write(b)
+}
+
+func writeHashInt(h hash.Hash, i int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(i))
+	_, _ = h.Write(b[:])
+}
+
+// canonicalJSON re-encodes a json document with sorted keys and no insignificant whitespace
+// so that the hash does not depend on the normalisation performed by the storage (e.g. postgres jsonb)
+func canonicalJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
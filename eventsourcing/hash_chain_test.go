@@ -0,0 +1,158 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHashChainTestEvent(aggregateId uuid.UUID, version int, data string) eventsourcing.EventInternal {
+	return eventsourcing.EventInternal{
+		EventId:          uuid.New(),
+		EventIssuedAt:    time.Now().UTC(),
+		EventIssuedBy:    "test",
+		EventType:        "name-set",
+		EventData:        []byte(data),
+		AggregateId:      aggregateId,
+		AggregateType:    "test",
+		AggregateVersion: version,
+	}
+}
+
+func TestComputeEventHash(t *testing.T) {
+	e := newHashChainTestEvent(uuid.New(), 0, `{"b": 1, "a": "x"}`)
+	h1, err := eventsourcing.ComputeEventHash(e, nil)
+	require.NoError(t, err)
+
+	// storage may normalise json documents
+	e.EventData = []byte(`{"a":"x","b":1}`)
+	h2, err := eventsourcing.ComputeEventHash(e, nil)
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2)
+
+	h3, err := eventsourcing.ComputeEventHash(e, h1)
+	require.NoError(t, err)
+	assert.NotEqual(t, h1, h3)
+}
+
+func TestHashChainVerifier(t *testing.T) {
+	ctx := context.Background()
+	repo := eventrepository.NewInMemoryEventRepository()
+	verifier := eventsourcing.NewHashChainVerifier(repo, 2)
+
+	aggregateA := uuid.New()
+	aggregateB := uuid.New()
+	batches := [][]eventsourcing.EventInternal{
+		{newHashChainTestEvent(aggregateA, 0, `{}`), newHashChainTestEvent(aggregateA, 1, `{"Name": "a"}`)},
+		{newHashChainTestEvent(aggregateB, 0, `{}`)},
+		{newHashChainTestEvent(aggregateA, 2, `{"Name": "b"}`)},
+	}
+
+	lastHashes := map[uuid.UUID][]byte{}
+	for _, batch := range batches {
+		aggregateId := batch[0].AggregateId
+		require.NoError(t, eventsourcing.ChainEvents(lastHashes[aggregateId], batch))
+		lastHashes[aggregateId] = batch[len(batch)-1].EventHash
+		require.NoError(t, repo.Save(ctx, true, batch...))
+	}
+
+	t.Run("valid chain", func(t *testing.T) {
+		report, err := verifier.VerifyAll(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Valid())
+		assert.Equal(t, 4, report.NbEvents)
+		assert.Equal(t, 2, report.NbAggregates)
+
+		report, err = verifier.VerifyAggregate(ctx, aggregateA)
+		require.NoError(t, err)
+		assert.True(t, report.Valid())
+		assert.Equal(t, 3, report.NbEvents)
+	})
+
+	t.Run("tampered event", func(t *testing.T) {
+		events := []eventsourcing.EventInternal{newHashChainTestEvent(aggregateB, 1, `{"Name": "forged"}`)}
+		require.NoError(t, eventsourcing.ChainEvents(lastHashes[aggregateB], events))
+		forged := events[0]
+		forged.EventData = []byte(`{"Name": "altered"}`)
+		require.NoError(t, repo.Save(ctx, true, forged))
+
+		report, err := verifier.VerifyAll(ctx)
+		require.NoError(t, err)
+		require.False(t, report.Valid())
+		assert.Equal(t, forged.EventId, report.FirstBreak.EventId)
+		assert.Equal(t, eventsourcing.HashChainHashMismatch, report.FirstBreak.Reason)
+		assert.Equal(t, int64(5), report.FirstBreak.GlobalPosition)
+	})
+
+	t.Run("missing link", func(t *testing.T) {
+		events := []eventsourcing.EventInternal{newHashChainTestEvent(aggregateA, 3, `{}`)}
+		require.NoError(t, eventsourcing.ChainEvents([]byte("unknown"), events))
+		orphan := events[0]
+		require.NoError(t, repo.Save(ctx, true, orphan))
+
+		report, err := verifier.VerifyAggregate(ctx, aggregateA)
+		require.NoError(t, err)
+		require.False(t, report.Valid())
+		assert.Equal(t, orphan.EventId, report.FirstBreak.EventId)
+		assert.Equal(t, eventsourcing.HashChainPreviousMismatch, report.FirstBreak.Reason)
+	})
+}
+
+// slowSaveRepository delays saves so that concurrent writers read the same last event before saving
+type slowSaveRepository struct {
+	eventsourcing.EventRepository
+}
+
+func (r slowSaveRepository) Save(ctx context.Context, publishOutbox bool, events ...eventsourcing.EventInternal) error {
+	time.Sleep(10 * time.Millisecond)
+	return r.EventRepository.Save(ctx, publishOutbox, events...)
+}
+
+func TestHashChainConcurrentStore(t *testing.T) {
+	ctx := context.Background()
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](slowSaveRepository{repo}, registry, userFactory, false, eventsourcing.EventStoreWithHashChain())
+
+	const nbWriters = 8
+	issuer := &signedUser{id: uuid.New()}
+	aggregateId := uuid.New()
+	var (
+		wg     sync.WaitGroup
+		stored atomic.Int32
+	)
+	for i := 0; i < nbWriters; i++ {
+		wg.Add(1)
+		go func(version int) {
+			defer wg.Done()
+
+			err := store.Store(ctx, &evtSigned{
+				EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, version, "signed", aggregateId, issuer),
+			})
+			if err == nil {
+				stored.Add(1)
+				return
+			}
+			// writers which lost the race fail, they never fork the chain
+			assert.ErrorIs(t, err, eventsourcing.ErrConcurrencyConflict)
+		}(i)
+	}
+	wg.Wait()
+
+	report, err := eventsourcing.NewHashChainVerifier(repo, 0).VerifyAggregate(ctx, aggregateId)
+	require.NoError(t, err)
+	assert.True(t, report.Valid(), report.FirstBreak)
+	assert.Equal(t, int(stored.Load()), report.NbEvents)
+	assert.Positive(t, report.NbEvents)
+}
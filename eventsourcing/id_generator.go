@@ -0,0 +1,91 @@
+package eventsourcing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator provides ids to events and aggregates
+type IDGenerator interface {
+	NewID() uuid.UUID
+}
+
+// UUIDv4Generator generates random ids
+type UUIDv4Generator struct{}
+
+func (UUIDv4Generator) NewID() uuid.UUID {
+	return uuid.New()
+}
+
+// UUIDv7Generator generates time ordered ids as defined by RFC 9562
+// ids generated by the same generator are strictly increasing
+type UUIDv7Generator struct {
+	clock Clock
+	// last is the last generated 48 bits unix milliseconds timestamp followed by 12 bits of sub-millisecond sequence
+	last uint64
+	mtx  sync.Mutex
+}
+
+func NewUUIDv7Generator(clock Clock) *UUIDv7Generator {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+
+	return &UUIDv7Generator{
+		clock: clock,
+	}
+}
+
+func (g *UUIDv7Generator) NewID() uuid.UUID {
+	var id uuid.UUID
+	_, err := rand.Read(id[6:])
+	if err != nil {
+		panic(fmt.Errorf("uuidv7 generator: failed to read random bytes: %w", err))
+	}
+
+	now := g.clock.Now()
+	// sub-millisecond precision is used as the initial sequence (RFC 9562 method 3)
+	current := uint64(now.UnixMilli())<<12 | uint64(now.Nanosecond()%1e6*4096/1e6)
+
+	g.mtx.Lock()
+	if current <= g.last {
+		current = g.last + 1
+	}
+	g.last = current
+	g.mtx.Unlock()
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], current>>12)
+	copy(id[0:6], ts[2:8])
+	id[6] = 0x70 | byte(current>>8&0x0f)
+	id[7] = byte(current)
+	id[8] = 0x80 | id[8]&0x3f
+
+	return id
+}
+
+// SequentialIDGenerator generates predictable ids for tests
+// 00000000-0000-0000-0000-000000000001, 00000000-0000-0000-0000-000000000002, ...
+type SequentialIDGenerator struct {
+	last uint64
+	mtx  sync.Mutex
+}
+
+func NewSequentialIDGenerator() *SequentialIDGenerator {
+	return &SequentialIDGenerator{}
+}
+
+func (g *SequentialIDGenerator) NewID() uuid.UUID {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.last++
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[8:], g.last)
+
+	return id
+}
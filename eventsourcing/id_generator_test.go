@@ -0,0 +1,80 @@
+//go:build unit
+
+package eventsourcing
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDv7Generator(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	generator := NewUUIDv7Generator(clock)
+
+	previous := generator.NewID()
+	assert.Equal(t, uuid.Version(7), previous.Version())
+	assert.Equal(t, uuid.RFC4122, previous.Variant())
+	var ms [8]byte
+	copy(ms[2:], previous[0:6])
+	assert.Equal(t, clock.Now().UnixMilli(), int64(binary.BigEndian.Uint64(ms[:])))
+
+	for i := 0; i < 10000; i++ {
+		if i%100 == 0 {
+			clock.Advance(time.Millisecond)
+		}
+
+		id := generator.NewID()
+		require.Equal(t, uuid.Version(7), id.Version())
+		require.Less(t, previous.String(), id.String())
+		previous = id
+	}
+}
+
+func TestSequentialIDGenerator(t *testing.T) {
+	generator := NewSequentialIDGenerator()
+
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", generator.NewID().String())
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", generator.NewID().String())
+}
+
+type cmdSchemaTest struct {
+	CommandBase[schemaTestAggregate]
+}
+
+func (c cmdSchemaTest) Apply(*schemaTestAggregate) ([]Event[schemaTestAggregate], error) {
+	return []Event[schemaTestAggregate]{
+		&evtSchemaTest{
+			EventBase: NewEventBase[schemaTestAggregate](schemaTestAggregateType, 0, "schema-tested", c.AggregateId(), c.IssuedBy()),
+		},
+	}, nil
+}
+
+func TestCommandHandlerStampEvents(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	handler := NewCommandHandler[schemaTestAggregate](
+		nil,
+		func() *schemaTestAggregate { return &schemaTestAggregate{} },
+		CacheOption{Disabled: true},
+		CommandHandlerWithClock(clock),
+		CommandHandlerWithIDGenerator(NewSequentialIDGenerator()),
+	)
+
+	cmd := cmdSchemaTest{
+		CommandBase: NewCommandBaseWith[schemaTestAggregate](clock, uuid.New(), schemaTestAggregateType, &testUser{id: uuid.New()}),
+	}
+	assert.Equal(t, clock.Now(), cmd.CreatedAt())
+
+	events, err := handler.ApplyCommand(context.Background(), &schemaTestAggregate{}, cmd)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", events[0].Id().String())
+	assert.Equal(t, clock.Now(), events[0].IssuedAt())
+	assert.Equal(t, cmd.AggregateId(), events[0].AggregateId())
+	assert.Equal(t, EventType("schema-tested"), events[0].EventType())
+}
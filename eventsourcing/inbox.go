@@ -0,0 +1,15 @@
+package eventsourcing
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Inbox records the events processed by consumers so that the events delivered again are skipped
+// subscribers get events at least once, through retries or republished aggregates, an inbox makes their effect happen once
+type Inbox interface {
+	// Process calls handle unless the consumer already processed the event, processed is false when the event is skipped
+	// the event is recorded once handle succeeded, it is processed again when handle fails
+	Process(ctx context.Context, consumer string, eventId uuid.UUID, handle func(ctx context.Context) error) (processed bool, err error)
+}
@@ -0,0 +1,27 @@
+package eventsourcing
+
+import (
+	"context"
+	"time"
+)
+
+const defaultOutboxFallbackPollInterval = 30 * time.Second
+
+// OutboxNotifier wakes up publishers when events are waiting in the outbox
+type OutboxNotifier interface {
+	// Listen returns a channel receiving a value when events of the aggregate type, or of any type for AllAggregateTypes, are added to the outbox
+	// notifications may be coalesced or lost, the channel is closed once ctx is done
+	Listen(ctx context.Context, aggregateType AggregateType) (<-chan struct{}, error)
+}
+
+// EventStreamPublisherWithOutboxNotifier makes the publisher wait for outbox notifications instead of polling the repository
+// the outbox is still polled every fallbackPollInterval to publish events which notifications were missed
+func EventStreamPublisherWithOutboxNotifier(notifier OutboxNotifier, fallbackPollInterval time.Duration) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		o.outboxNotifier = notifier
+		o.fallbackPollInterval = fallbackPollInterval
+		if o.fallbackPollInterval <= 0 {
+			o.fallbackPollInterval = defaultOutboxFallbackPollInterval
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// OutboxPublisher publishes the outbox of an aggregate type or of all the types
+// the events of each type are sent to the route registered with RouteEventStream, or to the sink when the type has no route
+type OutboxPublisher struct {
+	eventRepo     EventRepository
+	aggregateType AggregateType
+	deadLetters   DeadLetterRepository
+	options       eventStreamPublisherOptions
+
+	mtx    sync.Mutex
+	routes map[AggregateType]outboxRoute
+	status PublisherStatus
+	// stop is closed by Stop, done is closed once the publisher returned
+	stop     chan struct{}
+	done     chan struct{}
+	drainCtx context.Context
+}
+
+type eventStreamPublisherOptions struct {
+	batchSize            int
+	pollInterval         time.Duration
+	backoff              PublisherBackoff
+	maxRetries           int
+	batchTimeout         time.Duration
+	onError              func(ctx context.Context, err error)
+	unknownEventPolicy   UnknownEventPolicy
+	keys                 KeyRegistry
+	verificationMode     SignatureVerificationMode
+	outboxNotifier       OutboxNotifier
+	fallbackPollInterval time.Duration
+	maxAttempts          int
+	sink                 EventInternalPublisher
+}
+
+// EventStreamPublisherOption configures an EventStreamPublisher or an OutboxPublisher
+type EventStreamPublisherOption func(*eventStreamPublisherOptions)
+
+// EventInternalPublisher publishes events without hydrating them, for bridges forwarding the stored representation
+// as a PublisherV2, it returns an error when the events are not delivered so that they are retried
+type EventInternalPublisher interface {
+	Publish(ctx context.Context, events ...EventInternal) error
+}
+
+// OutboxPublisherWithSink sends the events of the aggregate types without a route to sink
+// without a sink, publishing these events fails and they are dead lettered
+func OutboxPublisherWithSink(sink EventInternalPublisher) EventStreamPublisherOption {
+	return func(o *eventStreamPublisherOptions) {
+		o.sink = sink
+	}
+}
+
+// NewOutboxPublisher returns a publisher polling the outbox of all the aggregate types in a single query
+// routes must be registered with RouteEventStream before it runs
+func NewOutboxPublisher(eventRepo EventRepository, opts ...EventStreamPublisherOption) *OutboxPublisher {
+	return newOutboxPublisher(eventRepo, AllAggregateTypes, eventStreamPublisherOptions{}, opts)
+}
+
+func newOutboxPublisher(eventRepo EventRepository, aggregateType AggregateType, options eventStreamPublisherOptions, opts []EventStreamPublisherOption) *OutboxPublisher {
+	options.pollInterval = DefaultPublisherPollInterval
+	options.batchTimeout = DefaultPublisherBatchTimeout
+	options.maxAttempts = DefaultMaxPublishAttempts
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.batchSize <= 0 {
+		options.batchSize = DefaultPublisherBatchSize
+	}
+
+	p := &OutboxPublisher{
+		eventRepo:     eventRepo,
+		aggregateType: aggregateType,
+		options:       options,
+		routes:        make(map[AggregateType]outboxRoute),
+		status: PublisherStatus{
+			AggregateType: aggregateType,
+			State:         PublisherStopped,
+		},
+	}
+
+	// failures are only counted by repositories supporting dead letters, other repositories retry events forever
+	deadLetters, ok := eventRepo.(DeadLetterRepository)
+	if ok && options.maxAttempts > 0 {
+		p.deadLetters = deadLetters
+	}
+
+	return p
+}
+
+// outboxRoute publishes the events of an aggregate type
+type outboxRoute interface {
+	// publish publishes the events of an aggregate in order until one fails, it returns the number of events published
+	// before the failing one and the number of events sent to the stream, which differs when events are skipped
+	publish(ctx context.Context, events []EventInternal) (int, int, error)
+}
+
+func (p *OutboxPublisher) addRoute(aggregateType AggregateType, route outboxRoute) {
+	if aggregateType == AllAggregateTypes {
+		panic(fmt.Errorf("%w: routes require an aggregate type", ErrInvalidAggregateType))
+	}
+	if p.aggregateType != AllAggregateTypes && p.aggregateType != aggregateType {
+		panic(fmt.Errorf("%w: publisher of %s can not route %s", ErrInvalidAggregateType, p.aggregateType, aggregateType))
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if _, ok := p.routes[aggregateType]; ok {
+		panic(fmt.Errorf("%w: aggregate type %s already routed", ErrDuplicatePublisherRoute, aggregateType))
+	}
+	p.routes[aggregateType] = route
+}
+
+func (p *OutboxPublisher) route(aggregateType AggregateType) (outboxRoute, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	route, ok := p.routes[aggregateType]
+	if ok {
+		return route, nil
+	}
+	if p.options.sink != nil {
+		return sinkRoute{sink: p.options.sink}, nil
+	}
+
+	return nil, fmt.Errorf("%w: no route for aggregate type %s", ErrInvalidAggregateType, aggregateType)
+}
+
+// Run publishes the outbox until ctx is done, Stop is called or MaxRetries consecutive batches failed
+// it returns nil when it is stopped, ErrPublisherMaxRetries when it gives up and ErrOutboxNotifierClosed when the notifier stops notifying
+// it fails with ErrPublisherRunning if the publisher already runs
+func (p *OutboxPublisher) Run(ctx context.Context) error {
+	stop, err := p.begin()
+	if err != nil {
+		return err
+	}
+
+	return p.run(ctx, stop)
+}
+
+// run polls the outbox, without waiting while batches are full, after the poll interval once it is empty and with a backoff after failures
+// when an outbox notifier is used, the outbox is also polled whenever it is notified
+func (p *OutboxPublisher) run(ctx context.Context, stop <-chan struct{}) (err error) {
+	defer func() { p.end(err) }()
+
+	// the notifier stops listening once the publisher returns, Start runs it with a context which is never done
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wakeUps := p.listen(ctx)
+	pollInterval := p.options.pollInterval
+	if wakeUps != nil {
+		pollInterval = p.options.fallbackPollInterval
+	}
+	retryBackOff := p.options.backoff.newBackOff(pollInterval)
+	log.Ctx(ctx).
+		Debug().
+		Str("aggregate_type", string(p.aggregateType)).
+		Int("batch_size", p.options.batchSize).
+		Dur("poll_interval", pollInterval).
+		Bool("backoff", !p.options.backoff.Disabled).
+		Int("max_retries", p.options.maxRetries).
+		Msg("event publisher: started")
+
+	wait := time.NewTimer(0)
+	defer wait.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-stop:
+			return p.drain(ctx)
+		case _, ok := <-wakeUps:
+			if !ok {
+				// the notifier closes the channel once ctx is done
+				if ctx.Err() != nil {
+					return nil
+				}
+				return ErrOutboxNotifierClosed
+			}
+		case <-wait.C:
+		}
+
+		nb, batchErr := p.processBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		failures := p.recordBatch(nb, batchErr)
+		if batchErr != nil {
+			p.reportError(ctx, batchErr)
+		}
+		if p.options.maxRetries > 0 && failures > p.options.maxRetries {
+			return fmt.Errorf("%w: %d consecutive failed batches: %w", ErrPublisherMaxRetries, failures, batchErr)
+		}
+
+		// batches which published events are followed right away by the next one
+		var next time.Duration
+		switch {
+		case failures > 0:
+			next = retryBackOff.NextBackOff()
+		case nb == 0:
+			retryBackOff.Reset()
+			next = pollInterval
+		default:
+			retryBackOff.Reset()
+		}
+
+		resetTimer(wait, next)
+	}
+}
+
+// listen returns the outbox notifications or a nil channel, which never receives, when there is no notifier
+func (p *OutboxPublisher) listen(ctx context.Context) <-chan struct{} {
+	if p.options.outboxNotifier == nil {
+		return nil
+	}
+
+	wakeUps, err := p.options.outboxNotifier.Listen(ctx, p.aggregateType)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("event publisher: failed to listen to outbox notifications, falling back to polling")
+		return nil
+	}
+
+	return wakeUps
+}
+
+// drain publishes batches until the outbox is empty, a batch fails or the context given to Stop is done
+func (p *OutboxPublisher) drain(ctx context.Context) error {
+	drainCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopDrain := context.AfterFunc(p.stopContext(), cancel)
+	defer stopDrain()
+
+	for drainCtx.Err() == nil {
+		nb, err := p.processBatch(drainCtx)
+		p.recordBatch(nb, err)
+		if err != nil {
+			if drainCtx.Err() == nil {
+				p.reportError(ctx, err)
+			}
+			return nil
+		}
+
+		if nb == 0 {
+			break
+		}
+	}
+
+	log.Ctx(ctx).Debug().Str("aggregate_type", string(p.aggregateType)).Msg("event publisher: stopped")
+	return nil
+}
+
+func (p *OutboxPublisher) reportError(ctx context.Context, err error) {
+	log.Ctx(ctx).Error().Err(err).Str("aggregate_type", string(p.aggregateType)).Msg("event publisher: failed to process batch")
+	if p.options.onError != nil {
+		p.options.onError(ctx, err)
+	}
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+// processBatch publishes a batch of unpublished events aggregate by aggregate
+// when the events of an aggregate fail they are released, the other aggregates of the batch are still published
+// the repository does not return later events of an aggregate while earlier ones are unpublished, so a failing aggregate is kept in order
+func (p *OutboxPublisher) processBatch(ctx context.Context) (int, error) {
+	internalEvents, err := p.eventRepo.GetUnpublished(ctx, p.aggregateType, p.options.batchSize)
+	if err != nil {
+		return -1, err
+	}
+
+	if len(internalEvents) == 0 {
+		return 0, nil
+	}
+
+	// the whole batch is published before the lease expires, the events are still marked or released with ctx
+	publishCtx, cancel := context.WithTimeout(ctx, p.options.batchTimeout)
+	defer cancel()
+
+	published := 0
+	var errs []error
+	for _, aggregateEvents := range groupByAggregate(internalEvents) {
+		nb, err := p.publishAggregate(ctx, publishCtx, aggregateEvents)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		published += nb
+	}
+
+	return published, errors.Join(errs...)
+}
+
+// publishAggregate publishes the events of an aggregate with publishCtx until one of them fails
+// the failing event is counted as failed and the following ones are released to be retried after it
+func (p *OutboxPublisher) publishAggregate(ctx context.Context, publishCtx context.Context, internalEvents []EventInternal) (int, error) {
+	route, err := p.route(internalEvents[0].AggregateType)
+	nbPublished, nbSent := 0, 0
+	if err == nil {
+		nbPublished, nbSent, err = route.publish(publishCtx, internalEvents)
+	}
+
+	if nbPublished > 0 {
+		// events which can not be marked are published again once their lease expires
+		markErr := p.eventRepo.MarkAs(ctx, Published, internalEvents[:nbPublished]...)
+		if markErr != nil {
+			return -1, markErr
+		}
+	}
+
+	if err != nil {
+		failure := fmt.Errorf("event publisher: failed to publish event(%s): %w", internalEvents[nbPublished].EventId, err)
+		p.fail(ctx, internalEvents[nbPublished], failure)
+		p.release(ctx, internalEvents[nbPublished+1:])
+		return -1, failure
+	}
+
+	return nbSent, nil
+}
+
+// publishInOrder publishes the events and returns how many of them were published before one failed
+// converted[i] holds the events of the i-th outbox event, it is empty when the event is skipped
+// the events before the one reported by a *PublishError were delivered, other errors fail all the events
+func publishInOrder[E any](ctx context.Context, converted [][]E, id func(E) uuid.UUID, publish func(ctx context.Context, events ...E) error) (int, error) {
+	events := make([]E, 0, len(converted))
+	for _, e := range converted {
+		events = append(events, e...)
+	}
+
+	if len(events) == 0 {
+		return len(converted), nil
+	}
+
+	err := publish(ctx, events...)
+	if err == nil {
+		return len(converted), nil
+	}
+
+	var publishErr *PublishError
+	if !errors.As(err, &publishErr) {
+		return 0, err
+	}
+
+	for i, e := range converted {
+		if slices.ContainsFunc(e, func(event E) bool { return id(event) == publishErr.EventId }) {
+			return i, err
+		}
+	}
+
+	return 0, err
+}
+
+// sinkRoute publishes events as they are stored
+type sinkRoute struct {
+	sink EventInternalPublisher
+}
+
+func (r sinkRoute) publish(ctx context.Context, events []EventInternal) (int, int, error) {
+	converted := make([][]EventInternal, 0, len(events))
+	for _, e := range events {
+		converted = append(converted, []EventInternal{e})
+	}
+
+	nbPublished, err := publishInOrder(ctx, converted, func(e EventInternal) uuid.UUID { return e.EventId }, r.sink.Publish)
+	return nbPublished, nbPublished, err
+}
+
+// fail counts a failed publication of the event, the event is released to be retried when failures are not counted
+func (p *OutboxPublisher) fail(ctx context.Context, event EventInternal, failure error) {
+	if p.deadLetters == nil {
+		p.release(ctx, []EventInternal{event})
+		return
+	}
+
+	deadLettered, err := p.deadLetters.MarkFailed(ctx, event, failure.Error(), p.options.maxAttempts)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("event_id", event.EventId.String()).Msg("event publisher: failed to count publication failure")
+		p.release(ctx, []EventInternal{event})
+		return
+	}
+
+	if deadLettered {
+		log.Ctx(ctx).
+			Error().
+			Err(failure).
+			Str("event_id", event.EventId.String()).
+			Str("aggregate_id", event.AggregateId.String()).
+			Int("max_attempts", p.options.maxAttempts).
+			Msg("event publisher: event dead lettered")
+	}
+}
+
+// release gives back the lease of events which could not be published so that they are retried without waiting for the lease to expire
+func (p *OutboxPublisher) release(ctx context.Context, events []EventInternal) {
+	if len(events) == 0 {
+		return
+	}
+
+	err := p.eventRepo.MarkAs(ctx, Unpublished, events...)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("event publisher: failed to release unpublished events")
+	}
+}
+
+// groupByAggregate splits events by aggregate, keeping the order of the events and of the aggregates first appearance
+func groupByAggregate(events []EventInternal) [][]EventInternal {
+	groups := make([][]EventInternal, 0)
+	indexes := make(map[uuid.UUID]int)
+	for _, e := range events {
+		i, ok := indexes[e.AggregateId]
+		if !ok {
+			i = len(groups)
+			indexes[e.AggregateId] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], e)
+	}
+
+	return groups
+}
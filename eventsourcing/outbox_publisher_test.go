@@ -0,0 +1,103 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mtx    sync.Mutex
+	events []eventsourcing.EventInternal
+}
+
+func (s *recordingSink) Publish(_ context.Context, events ...eventsourcing.EventInternal) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *recordingSink) count() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.events)
+}
+
+func TestOutboxPublisher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[signedAggregate]()
+	eventsourcing.MustRegister[signedAggregate](registry, "signed", func() eventsourcing.Event[signedAggregate] {
+		return &evtSigned{EventBase: &eventsourcing.EventBase[signedAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &signedUser{} }
+	store := eventsourcing.NewEventStore[signedAggregate](repo, registry, userFactory, true)
+
+	require.NoError(t, store.Store(ctx, newPublisherTestEvents(t, 3)...))
+	aggregateId := uuid.New()
+	require.NoError(t, repo.Save(ctx, true,
+		newHashChainTestEvent(aggregateId, 1, `{}`),
+		newHashChainTestEvent(aggregateId, 2, `{}`),
+	))
+
+	stream := &recordingPublisher[signedAggregate]{}
+	sink := &recordingSink{}
+	publisher := eventsourcing.NewOutboxPublisher(repo, eventsourcing.OutboxPublisherWithSink(sink))
+	eventsourcing.RouteEventStream[signedAggregate](publisher, signedAggregateType, registry, userFactory, stream)
+	assert.Panics(t, func() {
+		eventsourcing.RouteEventStream[signedAggregate](publisher, signedAggregateType, registry, userFactory, stream)
+	})
+
+	require.NoError(t, publisher.Start(ctx))
+	assert.Eventually(t, func() bool { return stream.count() == 3 && sink.count() == 2 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, publisher.Stop(ctx))
+
+	sink.mtx.Lock()
+	defer sink.mtx.Unlock()
+	assert.Equal(t, 1, sink.events[0].AggregateVersion)
+	assert.Equal(t, 2, sink.events[1].AggregateVersion)
+	assert.Equal(t, 5, publisher.Status().Published)
+
+	unpublished, err := repo.GetUnpublished(ctx, eventsourcing.AllAggregateTypes, 10)
+	require.NoError(t, err)
+	assert.Empty(t, unpublished)
+}
+
+func TestOutboxPublisherUnroutedEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	deadLetters, ok := repo.(eventsourcing.DeadLetterRepository)
+	require.True(t, ok)
+	unrouted := newHashChainTestEvent(uuid.New(), 1, `{}`)
+	require.NoError(t, repo.Save(ctx, true, unrouted))
+
+	publisher := eventsourcing.NewOutboxPublisher(
+		repo,
+		eventsourcing.EventStreamPublisherWithMaxAttempts(1),
+		eventsourcing.EventStreamPublisherWithOptions(eventsourcing.PublisherOptions{PollInterval: 10 * time.Millisecond}),
+	)
+	require.NoError(t, publisher.Start(ctx))
+	defer func() { _ = publisher.Stop(ctx) }()
+
+	// events without a route nor a sink can not be published
+	assert.Eventually(t, func() bool {
+		deadLetter, err := deadLetters.GetDeadLetter(ctx, unrouted.EventId)
+		return err == nil && deadLetter.Attempts == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,95 @@
+package eventsourcing
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventHeader is the part of an event known without its aggregate type, events can be asserted to Event[T] to read their payload
+type EventHeader interface {
+	Id() uuid.UUID
+	AggregateId() uuid.UUID
+	AggregateType() AggregateType
+	EventType() EventType
+	IssuedAt() time.Time
+	AggregateVersion() int
+}
+
+type SubscribeOption func(*SubscriptionFilter)
+
+// SubscribeToEventTypes delivers only the events of the given types, it can be given several times to extend the set
+// without event types it does not filter the events, as when it is not given
+func SubscribeToEventTypes(eventTypes ...EventType) SubscribeOption {
+	return func(f *SubscriptionFilter) {
+		if len(eventTypes) == 0 {
+			return
+		}
+		if f.eventTypes == nil {
+			f.eventTypes = make(map[EventType]struct{}, len(eventTypes))
+		}
+		for _, eventType := range eventTypes {
+			f.eventTypes[eventType] = struct{}{}
+		}
+	}
+}
+
+// SubscribeWithPredicate delivers only the events matching predicate, all the predicates must match
+func SubscribeWithPredicate(predicate func(e EventHeader) bool) SubscribeOption {
+	return func(f *SubscriptionFilter) {
+		f.predicates = append(f.predicates, predicate)
+	}
+}
+
+// SubscriptionFilter is applied by streams before delivering an event to a subscriber
+// brokers able to filter server side can push the event types down and apply Match to the events they receive
+type SubscriptionFilter struct {
+	eventTypes map[EventType]struct{}
+	predicates []func(e EventHeader) bool
+}
+
+func NewSubscriptionFilter(opts ...SubscribeOption) SubscriptionFilter {
+	var f SubscriptionFilter
+	for _, opt := range opts {
+		opt(&f)
+	}
+
+	return f
+}
+
+// EventTypes returns the sorted event types to deliver, nil when all types are delivered
+func (f SubscriptionFilter) EventTypes() []EventType {
+	if f.eventTypes == nil {
+		return nil
+	}
+
+	eventTypes := make([]EventType, 0, len(f.eventTypes))
+	for eventType := range f.eventTypes {
+		eventTypes = append(eventTypes, eventType)
+	}
+	slices.Sort(eventTypes)
+
+	return eventTypes
+}
+
+// HasPredicates tells if events must be matched by the stream, even when their types are filtered by a broker
+func (f SubscriptionFilter) HasPredicates() bool {
+	return len(f.predicates) > 0
+}
+
+func (f SubscriptionFilter) Match(e EventHeader) bool {
+	if f.eventTypes != nil {
+		if _, ok := f.eventTypes[e.EventType()]; !ok {
+			return false
+		}
+	}
+
+	for _, predicate := range f.predicates {
+		if !predicate(e) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+//go:build unit
+
+package eventsourcing_test
+
+import (
+	"testing"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionFilter(t *testing.T) {
+	issuer := &signedUser{id: uuid.New()}
+	created := &evtSigned{EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 1, "created", uuid.New(), issuer)}
+	updated := &evtSigned{EventBase: eventsourcing.NewEventBase[signedAggregate](signedAggregateType, 2, "updated", uuid.New(), issuer)}
+
+	t.Run("match all", func(t *testing.T) {
+		filter := eventsourcing.NewSubscriptionFilter()
+		assert.Nil(t, filter.EventTypes())
+		assert.False(t, filter.HasPredicates())
+		assert.True(t, filter.Match(created))
+	})
+
+	t.Run("event types", func(t *testing.T) {
+		filter := eventsourcing.NewSubscriptionFilter(
+			eventsourcing.SubscribeToEventTypes("updated"),
+			eventsourcing.SubscribeToEventTypes("deleted"),
+		)
+		assert.Equal(t, []eventsourcing.EventType{"deleted", "updated"}, filter.EventTypes())
+		assert.False(t, filter.Match(created))
+		assert.True(t, filter.Match(updated))
+	})
+
+	t.Run("no event types match all", func(t *testing.T) {
+		filter := eventsourcing.NewSubscriptionFilter(eventsourcing.SubscribeToEventTypes())
+		assert.Nil(t, filter.EventTypes())
+		assert.True(t, filter.Match(created))
+
+		filter = eventsourcing.NewSubscriptionFilter(
+			eventsourcing.SubscribeToEventTypes("updated"),
+			eventsourcing.SubscribeToEventTypes(),
+		)
+		assert.Equal(t, []eventsourcing.EventType{"updated"}, filter.EventTypes())
+		assert.False(t, filter.Match(created))
+	})
+
+	t.Run("predicates must all match", func(t *testing.T) {
+		filter := eventsourcing.NewSubscriptionFilter(
+			eventsourcing.SubscribeWithPredicate(func(e eventsourcing.EventHeader) bool { return e.AggregateVersion() > 1 }),
+			eventsourcing.SubscribeWithPredicate(func(e eventsourcing.EventHeader) bool {
+				_, ok := e.(eventsourcing.Event[signedAggregate])
+				return ok
+			}),
+		)
+		assert.True(t, filter.HasPredicates())
+		assert.False(t, filter.Match(created))
+		assert.True(t, filter.Match(updated))
+	})
+}
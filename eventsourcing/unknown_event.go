@@ -0,0 +1,65 @@
+package eventsourcing
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UnknownEventPolicy defines how events of an unregistered type are handled on hydration
+type UnknownEventPolicy int
+
+const (
+	// UnknownEventStrict fails hydration when an event type is not registered
+	UnknownEventStrict UnknownEventPolicy = iota
+	// UnknownEventSkip logs and drops events which type is not registered
+	// aggregates loaded by the event store still process them as an UnknownEvent to keep their version
+	UnknownEventSkip
+	// UnknownEventTombstone replaces events which type is not registered by an UnknownEvent
+	UnknownEventTombstone
+)
+
+func (p UnknownEventPolicy) String() string {
+	switch p {
+	case UnknownEventStrict:
+		return "strict"
+	case UnknownEventSkip:
+		return "skip"
+	case UnknownEventTombstone:
+		return "tombstone"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// UnknownEvent is a placeholder for an event which type is not registered
+// it keeps the raw payload of the event and only moves the version of the aggregate when applied
+// so that the next events emitted by the aggregate follow the stored stream
+type UnknownEvent[T Aggregate] struct {
+	*EventBase[T]
+	Data json.RawMessage
+}
+
+func NewUnknownEvent[T Aggregate](base EventBase[T], data []byte) *UnknownEvent[T] {
+	return &UnknownEvent[T]{
+		EventBase: &base,
+		Data:      data,
+	}
+}
+
+// Apply processes the event on aggregates embedding AggregateBase without changing their state
+func (e UnknownEvent[T]) Apply(aggregate *T) error {
+	if p, ok := any(aggregate).(interface{ Process(Event[T]) }); ok {
+		p.Process(e)
+	}
+
+	return nil
+}
+
+// MarshalJSON returns the raw payload so that the event is stored back untouched
+func (e UnknownEvent[T]) MarshalJSON() ([]byte, error) {
+	if len(e.Data) == 0 {
+		return []byte("null"), nil
+	}
+
+	return e.Data, nil
+}
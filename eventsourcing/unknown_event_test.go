@@ -0,0 +1,114 @@
+//go:build unit
+
+package eventsourcing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	id uuid.UUID
+}
+
+func newTestUser() User {
+	return &testUser{}
+}
+
+func (u testUser) Id() uuid.UUID {
+	return u.id
+}
+
+func (u testUser) String() string {
+	return u.id.String()
+}
+
+func (u *testUser) FromString(s string) error {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return err
+	}
+	u.id = id
+
+	return nil
+}
+
+func TestFromEventInternalSliceWithPolicy(t *testing.T) {
+	registry := NewEventRegistry[schemaTestAggregate]()
+	MustRegister[schemaTestAggregate](registry, "known", func() Event[schemaTestAggregate] {
+		return &evtSchemaTest{EventBase: &EventBase[schemaTestAggregate]{}}
+	})
+
+	aggregateId := uuid.New()
+	internalEvents := []EventInternal{
+		{
+			EventId:          uuid.New(),
+			EventIssuedAt:    time.Now().UTC(),
+			EventIssuedBy:    uuid.New().String(),
+			EventType:        "known",
+			EventData:        []byte(`{"Name": "john"}`),
+			AggregateId:      aggregateId,
+			AggregateType:    schemaTestAggregateType,
+			AggregateVersion: 0,
+		},
+		{
+			EventId:          uuid.New(),
+			EventIssuedAt:    time.Now().UTC(),
+			EventIssuedBy:    uuid.New().String(),
+			EventType:        "removed",
+			EventData:        []byte(`{"Value": 42}`),
+			AggregateId:      aggregateId,
+			AggregateType:    schemaTestAggregateType,
+			AggregateVersion: 1,
+		},
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := FromEventInternalSliceWithPolicy(internalEvents, registry, newTestUser, UnknownEventStrict)
+		assert.ErrorIs(t, err, ErrUnknownEventType)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		events, err := FromEventInternalSliceWithPolicy(internalEvents, registry, newTestUser, UnknownEventSkip)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, EventType("known"), events[0].EventType())
+	})
+
+	t.Run("tombstone", func(t *testing.T) {
+		events, err := FromEventInternalSliceWithPolicy(internalEvents, registry, newTestUser, UnknownEventTombstone)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+
+		unknown, ok := events[1].(*UnknownEvent[schemaTestAggregate])
+		require.True(t, ok)
+		assert.Equal(t, EventType("removed"), unknown.EventType())
+		assert.Equal(t, 1, unknown.AggregateVersion())
+
+		aggregate := &schemaTestAggregate{AggregateBase: NewAggregateBase[schemaTestAggregate](aggregateId, 0)}
+		assert.NoError(t, unknown.Apply(aggregate))
+		assert.Equal(t, 1, aggregate.AggregateVersion())
+
+		data, err := json.Marshal(unknown)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"Value": 42}`, string(data))
+	})
+}
+
+func TestRegisterDuplicateEventType(t *testing.T) {
+	registry := NewEventRegistry[schemaTestAggregate]()
+	factory := func() Event[schemaTestAggregate] {
+		return &evtSchemaTest{EventBase: &EventBase[schemaTestAggregate]{}}
+	}
+	require.NoError(t, registry.Register("known", factory))
+
+	assert.ErrorIs(t, registry.Register("known", factory), ErrDuplicateEventType)
+	assert.PanicsWithError(t, "duplicate event type: event type known already registered", func() {
+		MustRegister[schemaTestAggregate](registry, "known", factory)
+	})
+}
@@ -0,0 +1,87 @@
+package eventsourcing
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is an endpoint receiving the events as signed JSON requests
+type Webhook struct {
+	Id  uuid.UUID
+	Url string
+	// Secret is the HMAC-SHA256 key signing the deliveries
+	Secret string
+	// EventTypes are the types delivered to the endpoint, every type is delivered when empty
+	EventTypes []EventType
+	// Paused webhooks do not receive events, the events published meanwhile are not delivered when resumed
+	Paused    bool
+	CreatedAt time.Time
+}
+
+// Accepts tells if events of the type are delivered to the webhook
+func (w Webhook) Accepts(eventType EventType) bool {
+	return len(w.EventTypes) == 0 || slices.Contains(w.EventTypes, eventType)
+}
+
+// WebhookDelivery is an attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	Id        uuid.UUID
+	WebhookId uuid.UUID
+	EventId   uuid.UUID
+	EventType EventType
+	Attempt   int
+	// StatusCode is the status of the response, 0 when no response was received
+	StatusCode int
+	// Error is empty when the endpoint acknowledged the event
+	Error       string
+	Duration    time.Duration
+	DeliveredAt time.Time
+}
+
+func (d WebhookDelivery) Succeeded() bool {
+	return d.Error == ""
+}
+
+// WebhookPendingDelivery is an event waiting to be delivered to a webhook, the events of a webhook are delivered in the order they were queued
+type WebhookPendingDelivery struct {
+	WebhookId uuid.UUID
+	EventId   uuid.UUID
+	EventType EventType
+	// Payload is the body of the delivery
+	Payload   []byte
+	CreatedAt time.Time
+	// Attempts counts the failed attempts, the delivery and the next ones are held once the publisher gave up until it is retried or dropped
+	Attempts int
+	// NextAttemptAt is the time from which the delivery is attempted again, it is zero before the first attempt
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// WebhookRepository stores the webhooks and the log of their deliveries, and the deliveries pending per webhook
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook Webhook) error
+	// ListWebhooks lists the webhooks by creation date
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	// GetWebhook returns ErrWebhookNotFound when the webhook does not exist
+	GetWebhook(ctx context.Context, id uuid.UUID) (Webhook, error)
+	SetWebhookPaused(ctx context.Context, id uuid.UUID, paused bool) error
+	// DeleteWebhook deletes the webhook, its deliveries and its pending deliveries
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	LogDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// ListDeliveries lists the latest deliveries of a webhook first
+	ListDeliveries(ctx context.Context, webhookId uuid.UUID, limit int) ([]WebhookDelivery, error)
+	// AddPendingDeliveries queues the deliveries behind those already pending for their webhook, the deliveries already pending are ignored
+	AddPendingDeliveries(ctx context.Context, deliveries []WebhookPendingDelivery) error
+	// ListPendingDeliveries lists the pending deliveries of a webhook in the order they were queued
+	ListPendingDeliveries(ctx context.Context, webhookId uuid.UUID, limit int) ([]WebhookPendingDelivery, error)
+	// GetPendingDelivery returns ErrWebhookDeliveryNotFound when the event is not pending for the webhook
+	GetPendingDelivery(ctx context.Context, webhookId uuid.UUID, eventId uuid.UUID) (WebhookPendingDelivery, error)
+	// UpdatePendingDelivery records the attempts, the next attempt and the last error of a pending delivery
+	// it returns ErrWebhookDeliveryNotFound when the delivery is not pending
+	UpdatePendingDelivery(ctx context.Context, delivery WebhookPendingDelivery) error
+	// DeletePendingDelivery removes a delivery once the webhook acknowledged or rejected it
+	DeletePendingDelivery(ctx context.Context, webhookId uuid.UUID, eventId uuid.UUID) error
+}
@@ -38,6 +38,9 @@ func NewMigrations() *Migrations {
 }
 
 func (m *Migrations) Append(key string, path string, lfs embed.FS) {
+	if m.localFS == nil {
+		m.localFS = map[string]iMigration{}
+	}
 	m.localFS[key] = iMigration{
 		FS:   lfs,
 		path: path,
@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+DROP INDEX IF EXISTS events_global_position_idx;
+ALTER TABLE events DROP COLUMN IF EXISTS global_position;
@@ -0,0 +1,5 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS global_position BIGSERIAL NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS events_global_position_idx ON events (global_position);
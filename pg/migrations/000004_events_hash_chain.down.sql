@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events DROP COLUMN IF EXISTS previous_event_hash;
+ALTER TABLE events DROP COLUMN IF EXISTS event_hash;
@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS event_hash BYTEA;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS previous_event_hash BYTEA;
@@ -0,0 +1,3 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events DROP COLUMN IF EXISTS event_signature;
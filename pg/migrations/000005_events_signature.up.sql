@@ -0,0 +1,3 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS event_signature BYTEA;
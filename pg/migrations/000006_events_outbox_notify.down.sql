@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+DROP TRIGGER IF EXISTS events_outbox_notify ON events_outbox;
+DROP FUNCTION IF EXISTS events_outbox_notify();
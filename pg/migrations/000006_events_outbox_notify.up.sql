@@ -0,0 +1,17 @@
+SET SCHEMA 'eventstore';
+
+-- publishers LISTEN on events_outbox and are woken up when events are waiting to be published
+-- notifications with the same payload are sent once per transaction
+CREATE OR REPLACE FUNCTION events_outbox_notify() RETURNS TRIGGER AS $$
+BEGIN
+  IF NOT NEW.published THEN
+    PERFORM pg_notify('events_outbox', NEW.aggregate_type);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS events_outbox_notify ON events_outbox;
+CREATE TRIGGER events_outbox_notify
+  AFTER INSERT OR UPDATE OF published ON events_outbox
+  FOR EACH ROW EXECUTE FUNCTION events_outbox_notify();
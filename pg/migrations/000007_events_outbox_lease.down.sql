@@ -0,0 +1,3 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events_outbox DROP COLUMN IF EXISTS leased_until;
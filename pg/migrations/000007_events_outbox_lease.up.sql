@@ -0,0 +1,5 @@
+SET SCHEMA 'eventstore';
+
+-- unpublished events are leased to the publisher which loaded them until leased_until
+-- the notify trigger only fires on published updates, taking a lease does not wake up publishers
+ALTER TABLE events_outbox ADD COLUMN IF NOT EXISTS leased_until TIMESTAMPTZ;
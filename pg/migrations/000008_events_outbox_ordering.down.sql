@@ -0,0 +1,5 @@
+SET SCHEMA 'eventstore';
+
+DROP INDEX IF EXISTS events_aggregate_id_global_position_idx;
+DROP INDEX IF EXISTS idx_events_outbox_unpublished_aggregate_type;
+CREATE INDEX IF NOT EXISTS idx_events_outbox_aggregate_version ON events_outbox (aggregate_version);
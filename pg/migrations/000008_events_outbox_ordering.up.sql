@@ -0,0 +1,7 @@
+SET SCHEMA 'eventstore';
+
+-- the outbox is published by global position, the aggregate version is not used to order it anymore
+DROP INDEX IF EXISTS idx_events_outbox_aggregate_version;
+CREATE INDEX IF NOT EXISTS idx_events_outbox_unpublished_aggregate_type ON events_outbox (aggregate_type) WHERE published = FALSE;
+-- earlier events of an aggregate are looked up before leasing an event
+CREATE INDEX IF NOT EXISTS events_aggregate_id_global_position_idx ON events (aggregate_id, global_position);
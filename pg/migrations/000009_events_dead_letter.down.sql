@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+DROP TABLE IF EXISTS events_dead_letter;
+ALTER TABLE events_outbox DROP COLUMN IF EXISTS failed_attempts;
@@ -0,0 +1,15 @@
+SET SCHEMA 'eventstore';
+
+-- failed publications of outbox events, events failing too many times are moved to events_dead_letter
+ALTER TABLE events_outbox ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
+
+-- dead lettered events stay unpublished in events_outbox and hold back the later events of their aggregate
+CREATE TABLE IF NOT EXISTS events_dead_letter (
+  event_id UUID PRIMARY KEY REFERENCES events_outbox (event_id),
+  aggregate_type VARCHAR(255) NOT NULL,
+  attempts INT NOT NULL,
+  reason TEXT NOT NULL,
+  dead_lettered_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_dead_letter_aggregate_type ON events_dead_letter (aggregate_type);
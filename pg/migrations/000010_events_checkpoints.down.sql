@@ -0,0 +1,3 @@
+SET SCHEMA 'eventstore';
+
+DROP TABLE IF EXISTS events_checkpoints;
@@ -0,0 +1,8 @@
+SET SCHEMA 'eventstore';
+
+-- global position of the last event handled by each catch-up subscription
+CREATE TABLE IF NOT EXISTS events_checkpoints (
+  name VARCHAR(255) PRIMARY KEY,
+  position BIGINT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
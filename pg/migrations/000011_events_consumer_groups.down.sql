@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+DROP TABLE IF EXISTS events_consumer_group_partitions;
+DROP TABLE IF EXISTS events_consumer_group_members;
@@ -0,0 +1,18 @@
+SET SCHEMA 'eventstore';
+
+-- live members of the consumer groups, a member is removed once its lease expired
+CREATE TABLE IF NOT EXISTS events_consumer_group_members (
+  group_name VARCHAR(255) NOT NULL,
+  member_id VARCHAR(255) NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (group_name, member_id)
+);
+
+-- partitions of the consumer groups and the member leasing them, their checkpoints are stored in events_checkpoints
+CREATE TABLE IF NOT EXISTS events_consumer_group_partitions (
+  group_name VARCHAR(255) NOT NULL,
+  partition_id INT NOT NULL,
+  member_id VARCHAR(255),
+  leased_until TIMESTAMPTZ,
+  PRIMARY KEY (group_name, partition_id)
+);
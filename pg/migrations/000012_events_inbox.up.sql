@@ -0,0 +1,11 @@
+SET SCHEMA 'eventstore';
+
+-- events processed by each consumer, the events delivered again are skipped
+CREATE TABLE IF NOT EXISTS events_inbox (
+  consumer VARCHAR(255) NOT NULL,
+  event_id UUID NOT NULL,
+  processed_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (consumer, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_inbox_processed_at ON events_inbox (processed_at);
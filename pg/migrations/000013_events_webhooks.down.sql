@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+DROP TABLE IF EXISTS events_webhook_deliveries;
+DROP TABLE IF EXISTS events_webhooks;
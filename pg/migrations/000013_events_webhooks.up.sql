@@ -0,0 +1,26 @@
+SET SCHEMA 'eventstore';
+
+-- endpoints receiving the events, event_types is a JSON array of the delivered types, every type is delivered when empty
+CREATE TABLE IF NOT EXISTS events_webhooks (
+  webhook_id UUID PRIMARY KEY,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  event_types JSONB NOT NULL DEFAULT '[]',
+  paused BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+-- every attempt to deliver an event to a webhook, error is empty when the endpoint acknowledged the event
+CREATE TABLE IF NOT EXISTS events_webhook_deliveries (
+  delivery_id UUID PRIMARY KEY,
+  webhook_id UUID NOT NULL REFERENCES events_webhooks (webhook_id) ON DELETE CASCADE,
+  event_id UUID NOT NULL,
+  event_type VARCHAR(255) NOT NULL,
+  attempt INT NOT NULL,
+  status_code INT NOT NULL,
+  error TEXT NOT NULL,
+  duration_ms BIGINT NOT NULL,
+  delivered_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_webhook_deliveries_webhook_id ON events_webhook_deliveries (webhook_id, delivered_at DESC);
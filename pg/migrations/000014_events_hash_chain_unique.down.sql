@@ -0,0 +1,3 @@
+SET SCHEMA 'eventstore';
+
+DROP INDEX IF EXISTS events_hash_chain_idx;
@@ -0,0 +1,4 @@
+SET SCHEMA 'eventstore';
+
+-- two chained events of an aggregate can not follow the same event, the first event of a chain has no previous hash
+CREATE UNIQUE INDEX IF NOT EXISTS events_hash_chain_idx ON events (aggregate_id, COALESCE(previous_event_hash, ''::BYTEA)) WHERE event_hash IS NOT NULL;
@@ -0,0 +1,14 @@
+SET SCHEMA 'eventstore';
+
+-- the events waiting to be delivered to a webhook, delivered in the order of pending_id before the next events of the webhook
+CREATE TABLE IF NOT EXISTS events_webhook_pending (
+  pending_id BIGSERIAL PRIMARY KEY,
+  webhook_id UUID NOT NULL REFERENCES events_webhooks (webhook_id) ON DELETE CASCADE,
+  event_id UUID NOT NULL,
+  event_type VARCHAR(255) NOT NULL,
+  payload BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_webhook_pending_webhook_id ON events_webhook_pending (webhook_id, pending_id);
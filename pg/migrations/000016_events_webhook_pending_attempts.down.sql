@@ -0,0 +1,6 @@
+SET SCHEMA 'eventstore';
+
+ALTER TABLE events_webhook_pending
+  DROP COLUMN IF EXISTS attempts,
+  DROP COLUMN IF EXISTS next_attempt_at,
+  DROP COLUMN IF EXISTS last_error;
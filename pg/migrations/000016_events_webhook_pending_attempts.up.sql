@@ -0,0 +1,7 @@
+SET SCHEMA 'eventstore';
+
+-- the failed attempts of a pending delivery, it is not attempted again before next_attempt_at
+ALTER TABLE events_webhook_pending
+  ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
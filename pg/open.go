@@ -22,6 +22,20 @@ type DBConfig struct {
 
 // DSN "user=gorm password=gorm dbname=gorm port=9920 sslmode=disable TimeZone=Asia/Shanghai"
 func Open(cfg DBConfig) (*gorm.DB, error) {
+	sqlDB, err := OpenDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return gorm.Open(
+		postgres.New(postgres.Config{
+			Conn: sqlDB,
+		}),
+		&gorm.Config{})
+}
+
+// OpenDB opens a database/sql connection pool backed by the pgx driver
+func OpenDB(cfg DBConfig) (*sql.DB, error) {
 	sqlDB, err := sql.Open(postgresDriverName, string(cfg.ConnString))
 	if err != nil {
 		return nil, err
@@ -32,9 +46,5 @@ func Open(cfg DBConfig) (*gorm.DB, error) {
 	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	return gorm.Open(
-		postgres.New(postgres.Config{
-			Conn: sqlDB,
-		}),
-		&gorm.Config{})
+	return sqlDB, nil
 }
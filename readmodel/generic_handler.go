@@ -1,6 +1,7 @@
 package readmodel
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/davidterranova/cqrs/eventsourcing"
@@ -19,9 +20,22 @@ type GenericHandler[T eventsourcing.Aggregate] struct {
 	aggFactory     eventsourcing.AggregateFactory[T]
 	evtTypeCreated eventsourcing.EventType
 	evtTypeDeleted eventsourcing.EventType
-	createFn       FnCreateRMAggregate[T]
-	updateFn       FnUpdateRMAggregate[T]
-	deleteFn       FnDeleteRMAggregate[T]
+	createFn       FnCreateRMAggregateContext[T]
+	updateFn       FnUpdateRMAggregateContext[T]
+	deleteFn       FnDeleteRMAggregateContext[T]
+	inbox          eventsourcing.Inbox
+	consumer       string
+}
+
+type GenericHandlerOption[T eventsourcing.Aggregate] func(*GenericHandler[T])
+
+// GenericHandlerWithInbox skips the events already applied to the read model, consumer identifies the read model in the inbox
+// an event is recorded once applied, it is applied again if the handler stops in between
+func GenericHandlerWithInbox[T eventsourcing.Aggregate](inbox eventsourcing.Inbox, consumer string) GenericHandlerOption[T] {
+	return func(gh *GenericHandler[T]) {
+		gh.inbox = inbox
+		gh.consumer = consumer
+	}
 }
 
 // FnCreateRMAggregate is a function that delegates the creation a read model aggregate
@@ -33,6 +47,16 @@ type FnUpdateRMAggregate[T eventsourcing.Aggregate] func(id uuid.UUID, fnRepo fu
 // FnDeleteRMAggregate is a function that delegates the deletion of a read model aggregate
 type FnDeleteRMAggregate[T eventsourcing.Aggregate] func(id uuid.UUID) error
 
+// FnCreateRMAggregateContext is a FnCreateRMAggregate receiving the context of the handled event
+// with an inbox, it holds the transaction of the inbox (see eventrepository.InboxTx)
+type FnCreateRMAggregateContext[T eventsourcing.Aggregate] func(ctx context.Context, a *T) error
+
+// FnUpdateRMAggregateContext is a FnUpdateRMAggregate receiving the context of the handled event
+type FnUpdateRMAggregateContext[T eventsourcing.Aggregate] func(ctx context.Context, id uuid.UUID, fnRepo func(a T) (T, error)) error
+
+// FnDeleteRMAggregateContext is a FnDeleteRMAggregate receiving the context of the handled event
+type FnDeleteRMAggregateContext[T eventsourcing.Aggregate] func(ctx context.Context, id uuid.UUID) error
+
 func NewGenericHandler[T eventsourcing.Aggregate](
 	aggFactory eventsourcing.AggregateFactory[T],
 	evtTypeCreated eventsourcing.EventType,
@@ -41,6 +65,31 @@ func NewGenericHandler[T eventsourcing.Aggregate](
 	updateFn FnUpdateRMAggregate[T],
 	deleteFn FnDeleteRMAggregate[T],
 	eventStream eventsourcing.Subscriber[T],
+	opts ...GenericHandlerOption[T],
+) *GenericHandler[T] {
+	return NewGenericHandlerContext[T](
+		aggFactory,
+		evtTypeCreated,
+		evtTypeDeleted,
+		func(_ context.Context, a *T) error { return createFn(a) },
+		func(_ context.Context, id uuid.UUID, fnRepo func(a T) (T, error)) error { return updateFn(id, fnRepo) },
+		func(_ context.Context, id uuid.UUID) error { return deleteFn(id) },
+		eventStream,
+		opts...,
+	)
+}
+
+// NewGenericHandlerContext returns a GenericHandler whose functions receive the context of the handled event
+// read models stored in the database of their inbox use it to update the read model in the transaction recording the event
+func NewGenericHandlerContext[T eventsourcing.Aggregate](
+	aggFactory eventsourcing.AggregateFactory[T],
+	evtTypeCreated eventsourcing.EventType,
+	evtTypeDeleted eventsourcing.EventType,
+	createFn FnCreateRMAggregateContext[T],
+	updateFn FnUpdateRMAggregateContext[T],
+	deleteFn FnDeleteRMAggregateContext[T],
+	eventStream eventsourcing.Subscriber[T],
+	opts ...GenericHandlerOption[T],
 ) *GenericHandler[T] {
 	gh := &GenericHandler[T]{
 		aggFactory:     aggFactory,
@@ -50,6 +99,9 @@ func NewGenericHandler[T eventsourcing.Aggregate](
 		updateFn:       updateFn,
 		deleteFn:       deleteFn,
 	}
+	for _, opt := range opts {
+		opt(gh)
+	}
 
 	if eventStream != nil {
 		eventStream.Subscribe(gh.HandleEvent)
@@ -58,23 +110,60 @@ func NewGenericHandler[T eventsourcing.Aggregate](
 	return gh
 }
 
+// HandleEvent handles events of a Subscriber, errors are logged as the subscriber can not report them
 func (rm GenericHandler[T]) HandleEvent(e eventsourcing.Event[T]) {
-	var err error
+	err := rm.Handle(context.Background(), e)
+	if err != nil {
+		logHandleError(err, e)
+	}
+}
 
+// HandleMessage handles messages of a SubscriberV2, messages which fail are negatively acknowledged to be delivered again
+func (rm GenericHandler[T]) HandleMessage(ctx context.Context, msg eventsourcing.Message[T]) {
+	err := rm.Handle(ctx, msg.Event())
+	if err != nil {
+		logHandleError(err, msg.Event())
+		msg.Nack(err)
+		return
+	}
+
+	msg.Ack()
+}
+
+// Handle applies the event to the read model, once per event when the handler has an inbox
+func (rm GenericHandler[T]) Handle(ctx context.Context, e eventsourcing.Event[T]) error {
+	if rm.inbox == nil {
+		return rm.apply(ctx, e)
+	}
+
+	processed, err := rm.inbox.Process(ctx, rm.consumer, e.Id(), func(ctx context.Context) error { return rm.apply(ctx, e) })
+	if err != nil {
+		return err
+	}
+	if !processed {
+		log.Ctx(ctx).Debug().
+			Str("event_id", e.Id().String()).
+			Str("consumer", rm.consumer).
+			Msg("read model: skipping event already processed")
+	}
+
+	return nil
+}
+
+func (rm GenericHandler[T]) apply(ctx context.Context, e eventsourcing.Event[T]) error {
 	switch e.EventType() {
 	case rm.evtTypeCreated:
 		agg := rm.aggFactory()
-		err = e.Apply(agg)
+		err := e.Apply(agg)
 		if err != nil {
-			err = fmt.Errorf("error applying event: %w", err)
-			break
+			return fmt.Errorf("error applying event: %w", err)
 		}
 
-		err = rm.createFn(agg)
+		return rm.createFn(ctx, agg)
 	case rm.evtTypeDeleted:
-		err = rm.deleteFn(e.AggregateId())
+		return rm.deleteFn(ctx, e.AggregateId())
 	default:
-		err = rm.updateFn(e.AggregateId(), func(agg T) (T, error) {
+		return rm.updateFn(ctx, e.AggregateId(), func(agg T) (T, error) {
 			err := e.Apply(&agg)
 			if err != nil {
 				return agg, fmt.Errorf("error applying event: %w", err)
@@ -83,13 +172,13 @@ func (rm GenericHandler[T]) HandleEvent(e eventsourcing.Event[T]) {
 			return agg, nil
 		})
 	}
+}
 
-	if err != nil {
-		log.Error().
-			Err(err).
-			Str("aggregate_id", e.AggregateId().String()).
-			Str("aggregate_type", string(e.AggregateType())).
-			Str("event_type", e.EventType().String()).
-			Msg("read model: error handling event")
-	}
+func logHandleError[T eventsourcing.Aggregate](err error, e eventsourcing.Event[T]) {
+	log.Error().
+		Err(err).
+		Str("aggregate_id", e.AggregateId().String()).
+		Str("aggregate_type", string(e.AggregateType())).
+		Str("event_type", e.EventType().String()).
+		Msg("read model: error handling event")
 }
@@ -0,0 +1,181 @@
+//go:build unit
+
+package readmodel
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davidterranova/cqrs/eventsourcing"
+	"github.com/davidterranova/cqrs/eventsourcing/eventrepository"
+	"github.com/davidterranova/cqrs/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericHandlerWithInbox(t *testing.T) {
+	ctx := context.Background()
+	rm := NewInMemoryReadModel(
+		nil, newTestAggregate, evtTypeTestAggregateCreated, eventTypeNil,
+		GenericHandlerWithInbox[testAggregate](eventrepository.NewInMemoryInbox(), "test-read-model"),
+	)
+
+	aggregateId := uuid.New()
+	events := []eventsourcing.Event[testAggregate]{
+		newEvtTestAggregateCreated(aggregateId, 0, nil),
+		newEvtTestAggregateValueSet(aggregateId, 1, nil, 1),
+	}
+
+	// the events are delivered again, as when the aggregate is republished
+	for i := 0; i < 2; i++ {
+		for _, e := range events {
+			require.NoError(t, rm.Handle(ctx, e))
+		}
+	}
+
+	assert.Len(t, rm.aggregates, 1)
+	agg, err := rm.Get(ctx, AggregateMatcherAggregateId[testAggregate](&aggregateId))
+	require.NoError(t, err)
+	assert.Equal(t, 1, agg.value)
+}
+
+func TestGenericHandlerWithSQLiteInbox(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.db")
+	require.NoError(t, sqlite.Migrate(path))
+	db, err := sqlite.Open(sqlite.DBConfig{Path: path, BusyTimeout: time.Second, MaxOpenConnections: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE test_aggregates (id TEXT PRIMARY KEY, version INTEGER NOT NULL)")
+	require.NoError(t, err)
+
+	// the read model is written in the transaction of the inbox, failures roll back both
+	var failure error
+	rm := NewGenericHandlerContext[testAggregate](
+		newTestAggregate, evtTypeTestAggregateCreated, eventTypeNil,
+		func(ctx context.Context, a *testAggregate) error {
+			_, err := eventrepository.InboxTx(ctx).ExecContext(ctx, "INSERT INTO test_aggregates (id, version) VALUES (?, ?)", a.AggregateId().String(), a.AggregateVersion())
+			return err
+		},
+		func(ctx context.Context, id uuid.UUID, fnRepo func(a testAggregate) (testAggregate, error)) error {
+			tx := eventrepository.InboxTx(ctx)
+			agg := *newTestAggregate()
+			var version int
+			err := tx.QueryRowContext(ctx, "SELECT version FROM test_aggregates WHERE id = ?", id.String()).Scan(&version)
+			if err != nil {
+				return err
+			}
+			agg.AggregateBase = eventsourcing.NewAggregateBase[testAggregate](id, version)
+
+			agg, err = fnRepo(agg)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, "UPDATE test_aggregates SET version = ? WHERE id = ?", agg.AggregateVersion(), id.String())
+			if err != nil {
+				return err
+			}
+
+			return failure
+		},
+		nil,
+		nil,
+		GenericHandlerWithInbox[testAggregate](eventrepository.NewSQLiteInbox(db), "test-read-model"),
+	)
+	version := func(t *testing.T, id uuid.UUID) int {
+		t.Helper()
+
+		var version int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT version FROM test_aggregates WHERE id = ?", id.String()).Scan(&version))
+		return version
+	}
+
+	aggregateId := uuid.New()
+	created := newEvtTestAggregateCreated(aggregateId, 1, nil)
+	valueSet := newEvtTestAggregateValueSet(aggregateId, 2, nil, 1)
+	require.NoError(t, rm.Handle(ctx, created))
+	require.NoError(t, rm.Handle(ctx, created))
+	assert.Equal(t, 1, version(t, aggregateId))
+
+	failure = errors.New("failed to update")
+	assert.ErrorIs(t, rm.Handle(ctx, valueSet), failure)
+	assert.Equal(t, 1, version(t, aggregateId))
+
+	failure = nil
+	require.NoError(t, rm.Handle(ctx, valueSet))
+	require.NoError(t, rm.Handle(ctx, valueSet))
+	assert.Equal(t, 2, version(t, aggregateId))
+}
+
+type testUser struct {
+	id uuid.UUID
+}
+
+func (u testUser) Id() uuid.UUID {
+	return u.id
+}
+
+func (u testUser) String() string {
+	return u.id.String()
+}
+
+func (u *testUser) FromString(s string) error {
+	id, err := uuid.Parse(s)
+	u.id = id
+	return err
+}
+
+func TestGenericHandlerWithConsumerGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := eventrepository.NewInMemoryEventRepository()
+	registry := eventsourcing.NewEventRegistry[testAggregate]()
+	eventsourcing.MustRegister[testAggregate](registry, evtTypeTestAggregateCreated, func() eventsourcing.Event[testAggregate] {
+		return &evtTestAggregateCreated{EventBase: &eventsourcing.EventBase[testAggregate]{}}
+	})
+	eventsourcing.MustRegister[testAggregate](registry, evtTypeTestAggregateValueSet, func() eventsourcing.Event[testAggregate] {
+		return &evtTestAggregateValueSet{EventBase: &eventsourcing.EventBase[testAggregate]{}}
+	})
+	userFactory := func() eventsourcing.User { return &testUser{} }
+	store := eventsourcing.NewEventStore[testAggregate](repo, registry, userFactory, false)
+
+	group := eventsourcing.NewConsumerGroup[testAggregate](
+		"read-model", repo, repo.(eventsourcing.CheckpointStore), eventrepository.NewInMemoryConsumerGroupCoordinator(),
+		registry, testAggregateAggregateType, userFactory,
+		eventsourcing.ConsumerGroupWithPartitions(2),
+		eventsourcing.ConsumerGroupWithPollInterval(5*time.Millisecond),
+	)
+	rm := NewInMemoryReadModel(eventsourcing.SubscriberV1FromV2[testAggregate](group), newTestAggregate, evtTypeTestAggregateCreated, eventTypeNil)
+
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, group.Run(ctx))
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	issuer := &testUser{id: uuid.New()}
+	aggregateIds := []uuid.UUID{uuid.New(), uuid.New()}
+	for _, aggregateId := range aggregateIds {
+		require.NoError(t, store.Store(ctx,
+			newEvtTestAggregateCreated(aggregateId, 1, issuer),
+			newEvtTestAggregateValueSet(aggregateId, 2, issuer, 1),
+		))
+	}
+
+	for _, aggregateId := range aggregateIds {
+		assert.Eventually(t, func() bool {
+			agg, err := rm.Get(ctx, AggregateMatcherAggregateId[testAggregate](&aggregateId))
+			return err == nil && (*agg).AggregateVersion() == 2
+		}, time.Second, 5*time.Millisecond)
+	}
+}
@@ -23,6 +23,7 @@ func NewInMemoryReadModel[T eventsourcing.Aggregate](
 	aggregateFactory eventsourcing.AggregateFactory[T],
 	evtTypeCreated eventsourcing.EventType,
 	evtTypeDeleted eventsourcing.EventType,
+	opts ...GenericHandlerOption[T],
 ) *InMemoryReadModel[T] {
 	rm := &InMemoryReadModel[T]{
 		aggregates: []*T{},
@@ -36,6 +37,7 @@ func NewInMemoryReadModel[T eventsourcing.Aggregate](
 		rm.update,
 		rm.delete,
 		eventStream,
+		opts...,
 	)
 
 	return rm
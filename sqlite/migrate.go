@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"embed"
+
+	"github.com/davidterranova/cqrs/pg"
+	// registers the sqlite:// database driver used to run migrations
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+)
+
+const EventSourcing = "eventsourcing-sqlite"
+
+//go:embed migrations/*.sql
+var EventSourcingFS embed.FS
+
+// NewMigrations returns the sqlite event store migrations
+// connection strings are expected as sqlite://path/to/events.db
+func NewMigrations() *pg.Migrations {
+	m := &pg.Migrations{}
+	m.Append(EventSourcing, "migrations", EventSourcingFS)
+
+	return m
+}
+
+// Migrate applies the event store migrations to the given sqlite database file
+func Migrate(path string) error {
+	return NewMigrations().MigrateAll("sqlite://" + path)
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS events (
+  global_position INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_id TEXT NOT NULL UNIQUE,
+  event_type TEXT NOT NULL,
+  event_issued_by TEXT NOT NULL,
+  event_issued_at TEXT NOT NULL,
+
+  aggregate_id TEXT NOT NULL,
+  aggregate_type TEXT NOT NULL,
+  aggregate_version INTEGER NOT NULL,
+
+  event_data BLOB,
+  event_hash BLOB,
+  previous_event_hash BLOB,
+  event_signature BLOB
+);
+
+CREATE INDEX IF NOT EXISTS events_aggregate_id_idx ON events (aggregate_id);
+CREATE INDEX IF NOT EXISTS events_aggregate_type_idx ON events (aggregate_type);
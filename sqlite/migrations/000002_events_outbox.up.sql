@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS events_outbox (
+  event_id TEXT PRIMARY KEY REFERENCES events (event_id),
+  published INTEGER NOT NULL DEFAULT 0,
+  aggregate_type TEXT NOT NULL,
+  aggregate_version INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_outbox_unpublished ON events_outbox (aggregate_type, published);
@@ -0,0 +1 @@
+ALTER TABLE events_outbox DROP COLUMN leased_until;
@@ -0,0 +1,2 @@
+-- unpublished events are leased to the publisher which loaded them until leased_until, in unix milliseconds
+ALTER TABLE events_outbox ADD COLUMN leased_until INTEGER;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS events_dead_letter;
+ALTER TABLE events_outbox DROP COLUMN failed_attempts;
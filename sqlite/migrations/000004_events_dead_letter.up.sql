@@ -0,0 +1,13 @@
+-- failed publications of outbox events, events failing too many times are moved to events_dead_letter
+ALTER TABLE events_outbox ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0;
+
+-- dead lettered events stay unpublished in events_outbox and hold back the later events of their aggregate
+CREATE TABLE IF NOT EXISTS events_dead_letter (
+  event_id TEXT PRIMARY KEY REFERENCES events_outbox (event_id),
+  aggregate_type TEXT NOT NULL,
+  attempts INTEGER NOT NULL,
+  reason TEXT NOT NULL,
+  dead_lettered_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_dead_letter_aggregate_type ON events_dead_letter (aggregate_type);
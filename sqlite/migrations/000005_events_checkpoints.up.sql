@@ -0,0 +1,6 @@
+-- global position of the last event handled by each catch-up subscription
+CREATE TABLE IF NOT EXISTS events_checkpoints (
+  name TEXT PRIMARY KEY,
+  position INTEGER NOT NULL,
+  updated_at TEXT NOT NULL
+);
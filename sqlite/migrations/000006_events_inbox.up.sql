@@ -0,0 +1,10 @@
+-- events processed by each consumer, the events delivered again are skipped
+-- processed_at is in unix milliseconds so that it can be compared
+CREATE TABLE IF NOT EXISTS events_inbox (
+  consumer TEXT NOT NULL,
+  event_id TEXT NOT NULL,
+  processed_at INTEGER NOT NULL,
+  PRIMARY KEY (consumer, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_inbox_processed_at ON events_inbox (processed_at);
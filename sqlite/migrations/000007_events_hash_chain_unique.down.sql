@@ -0,0 +1 @@
+DROP INDEX IF EXISTS events_hash_chain_idx;
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	// registers the pure go sqlite database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+const sqliteDriverName = "sqlite"
+
+type DBConfig struct {
+	// Path of the database file, ":memory:" is not supported as each connection would see its own database
+	Path               string        `envconfig:"PATH" required:"true"`
+	BusyTimeout        time.Duration `envconfig:"BUSY_TIMEOUT" default:"5s"`
+	MaxOpenConnections int           `envconfig:"MAX_OPEN_CONNECTIONS" default:"1"`
+}
+
+// Open opens a sqlite database in WAL mode with foreign keys enforced
+func Open(cfg DBConfig) (*sql.DB, error) {
+	pragmas := []string{
+		fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()),
+		"journal_mode(WAL)",
+		"foreign_keys(1)",
+	}
+
+	dsn := "file:" + cfg.Path + "?_pragma=" + strings.Join(pragmas, "&_pragma=")
+	db, err := sql.Open(sqliteDriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxOpenConnections > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConnections)
+	}
+
+	return db, nil
+}